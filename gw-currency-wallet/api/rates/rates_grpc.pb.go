@@ -0,0 +1,177 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: api/rates/rates.proto
+
+package rates
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RateService_SubscribeRates_FullMethodName           = "/exchange.rates.RateService/SubscribeRates"
+	RateService_GetExchangeRatesForPairs_FullMethodName = "/exchange.rates.RateService/GetExchangeRatesForPairs"
+)
+
+// RateServiceClient is the client API for RateService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// RateService раздает изменения курсов по мере их появления.
+type RateServiceClient interface {
+	// SubscribeRates присылает действующий курс валюты после каждого его
+	// изменения, а после переподключения gw-exchanger к базе — все курсы.
+	SubscribeRates(ctx context.Context, in *SubscribeRatesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RateUpdate], error)
+	// GetExchangeRatesForPairs возвращает курсы сразу для многих пар. Все курсы
+	// берутся из одного снимка и согласованы между собой. Ошибка в одной паре
+	// не мешает ответу по остальным.
+	GetExchangeRatesForPairs(ctx context.Context, in *PairsRequest, opts ...grpc.CallOption) (*PairsResponse, error)
+}
+
+type rateServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRateServiceClient(cc grpc.ClientConnInterface) RateServiceClient {
+	return &rateServiceClient{cc}
+}
+
+func (c *rateServiceClient) SubscribeRates(ctx context.Context, in *SubscribeRatesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RateUpdate], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RateService_ServiceDesc.Streams[0], RateService_SubscribeRates_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeRatesRequest, RateUpdate]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RateService_SubscribeRatesClient = grpc.ServerStreamingClient[RateUpdate]
+
+func (c *rateServiceClient) GetExchangeRatesForPairs(ctx context.Context, in *PairsRequest, opts ...grpc.CallOption) (*PairsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PairsResponse)
+	err := c.cc.Invoke(ctx, RateService_GetExchangeRatesForPairs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RateServiceServer is the server API for RateService service.
+// All implementations must embed UnimplementedRateServiceServer
+// for forward compatibility.
+//
+// RateService раздает изменения курсов по мере их появления.
+type RateServiceServer interface {
+	// SubscribeRates присылает действующий курс валюты после каждого его
+	// изменения, а после переподключения gw-exchanger к базе — все курсы.
+	SubscribeRates(*SubscribeRatesRequest, grpc.ServerStreamingServer[RateUpdate]) error
+	// GetExchangeRatesForPairs возвращает курсы сразу для многих пар. Все курсы
+	// берутся из одного снимка и согласованы между собой. Ошибка в одной паре
+	// не мешает ответу по остальным.
+	GetExchangeRatesForPairs(context.Context, *PairsRequest) (*PairsResponse, error)
+	mustEmbedUnimplementedRateServiceServer()
+}
+
+// UnimplementedRateServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRateServiceServer struct{}
+
+func (UnimplementedRateServiceServer) SubscribeRates(*SubscribeRatesRequest, grpc.ServerStreamingServer[RateUpdate]) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeRates not implemented")
+}
+func (UnimplementedRateServiceServer) GetExchangeRatesForPairs(context.Context, *PairsRequest) (*PairsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetExchangeRatesForPairs not implemented")
+}
+func (UnimplementedRateServiceServer) mustEmbedUnimplementedRateServiceServer() {}
+func (UnimplementedRateServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeRateServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RateServiceServer will
+// result in compilation errors.
+type UnsafeRateServiceServer interface {
+	mustEmbedUnimplementedRateServiceServer()
+}
+
+func RegisterRateServiceServer(s grpc.ServiceRegistrar, srv RateServiceServer) {
+	// If the following call pancis, it indicates UnimplementedRateServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RateService_ServiceDesc, srv)
+}
+
+func _RateService_SubscribeRates_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRatesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RateServiceServer).SubscribeRates(m, &grpc.GenericServerStream[SubscribeRatesRequest, RateUpdate]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RateService_SubscribeRatesServer = grpc.ServerStreamingServer[RateUpdate]
+
+func _RateService_GetExchangeRatesForPairs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PairsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateServiceServer).GetExchangeRatesForPairs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateService_GetExchangeRatesForPairs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateServiceServer).GetExchangeRatesForPairs(ctx, req.(*PairsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RateService_ServiceDesc is the grpc.ServiceDesc for RateService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RateService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "exchange.rates.RateService",
+	HandlerType: (*RateServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetExchangeRatesForPairs",
+			Handler:    _RateService_GetExchangeRatesForPairs_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeRates",
+			Handler:       _RateService_SubscribeRates_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "api/rates/rates.proto",
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"io/ioutil"
+	"os"
 
 	"gw-currency-wallet/internal/logger"
 
@@ -9,10 +10,210 @@ import (
 )
 
 type ConfigAdr struct {
-	Database_url string `yaml:"database_url"`
-	APP_ADR      string `yaml:"app_adr"`
-	Grpc_Adr     string `yaml:"grpc_adr"`
-	Swagger_url  string `yaml:"swagger_url"`
+	Database_url     string                `yaml:"database_url"`
+	APP_ADR          string                `yaml:"app_adr"`
+	Grpc_Adr         string                `yaml:"grpc_adr"`
+	Swagger_url      string                `yaml:"swagger_url"`
+	Deposit_policy   string                `yaml:"deposit_policy"` // active_only, allow_frozen (по умолчанию) или allow_all
+	Admin_token      string                `yaml:"admin_token"`
+	Login_limit      LoginLimit            `yaml:"login_limit"`
+	User_rate_limits map[string]RouteLimit `yaml:"user_rate_limits"` // ключ — путь маршрута
+	Limits           map[string]TierLimits `yaml:"limits"`           // ключ — тариф пользователя
+	Two_factor       TwoFactor             `yaml:"two_factor"`
+	Email            Email                 `yaml:"email"`
+	Password_policy  PasswordPolicy        `yaml:"password_policy"`
+	Transactions     Transactions          `yaml:"transactions"`
+	Database         Database              `yaml:"database"`
+	Exchanger        Exchanger             `yaml:"exchanger"`
+	Grpc_tls         TLS                   `yaml:"grpc_tls"`
+	Service_auth     ServiceAuth           `yaml:"service_auth"`
+	Orders           Orders                `yaml:"orders"`
+	Holds            Holds                 `yaml:"holds"`
+	Scheduler        Scheduler             `yaml:"scheduler"`
+	Webhooks         Webhooks              `yaml:"webhooks"`
+	Events           Events                `yaml:"events"`
+}
+
+// Events настраивает публикацию событий outbox для внутренних систем.
+// Publisher — log (по умолчанию), file, nats или none. Раз в Poll_interval_ms
+// реплика, взявшая блокировку Lock_key, закрепляет за собой пачку из Batch_size
+// событий и публикует ее вне транзакции.
+// Для nats тема события — Subject_prefix и тип события, например
+// wallet.events.wallet.deposited; Timeout_ms ограничивает ответ сервера.
+type Events struct {
+	Publisher        string `yaml:"publisher"`
+	File             string `yaml:"file"`
+	Nats_url         string `yaml:"nats_url"`
+	Subject_prefix   string `yaml:"subject_prefix"`
+	Timeout_ms       int    `yaml:"timeout_ms"`
+	Poll_interval_ms int    `yaml:"poll_interval_ms"`
+	Batch_size       int    `yaml:"batch_size"`
+	Lock_key         int64  `yaml:"lock_key"`
+}
+
+// Webhooks настраивает доставку вебхуков. Раз в Poll_interval_sec новые события
+// outbox раскладываются по подпискам и отправляются до Batch_size доставок.
+// Неудачная попытка повторяется через Backoff_base_sec, каждый следующий раз
+// вдвое позже, но не позже Backoff_max_sec. После Max_attempts попыток
+// доставка попадает в dead и повторяется только вручную.
+type Webhooks struct {
+	Poll_interval_sec int `yaml:"poll_interval_sec"`
+	Batch_size        int `yaml:"batch_size"`
+	Timeout_ms        int `yaml:"timeout_ms"`
+	Max_attempts      int `yaml:"max_attempts"`
+	Backoff_base_sec  int `yaml:"backoff_base_sec"`
+	Backoff_max_sec   int `yaml:"backoff_max_sec"`
+}
+
+// Scheduler настраивает регулярные операции. Реплики раз в Poll_interval_sec
+// соревнуются за рекомендательную блокировку Lock_key; лидер выполняет до
+// Batch_size наступивших запусков. После Max_failures неудач подряд расписание
+// приостанавливается, ноль — не приостанавливать.
+type Scheduler struct {
+	Poll_interval_sec int   `yaml:"poll_interval_sec"`
+	Lock_key          int64 `yaml:"lock_key"`
+	Batch_size        int   `yaml:"batch_size"`
+	Max_failures      int   `yaml:"max_failures"`
+}
+
+// Holds настраивает холды. Без ttl_sec в запросе холд живет Default_ttl_sec
+// секунд, дольше Max_ttl_sec его не создать. Просроченные холды перестают
+// действовать сразу, а раз в Expire_interval_sec получают статус expired.
+type Holds struct {
+	Default_ttl_sec     int `yaml:"default_ttl_sec"`
+	Max_ttl_sec         int `yaml:"max_ttl_sec"`
+	Expire_interval_sec int `yaml:"expire_interval_sec"`
+}
+
+// Orders настраивает исполнение лимитных заявок. Заявки сверяются с курсами
+// после каждого обновления курсов gw-exchanger и не реже раза в Match_interval_sec.
+type Orders struct {
+	Match_interval_sec int `yaml:"match_interval_sec"`
+}
+
+// ServiceAuth — сервисный токен для gw-exchanger. Name — имя кошелька в
+// списках доступа gw-exchanger, Secret — общий с ним секрет подписи.
+// Секрет берется из переменной окружения SERVICE_AUTH_SECRET, если она задана.
+// Пустой Secret отключает токены.
+type ServiceAuth struct {
+	Name          string `yaml:"name"`
+	Secret        string `yaml:"secret"`
+	Token_ttl_sec int    `yaml:"token_ttl_sec"`
+}
+
+// TLS настраивает защищенное соединение с gw-exchanger. Ca_file проверяет
+// сертификат сервера, Cert_file и Key_file предъявляются серверу при
+// взаимной аутентификации. Server_name переопределяет имя из Grpc_Adr.
+// Файлы перечитываются каждые Reload_sec секунд, если изменились.
+type TLS struct {
+	Enabled     bool   `yaml:"enabled"`
+	Cert_file   string `yaml:"cert_file"`
+	Key_file    string `yaml:"key_file"`
+	Ca_file     string `yaml:"ca_file"`
+	Server_name string `yaml:"server_name"`
+	Reload_sec  int    `yaml:"reload_sec"`
+}
+
+// Exchanger настраивает gRPC-клиент к gw-exchanger. Call_timeout_ms — дедлайн
+// одной попытки. Идемпотентные вызовы повторяются до Max_retries раз с паузой
+// от Retry_backoff_ms до Retry_backoff_max_ms со случайным разбросом.
+// После Breaker_failures неудач подряд клиент на Breaker_open_sec секунд
+// перестает обращаться к сервису и сразу возвращает Unavailable.
+type Exchanger struct {
+	Call_timeout_ms       int `yaml:"call_timeout_ms"`
+	Max_retries           int `yaml:"max_retries"`
+	Retry_backoff_ms      int `yaml:"retry_backoff_ms"`
+	Retry_backoff_max_ms  int `yaml:"retry_backoff_max_ms"`
+	Breaker_failures      int `yaml:"breaker_failures"`
+	Breaker_open_sec      int `yaml:"breaker_open_sec"`
+	Keepalive_time_sec    int `yaml:"keepalive_time_sec"`
+	Keepalive_timeout_sec int `yaml:"keepalive_timeout_sec"`
+}
+
+// Database настраивает пул соединений с базой. Нулевые значения оставляют
+// настройки pgx по умолчанию. Query_timeout_ms ограничивает время одного вызова
+// репозитория, включая повторы транзакции; ноль — только контекст запроса.
+// Connect_attempts — число попыток первого подключения, ноль — без ограничения.
+type Database struct {
+	Max_conns               int32 `yaml:"max_conns"`
+	Min_conns               int32 `yaml:"min_conns"`
+	Max_conn_lifetime_sec   int   `yaml:"max_conn_lifetime_sec"`
+	Max_conn_idle_time_sec  int   `yaml:"max_conn_idle_time_sec"`
+	Health_check_period_sec int   `yaml:"health_check_period_sec"`
+	Query_timeout_ms        int   `yaml:"query_timeout_ms"`
+	Connect_attempts        int   `yaml:"connect_attempts"`
+	Connect_backoff_ms      int   `yaml:"connect_backoff_ms"`
+	Connect_backoff_max_ms  int   `yaml:"connect_backoff_max_ms"`
+}
+
+// Transactions настраивает транзакции кошелька. Isolation — read_committed,
+// repeatable_read или serializable, пустое значение — уровень базы по умолчанию.
+// Max_retries — сколько раз повторять транзакцию после ошибки сериализации
+// или взаимоблокировки, ноль — значение по умолчанию.
+type Transactions struct {
+	Isolation   string `yaml:"isolation"`
+	Max_retries int    `yaml:"max_retries"`
+}
+
+// PasswordPolicy: Breached_list — путь к файлу с утекшими паролями, по одному в строке.
+type PasswordPolicy struct {
+	Min_length    int    `yaml:"min_length"`
+	Breached_list string `yaml:"breached_list"`
+}
+
+// Email настраивает подтверждение почты и восстановление пароля.
+// Base_url — адрес фронтенда, к которому добавляется ?token=... в письмах.
+type Email struct {
+	Require_verified bool   `yaml:"require_verified"`
+	Base_url         string `yaml:"base_url"`
+	Verify_ttl_sec   int    `yaml:"verify_ttl_sec"`
+	Reset_ttl_sec    int    `yaml:"reset_ttl_sec"`
+	Smtp             SMTP   `yaml:"smtp"`
+}
+
+type SMTP struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+// TwoFactor настраивает TOTP. Threshold — сумма по валютам, начиная с которой
+// вывод и обмен требуют свежий код в заголовке X-TOTP-Code.
+type TwoFactor struct {
+	Issuer           string             `yaml:"issuer"`
+	Pre_auth_ttl_sec int                `yaml:"pre_auth_ttl_sec"`
+	Threshold        map[string]float64 `yaml:"threshold"`
+}
+
+// TierLimits — лимиты тарифа по валютам, ключ — код валюты.
+type TierLimits map[string]CurrencyLimits
+
+// CurrencyLimits задает лимиты операций в одной валюте. Ноль — без ограничения.
+type CurrencyLimits struct {
+	Max_withdrawal   float64 `yaml:"max_withdrawal"`
+	Daily_withdrawal float64 `yaml:"daily_withdrawal"`
+	Daily_exchange   float64 `yaml:"daily_exchange"`
+}
+
+// RouteLimit разрешает Requests запросов за Per_sec секунд.
+type RouteLimit struct {
+	Requests int `yaml:"requests"`
+	Per_sec  int `yaml:"per_sec"`
+}
+
+// LoginLimit настраивает защиту /login и /register от перебора.
+// Нулевой лимит отключает соответствующую проверку.
+type LoginLimit struct {
+	Store            string `yaml:"store"` // memory или postgres
+	Window_sec       int    `yaml:"window_sec"`
+	Ip_limit         int    `yaml:"ip_limit"`
+	Username_limit   int    `yaml:"username_limit"`
+	Max_failures     int    `yaml:"max_failures"`
+	Failure_window   int    `yaml:"failure_window_sec"`
+	Lockout_base_sec int    `yaml:"lockout_base_sec"`
+	Lockout_max_sec  int    `yaml:"lockout_max_sec"`
 }
 
 func LoadConfig(filePath string) (*logger.Config, *ConfigAdr, error) {
@@ -28,5 +229,8 @@ func LoadConfig(filePath string) (*logger.Config, *ConfigAdr, error) {
 	if err := yaml.Unmarshal(data, cfgAdr); err != nil {
 		return nil, nil, err
 	}
+	if secret, ok := os.LookupEnv("SERVICE_AUTH_SECRET"); ok {
+		cfgAdr.Service_auth.Secret = secret
+	}
 	return cfg, cfgAdr, nil
 }
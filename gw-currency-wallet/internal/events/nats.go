@@ -0,0 +1,160 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// NATSPublisher публикует события в NATS по текстовому протоколу клиента:
+// PUB в тему prefix+тип события. В core NATS нет подтверждений публикации,
+// поэтому после каждого PUB отправляется PING: ответ PONG значит, что сервер
+// принял и обработал сообщение. При любой ошибке соединение закрывается и
+// открывается заново при следующей публикации.
+type NATSPublisher struct {
+	addr    string
+	user    string
+	pass    string
+	prefix  string
+	timeout time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// NewNATSPublisher принимает адрес вида nats://[user:pass@]host:port.
+// Соединение открывается при первой публикации.
+func NewNATSPublisher(rawURL, prefix string, timeout time.Duration) (*NATSPublisher, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "nats" || u.Host == "" {
+		return nil, fmt.Errorf("invalid nats url %q", rawURL)
+	}
+	p := &NATSPublisher{addr: u.Host, prefix: prefix, timeout: timeout}
+	if u.User != nil {
+		p.user = u.User.Username()
+		p.pass, _ = u.User.Password()
+	}
+	return p, nil
+}
+
+func (p *NATSPublisher) Publish(ctx context.Context, ev storages.Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.connect(ctx); err != nil {
+		return err
+	}
+	p.setDeadline(ctx)
+	cmd := fmt.Sprintf("PUB %s%s %d\r\n%s\r\nPING\r\n", p.prefix, ev.Type, len(body), body)
+	if _, err := p.conn.Write([]byte(cmd)); err != nil {
+		p.reset()
+		return err
+	}
+	if err := p.waitPong(); err != nil {
+		p.reset()
+		return err
+	}
+	return nil
+}
+
+func (p *NATSPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reset()
+	return nil
+}
+
+// connect открывает соединение: сервер присылает INFO, клиент отвечает
+// CONNECT и проверяет его PING/PONG, чтобы сразу увидеть ошибку авторизации.
+func (p *NATSPublisher) connect(ctx context.Context) error {
+	if p.conn != nil {
+		return nil
+	}
+	d := net.Dialer{Timeout: p.timeout}
+	conn, err := d.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return err
+	}
+	p.conn, p.r = conn, bufio.NewReader(conn)
+	p.setDeadline(ctx)
+
+	line, err := p.readLine()
+	if err != nil {
+		p.reset()
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		p.reset()
+		return fmt.Errorf("nats: unexpected greeting %q", line)
+	}
+	opts, _ := json.Marshal(map[string]any{
+		"verbose": false, "pedantic": false, "lang": "go", "name": "gw-currency-wallet",
+		"user": p.user, "pass": p.pass,
+	})
+	if _, err := fmt.Fprintf(p.conn, "CONNECT %s\r\nPING\r\n", opts); err != nil {
+		p.reset()
+		return err
+	}
+	if err := p.waitPong(); err != nil {
+		p.reset()
+		return err
+	}
+	return nil
+}
+
+// waitPong читает ответы сервера до PONG. -ERR — ошибка, на PING сервера
+// отвечаем PONG, остальное (+OK, новые INFO) пропускаем.
+func (p *NATSPublisher) waitPong() error {
+	for {
+		line, err := p.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := p.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New("nats: " + strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+func (p *NATSPublisher) readLine() (string, error) {
+	line, err := p.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (p *NATSPublisher) setDeadline(ctx context.Context) {
+	deadline := time.Now().Add(p.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	p.conn.SetDeadline(deadline)
+}
+
+func (p *NATSPublisher) reset() {
+	if p.conn != nil {
+		p.conn.Close()
+	}
+	p.conn, p.r = nil, nil
+}
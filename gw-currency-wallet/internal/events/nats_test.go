@@ -0,0 +1,177 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type natsMsg struct {
+	subject string
+	data    []byte
+}
+
+// natsStandIn — минимальный сервер NATS в процессе: отвечает на CONNECT,
+// PING и PUB и запоминает опубликованные сообщения.
+type natsStandIn struct {
+	lis   net.Listener
+	user  string
+	mu    sync.Mutex
+	msgs  []natsMsg
+	conns int
+	// dropNext обрывает соединение, не ответив на следующую публикацию.
+	dropNext bool
+}
+
+func newNATSStandIn(t *testing.T) *natsStandIn {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &natsStandIn{lis: lis}
+	t.Cleanup(func() { lis.Close() })
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *natsStandIn) url(userinfo string) string {
+	return "nats://" + userinfo + s.lis.Addr().String()
+}
+
+func (s *natsStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+	s.mu.Lock()
+	s.conns++
+	s.mu.Unlock()
+	fmt.Fprintf(conn, "INFO {\"server_id\":\"stand-in\",\"max_payload\":1048576}\r\n")
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, args, _ := strings.Cut(line, " ")
+		switch verb {
+		case "CONNECT":
+			var opts struct{ User, Pass string }
+			json.Unmarshal([]byte(args), &opts)
+			if s.user != "" && opts.User != s.user {
+				fmt.Fprintf(conn, "-ERR 'Authorization Violation'\r\n")
+				return
+			}
+		case "PING":
+			fmt.Fprintf(conn, "PONG\r\n")
+		case "PUB":
+			fields := strings.Fields(args)
+			n, _ := strconv.Atoi(fields[len(fields)-1])
+			buf := make([]byte, n+2)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return
+			}
+			s.mu.Lock()
+			drop := s.dropNext
+			s.dropNext = false
+			if !drop {
+				s.msgs = append(s.msgs, natsMsg{subject: fields[0], data: buf[:n]})
+			}
+			s.mu.Unlock()
+			if drop {
+				return
+			}
+		}
+	}
+}
+
+func (s *natsStandIn) received() []natsMsg {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]natsMsg(nil), s.msgs...)
+}
+
+func testEvent(id int64, kind string) storages.Event {
+	user_id := 1
+	return storages.Event{ID: id, UserID: &user_id, Type: kind, CreatedAt: time.Now().UTC(), Data: json.RawMessage(`{"currency":"USD","amount":"50"}`)}
+}
+
+func TestNATSPublisherPublishes(t *testing.T) {
+	srv := newNATSStandIn(t)
+	p, err := NewNATSPublisher(srv.url(""), "wallet.events.", time.Second)
+	require.NoError(t, err)
+	defer p.Close()
+
+	require.NoError(t, p.Publish(context.Background(), testEvent(1, storages.EventDeposited)))
+	require.NoError(t, p.Publish(context.Background(), testEvent(2, storages.EventUserRegistered)))
+
+	msgs := srv.received()
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "wallet.events.wallet.deposited", msgs[0].subject)
+	assert.Equal(t, "wallet.events.user.registered", msgs[1].subject)
+	var ev storages.Event
+	require.NoError(t, json.Unmarshal(msgs[0].data, &ev))
+	assert.Equal(t, int64(1), ev.ID)
+	assert.JSONEq(t, `{"currency":"USD","amount":"50"}`, string(ev.Data))
+	assert.Equal(t, 1, srv.conns, "connection is reused")
+}
+
+func TestNATSPublisherReconnects(t *testing.T) {
+	srv := newNATSStandIn(t)
+	p, err := NewNATSPublisher(srv.url(""), "", time.Second)
+	require.NoError(t, err)
+	defer p.Close()
+
+	require.NoError(t, p.Publish(context.Background(), testEvent(1, storages.EventDeposited)))
+	srv.mu.Lock()
+	srv.dropNext = true
+	srv.mu.Unlock()
+	assert.Error(t, p.Publish(context.Background(), testEvent(2, storages.EventWithdrawn)), "unconfirmed publish is an error")
+	require.NoError(t, p.Publish(context.Background(), testEvent(2, storages.EventWithdrawn)))
+
+	msgs := srv.received()
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "wallet.withdrawn", msgs[1].subject)
+	assert.Equal(t, 2, srv.conns)
+}
+
+func TestNATSPublisherAuthError(t *testing.T) {
+	srv := newNATSStandIn(t)
+	srv.user = "wallet"
+	p, err := NewNATSPublisher(srv.url("intruder:x@"), "", time.Second)
+	require.NoError(t, err)
+	defer p.Close()
+
+	err = p.Publish(context.Background(), testEvent(1, storages.EventDeposited))
+	assert.EqualError(t, err, "nats: 'Authorization Violation'")
+	assert.Empty(t, srv.received())
+}
+
+func TestNATSPublisherUnreachable(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	lis.Close()
+	p, err := NewNATSPublisher("nats://"+addr, "", 100*time.Millisecond)
+	require.NoError(t, err)
+
+	assert.Error(t, p.Publish(context.Background(), testEvent(1, storages.EventDeposited)))
+}
@@ -0,0 +1,108 @@
+// Package events публикует события кошелька из outbox для внутренних систем
+// через подключаемого издателя: журнал, файл или NATS.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"gw-currency-wallet/internal/config"
+	"gw-currency-wallet/internal/logger"
+	"gw-currency-wallet/internal/storages"
+)
+
+// Издатели, которые можно выбрать в events.publisher.
+const (
+	PublisherLog  = "log"
+	PublisherFile = "file"
+	PublisherNATS = "nats"
+	PublisherNone = "none"
+)
+
+const defaultTimeout = 2 * time.Second
+
+// Publisher отправляет событие получателям. Publish возвращает nil, только
+// когда событие принято; иначе relay повторит его позже. Событие может прийти
+// повторно, получатели отбрасывают дубли по ID.
+type Publisher interface {
+	Publish(ctx context.Context, ev storages.Event) error
+	Close() error
+}
+
+// NewPublisher создает издателя по настройке cfg.Publisher. Для none
+// возвращает nil: события остаются в outbox и не публикуются.
+func NewPublisher(cfg config.Events, lg logger.Logger) (Publisher, error) {
+	switch cfg.Publisher {
+	case "", PublisherLog:
+		return NewLogPublisher(lg), nil
+	case PublisherFile:
+		return NewFilePublisher(cfg.File)
+	case PublisherNATS:
+		timeout := time.Duration(cfg.Timeout_ms) * time.Millisecond
+		if timeout <= 0 {
+			timeout = defaultTimeout
+		}
+		return NewNATSPublisher(cfg.Nats_url, cfg.Subject_prefix, timeout)
+	case PublisherNone:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown events publisher %q", cfg.Publisher)
+}
+
+// LogPublisher пишет события в журнал сервиса.
+type LogPublisher struct {
+	lg logger.Logger
+}
+
+func NewLogPublisher(lg logger.Logger) *LogPublisher {
+	return &LogPublisher{lg: lg}
+}
+
+func (p *LogPublisher) Publish(ctx context.Context, ev storages.Event) error {
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	p.lg.InfoCtx(ctx, "event "+string(line))
+	return nil
+}
+
+func (p *LogPublisher) Close() error { return nil }
+
+// FilePublisher дописывает события в файл по одному JSON в строке и
+// сбрасывает запись на диск до подтверждения.
+type FilePublisher struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+func NewFilePublisher(path string) (*FilePublisher, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, err
+	}
+	return &FilePublisher{f: f}, nil
+}
+
+func (p *FilePublisher) Publish(ctx context.Context, ev storages.Event) error {
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := p.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return p.f.Sync()
+}
+
+func (p *FilePublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.f.Close()
+}
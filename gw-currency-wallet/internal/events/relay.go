@@ -0,0 +1,117 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gw-currency-wallet/internal/config"
+	"gw-currency-wallet/internal/logger"
+	"gw-currency-wallet/internal/storages"
+)
+
+const (
+	defaultPollInterval = time.Second
+	defaultBatchSize    = 100
+)
+
+// Store — операции репозитория, которые нужны relay.
+type Store interface {
+	ClaimEvents(ctx context.Context, lockKey int64, limit int, lease time.Duration) ([]storages.Event, error)
+	FinishEvents(ctx context.Context, claimed, published []int64) error
+}
+
+// Relay переносит события из outbox в Publisher по порядку их записи.
+type Relay struct {
+	store    Store
+	pub      Publisher
+	lg       logger.Logger
+	interval time.Duration
+	batch    int
+	lockKey  int64
+	lease    time.Duration
+}
+
+func NewRelay(store Store, pub Publisher, lg logger.Logger, cfg config.Events) *Relay {
+	r := &Relay{
+		store:    store,
+		pub:      pub,
+		lg:       lg,
+		interval: time.Duration(cfg.Poll_interval_ms) * time.Millisecond,
+		batch:    cfg.Batch_size,
+		lockKey:  cfg.Lock_key,
+	}
+	if r.interval <= 0 {
+		r.interval = defaultPollInterval
+	}
+	if r.batch <= 0 {
+		r.batch = defaultBatchSize
+	}
+	timeout := time.Duration(cfg.Timeout_ms) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	// Пачку должно хватить опубликовать, даже если каждое событие ждет ответа
+	// издателя до таймаута.
+	r.lease = timeout*time.Duration(r.batch) + r.interval
+	return r
+}
+
+// Run раз в interval публикует накопившиеся события и закрывает издателя
+// при отмене ctx. Если издатель не принял событие, оно и следующие за ним
+// ждут следующего раза.
+func (r *Relay) Run(ctx context.Context) {
+	defer r.pub.Close()
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := r.Flush(ctx); err != nil && ctx.Err() == nil {
+			r.lg.WarnCtx(ctx, fmt.Sprintf("events: %v", err))
+		}
+	}
+}
+
+// Flush публикует события пачками, пока outbox не опустеет или не случится ошибка.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		claimed, err := r.store.ClaimEvents(ctx, r.lockKey, r.batch, r.lease)
+		if err != nil || len(claimed) == 0 {
+			return total, err
+		}
+		published, pubErr := r.publish(ctx, claimed)
+		ids := make([]int64, len(claimed))
+		for i, ev := range claimed {
+			ids[i] = ev.ID
+		}
+		// Отметку пишем и после отмены ctx: события уже приняты издателем.
+		if err := r.store.FinishEvents(context.WithoutCancel(ctx), ids, published); err != nil {
+			return total, err
+		}
+		total += len(published)
+		if pubErr != nil || len(claimed) < r.batch {
+			return total, pubErr
+		}
+	}
+}
+
+// publish отправляет события по порядку до первой ошибки и возвращает id
+// принятых. Публикация заканчивается до конца закрепления, чтобы другая
+// реплика не начала публиковать те же события параллельно.
+func (r *Relay) publish(ctx context.Context, claimed []storages.Event) ([]int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.lease-r.interval)
+	defer cancel()
+	published := make([]int64, 0, len(claimed))
+	for _, ev := range claimed {
+		if err := r.pub.Publish(ctx, ev); err != nil {
+			return published, err
+		}
+		published = append(published, ev.ID)
+	}
+	return published, nil
+}
@@ -0,0 +1,170 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gw-currency-wallet/internal/config"
+	"gw-currency-wallet/internal/storages"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) DebugCtx(ctx context.Context, msg string)            {}
+func (nopLogger) InfoCtx(ctx context.Context, msg string)             {}
+func (nopLogger) WarnCtx(ctx context.Context, msg string)             {}
+func (nopLogger) ErrorCtx(ctx context.Context, msg string)            {}
+func (nopLogger) FatalCtx(ctx context.Context, msg string, err error) {}
+
+// fakeOutbox ведет себя как ClaimEvents и FinishEvents: отдает события по
+// порядку и убирает из outbox опубликованные.
+type fakeOutbox struct {
+	events   []storages.Event
+	calls    int
+	finished [][]int64
+	lease    time.Duration
+}
+
+func (f *fakeOutbox) ClaimEvents(ctx context.Context, lockKey int64, limit int, lease time.Duration) ([]storages.Event, error) {
+	f.calls++
+	f.lease = lease
+	return f.events[:min(limit, len(f.events))], nil
+}
+
+func (f *fakeOutbox) FinishEvents(ctx context.Context, claimed, published []int64) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	f.finished = append(f.finished, published)
+	f.events = f.events[len(published):]
+	return nil
+}
+
+// flakyPublisher не принимает событие failOn один раз.
+type flakyPublisher struct {
+	failOn int64
+	got    []int64
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, ev storages.Event) error {
+	if ev.ID == p.failOn {
+		p.failOn = 0
+		return errors.New("broker is down")
+	}
+	p.got = append(p.got, ev.ID)
+	return nil
+}
+
+func (p *flakyPublisher) Close() error { return nil }
+
+func outbox(n int) *fakeOutbox {
+	f := new(fakeOutbox)
+	for i := 1; i <= n; i++ {
+		f.events = append(f.events, testEvent(int64(i), storages.EventDeposited))
+	}
+	return f
+}
+
+func TestRelayDrainsOutboxInBatches(t *testing.T) {
+	store := outbox(5)
+	pub := new(flakyPublisher)
+	r := NewRelay(store, pub, nopLogger{}, config.Events{Batch_size: 2})
+
+	n, err := r.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, pub.got)
+	assert.Equal(t, 3, store.calls)
+}
+
+func TestRelayKeepsOrderAfterFailure(t *testing.T) {
+	store := outbox(4)
+	pub := &flakyPublisher{failOn: 3}
+	r := NewRelay(store, pub, nopLogger{}, config.Events{Batch_size: 10})
+
+	n, err := r.Flush(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 2, n)
+
+	n, err = r.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []int64{1, 2, 3, 4}, pub.got, "failed event is retried before later ones")
+}
+
+// Отмена ctx во время публикации не теряет отметку о принятых событиях.
+func TestRelayFinishesAfterShutdown(t *testing.T) {
+	store := outbox(3)
+	ctx, cancel := context.WithCancel(context.Background())
+	pub := &cancellingPublisher{cancel: cancel}
+	r := NewRelay(store, pub, nopLogger{}, config.Events{Timeout_ms: 100, Batch_size: 10})
+
+	n, err := r.Flush(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, [][]int64{{1}}, store.finished)
+	assert.Len(t, store.events, 2)
+	assert.Equal(t, time.Second+defaultPollInterval, store.lease, "lease covers a batch of timeouts")
+}
+
+// cancellingPublisher принимает первое событие и отменяет контекст relay.
+type cancellingPublisher struct {
+	cancel context.CancelFunc
+}
+
+func (p *cancellingPublisher) Publish(ctx context.Context, ev storages.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	p.cancel()
+	return nil
+}
+
+func (p *cancellingPublisher) Close() error { return nil }
+
+func TestRelayToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	pub, err := NewPublisher(config.Events{Publisher: PublisherFile, File: path}, nopLogger{})
+	require.NoError(t, err)
+	r := NewRelay(outbox(3), pub, nopLogger{}, config.Events{})
+
+	_, err = r.Flush(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, pub.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	var ids []int64
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var ev storages.Event
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &ev))
+		ids = append(ids, ev.ID)
+	}
+	assert.Equal(t, []int64{1, 2, 3}, ids)
+}
+
+func TestNewPublisher(t *testing.T) {
+	pub, err := NewPublisher(config.Events{}, nopLogger{})
+	require.NoError(t, err)
+	assert.IsType(t, &LogPublisher{}, pub)
+
+	pub, err = NewPublisher(config.Events{Publisher: PublisherNone}, nopLogger{})
+	require.NoError(t, err)
+	assert.Nil(t, pub)
+
+	_, err = NewPublisher(config.Events{Publisher: PublisherNATS, Nats_url: "localhost:4222"}, nopLogger{})
+	assert.Error(t, err)
+	_, err = NewPublisher(config.Events{Publisher: "kafka"}, nopLogger{})
+	assert.Error(t, err)
+}
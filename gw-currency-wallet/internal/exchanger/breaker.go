@@ -0,0 +1,85 @@
+package exchanger
+
+import (
+	"sync"
+	"time"
+)
+
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+// Breaker — автомат отключения вызовов. После maxFailures неудач подряд он
+// размыкается и на время openFor отклоняет вызовы. Затем пропускает один
+// пробный вызов: успех замыкает автомат, неудача снова размыкает.
+// Нулевой maxFailures отключает автомат.
+type Breaker struct {
+	mu          sync.Mutex
+	state       breakerState
+	failures    int
+	openedAt    time.Time
+	maxFailures int
+	openFor     time.Duration
+	now         func() time.Time
+}
+
+func NewBreaker(maxFailures int, openFor time.Duration) *Breaker {
+	return &Breaker{maxFailures: maxFailures, openFor: openFor, now: time.Now}
+}
+
+// Allow сообщает, можно ли сейчас выполнить вызов.
+func (b *Breaker) Allow() bool {
+	if b.maxFailures <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case stateOpen:
+		if b.now().Sub(b.openedAt) < b.openFor {
+			return false
+		}
+		b.state = stateHalfOpen
+		return true
+	case stateHalfOpen:
+		// Пробный вызов уже выполняется.
+		return false
+	}
+	return true
+}
+
+// Success отмечает успешный вызов и замыкает автомат.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = stateClosed
+	b.failures = 0
+}
+
+// Failure отмечает неудачный вызов.
+func (b *Breaker) Failure() {
+	if b.maxFailures <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.state == stateHalfOpen || b.failures >= b.maxFailures {
+		b.state = stateOpen
+		b.openedAt = b.now()
+	}
+}
+
+// Abort возвращает разрешение на пробный вызов, исход которого неизвестен,
+// например если клиент сам отменил запрос. Следующий вызов снова станет пробным.
+func (b *Breaker) Abort() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == stateHalfOpen {
+		b.state = stateOpen
+	}
+}
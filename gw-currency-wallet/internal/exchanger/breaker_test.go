@@ -0,0 +1,51 @@
+package exchanger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreakerHalfOpen(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker(2, time.Second)
+	b.now = func() time.Time { return now }
+
+	b.Failure()
+	assert.True(t, b.Allow())
+	b.Failure()
+	assert.False(t, b.Allow())
+
+	now = now.Add(time.Second)
+	assert.True(t, b.Allow(), "after openFor one probe is allowed")
+	assert.False(t, b.Allow(), "only one probe at a time")
+	b.Failure()
+	assert.False(t, b.Allow(), "failed probe opens the breaker again")
+
+	now = now.Add(time.Second)
+	assert.True(t, b.Allow())
+	b.Success()
+	assert.True(t, b.Allow())
+	assert.True(t, b.Allow())
+}
+
+func TestBreakerAbortedProbe(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker(1, time.Second)
+	b.now = func() time.Time { return now }
+
+	b.Failure()
+	now = now.Add(time.Second)
+	assert.True(t, b.Allow())
+	b.Abort()
+	assert.True(t, b.Allow(), "aborted probe gives the next call a chance")
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b := NewBreaker(0, time.Second)
+	for i := 0; i < 10; i++ {
+		b.Failure()
+	}
+	assert.True(t, b.Allow())
+}
@@ -0,0 +1,227 @@
+// Package exchanger содержит gRPC-клиент к gw-exchanger с дедлайнами,
+// повторами и автоматом отключения.
+package exchanger
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"gw-currency-wallet/api/rates"
+	"gw-currency-wallet/internal/config"
+	"gw-currency-wallet/internal/logger"
+	"gw-currency-wallet/internal/tlsconfig"
+
+	exchange "github.com/IlyaBroo/exchange_grpc/exchange"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultCallTimeout     = 2 * time.Second
+	defaultRetryBackoff    = 100 * time.Millisecond
+	defaultRetryBackoffMax = time.Second
+)
+
+// ErrCircuitOpen возвращается без обращения к сервису, пока автомат разомкнут.
+var ErrCircuitOpen = status.Error(codes.Unavailable, "exchanger is unavailable: circuit breaker is open")
+
+// Client реализует exchange.ExchangeServiceClient и пакетный запрос курсов
+// RateService поверх соединения cc. Все эти методы только читают курсы,
+// поэтому их можно безопасно повторять.
+type Client struct {
+	conn       *grpc.ClientConn
+	rpc        exchange.ExchangeServiceClient
+	rates      rates.RateServiceClient
+	health     healthpb.HealthClient
+	breaker    *Breaker
+	lg         logger.Logger
+	timeout    time.Duration
+	retries    int
+	backoff    time.Duration
+	backoffMax time.Duration
+}
+
+// Dial создает клиент к cfg.Grpc_Adr. Соединение устанавливается лениво,
+// поэтому недоступный при старте gw-exchanger не мешает запуску кошелька.
+// Если включен cfg.Grpc_tls, сертификаты перечитываются до отмены ctx.
+func Dial(ctx context.Context, cfg *config.ConfigAdr, lg logger.Logger, opts ...grpc.DialOption) (*Client, error) {
+	ex := cfg.Exchanger
+	creds, err := transportCredentials(ctx, cfg.Grpc_tls, lg)
+	if err != nil {
+		return nil, err
+	}
+	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if sa := cfg.Service_auth; sa.Secret != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(
+			NewServiceToken(sa.Name, sa.Secret, time.Duration(sa.Token_ttl_sec)*time.Second)))
+	}
+	if ex.Keepalive_time_sec > 0 {
+		dialOpts = append(dialOpts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                time.Duration(ex.Keepalive_time_sec) * time.Second,
+			Timeout:             time.Duration(ex.Keepalive_timeout_sec) * time.Second,
+			PermitWithoutStream: true,
+		}))
+	}
+	conn, err := grpc.NewClient(cfg.Grpc_Adr, append(dialOpts, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	c := New(conn, ex, lg)
+	c.conn = conn
+	return c, nil
+}
+
+func transportCredentials(ctx context.Context, cfg config.TLS, lg logger.Logger) (credentials.TransportCredentials, error) {
+	if !cfg.Enabled {
+		return insecure.NewCredentials(), nil
+	}
+	r, err := tlsconfig.New(cfg.Cert_file, cfg.Key_file, cfg.Ca_file)
+	if err != nil {
+		return nil, err
+	}
+	go r.Run(ctx, time.Duration(cfg.Reload_sec)*time.Second, lg)
+	return r.ClientCredentials(cfg.Server_name), nil
+}
+
+// New оборачивает готовое соединение, например bufconn в тестах.
+func New(cc grpc.ClientConnInterface, cfg config.Exchanger, lg logger.Logger) *Client {
+	c := &Client{
+		rpc:        exchange.NewExchangeServiceClient(cc),
+		rates:      rates.NewRateServiceClient(cc),
+		health:     healthpb.NewHealthClient(cc),
+		breaker:    NewBreaker(cfg.Breaker_failures, time.Duration(cfg.Breaker_open_sec)*time.Second),
+		lg:         lg,
+		timeout:    time.Duration(cfg.Call_timeout_ms) * time.Millisecond,
+		retries:    cfg.Max_retries,
+		backoff:    time.Duration(cfg.Retry_backoff_ms) * time.Millisecond,
+		backoffMax: time.Duration(cfg.Retry_backoff_max_ms) * time.Millisecond,
+	}
+	if c.timeout <= 0 {
+		c.timeout = defaultCallTimeout
+	}
+	if c.backoff <= 0 {
+		c.backoff = defaultRetryBackoff
+	}
+	if c.backoffMax < c.backoff {
+		c.backoffMax = max(defaultRetryBackoffMax, c.backoff)
+	}
+	return c
+}
+
+// Health возвращает клиент grpc.health.v1 того же соединения. Проверки
+// здоровья идут мимо автомата, чтобы видеть, когда сервис поднялся.
+func (c *Client) Health() healthpb.HealthClient {
+	return c.health
+}
+
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+func (c *Client) GetExchangeRates(ctx context.Context, in *exchange.Empty, opts ...grpc.CallOption) (*exchange.ExchangeRatesResponse, error) {
+	var res *exchange.ExchangeRatesResponse
+	err := c.call(ctx, "GetExchangeRates", func(ctx context.Context) error {
+		var err error
+		res, err = c.rpc.GetExchangeRates(ctx, in, opts...)
+		return err
+	})
+	return res, err
+}
+
+func (c *Client) GetExchangeRateForCurrency(ctx context.Context, in *exchange.CurrencyRequest, opts ...grpc.CallOption) (*exchange.ExchangeRateResponse, error) {
+	var res *exchange.ExchangeRateResponse
+	err := c.call(ctx, "GetExchangeRateForCurrency", func(ctx context.Context) error {
+		var err error
+		res, err = c.rpc.GetExchangeRateForCurrency(ctx, in, opts...)
+		return err
+	})
+	return res, err
+}
+
+// GetExchangeRatesForPairs запрашивает курсы многих пар одним вызовом.
+// Ошибки отдельных пар приходят в ответе, а не в err.
+func (c *Client) GetExchangeRatesForPairs(ctx context.Context, in *rates.PairsRequest, opts ...grpc.CallOption) (*rates.PairsResponse, error) {
+	var res *rates.PairsResponse
+	err := c.call(ctx, "GetExchangeRatesForPairs", func(ctx context.Context) error {
+		var err error
+		res, err = c.rates.GetExchangeRatesForPairs(ctx, in, opts...)
+		return err
+	})
+	return res, err
+}
+
+// call выполняет fn с дедлайном на каждую попытку и повторяет ее после
+// временных ошибок, пока не кончатся попытки или контекст вызывающего.
+func (c *Client) call(ctx context.Context, method string, fn func(ctx context.Context) error) error {
+	for attempt := 0; ; attempt++ {
+		if !c.breaker.Allow() {
+			return ErrCircuitOpen
+		}
+		callCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		err := fn(callCtx)
+		cancel()
+
+		switch {
+		case err == nil:
+			c.breaker.Success()
+			return nil
+		case ctx.Err() != nil:
+			// Запрос отменил сам клиент, о здоровье сервиса это ничего не говорит.
+			c.breaker.Abort()
+			return err
+		case serviceFailure(err):
+			c.breaker.Failure()
+		default:
+			c.breaker.Success()
+		}
+
+		if !retryable(err) || attempt >= c.retries {
+			return err
+		}
+		wait := c.retryDelay(attempt)
+		c.lg.WarnCtx(ctx, fmt.Sprintf("exchanger %s attempt %d failed, retrying in %s: %v", method, attempt+1, wait, err))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
+
+// retryDelay удваивает паузу с каждой попыткой до backoffMax и выбирает
+// случайное значение в ее второй половине, чтобы клиенты не повторяли синхронно.
+func (c *Client) retryDelay(attempt int) time.Duration {
+	d := c.backoff << attempt
+	if d > c.backoffMax || d <= 0 {
+		d = c.backoffMax
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryable — ошибки, после которых повтор того же запроса может пройти.
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Aborted, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+// serviceFailure — ошибки, говорящие о проблемах с самим сервисом, а не с запросом.
+func serviceFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal:
+		return true
+	}
+	return false
+}
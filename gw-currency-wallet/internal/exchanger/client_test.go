@@ -0,0 +1,213 @@
+package exchanger
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"gw-currency-wallet/api/rates"
+	"gw-currency-wallet/internal/config"
+
+	exchange "github.com/IlyaBroo/exchange_grpc/exchange"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) DebugCtx(ctx context.Context, msg string)            {}
+func (nopLogger) InfoCtx(ctx context.Context, msg string)             {}
+func (nopLogger) WarnCtx(ctx context.Context, msg string)             {}
+func (nopLogger) ErrorCtx(ctx context.Context, msg string)            {}
+func (nopLogger) FatalCtx(ctx context.Context, msg string, err error) {}
+
+// faultyServer отвечает ошибками из faults по порядку, а затем успешно.
+// Если задан hang, каждый вызов висит до отмены.
+type faultyServer struct {
+	exchange.UnimplementedExchangeServiceServer
+	rates.UnimplementedRateServiceServer
+	mu     sync.Mutex
+	faults []codes.Code
+	hang   bool
+	calls  int
+}
+
+func (f *faultyServer) next(ctx context.Context) error {
+	f.mu.Lock()
+	f.calls++
+	hang := f.hang
+	var fault codes.Code
+	if len(f.faults) > 0 {
+		fault, f.faults = f.faults[0], f.faults[1:]
+	}
+	f.mu.Unlock()
+
+	if hang {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if fault != codes.OK {
+		return status.Error(fault, "injected fault")
+	}
+	return nil
+}
+
+func (f *faultyServer) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func (f *faultyServer) GetExchangeRates(ctx context.Context, in *exchange.Empty) (*exchange.ExchangeRatesResponse, error) {
+	if err := f.next(ctx); err != nil {
+		return nil, err
+	}
+	return &exchange.ExchangeRatesResponse{Rates: map[string]float32{"USD": 1, "EUR": 0.92}}, nil
+}
+
+func (f *faultyServer) GetExchangeRateForCurrency(ctx context.Context, in *exchange.CurrencyRequest) (*exchange.ExchangeRateResponse, error) {
+	if err := f.next(ctx); err != nil {
+		return nil, err
+	}
+	return &exchange.ExchangeRateResponse{Rate: 0.92}, nil
+}
+
+func (f *faultyServer) GetExchangeRatesForPairs(ctx context.Context, in *rates.PairsRequest) (*rates.PairsResponse, error) {
+	if err := f.next(ctx); err != nil {
+		return nil, err
+	}
+	res := new(rates.PairsResponse)
+	for _, p := range in.Pairs {
+		res.Rates = append(res.Rates, &rates.PairRate{FromCurrency: p.FromCurrency, ToCurrency: p.ToCurrency, Rate: 0.92})
+	}
+	return res, nil
+}
+
+// SubscribeRates отдает одно обновление и обрывает поток.
+func (f *faultyServer) SubscribeRates(in *rates.SubscribeRatesRequest, stream rates.RateService_SubscribeRatesServer) error {
+	f.next(stream.Context())
+	if err := stream.Send(&rates.RateUpdate{CurrencyCode: "EUR", Rate: 1.05, Enabled: true}); err != nil {
+		return err
+	}
+	return status.Error(codes.Unavailable, "stream reset")
+}
+
+func newTestClient(t *testing.T, srv *faultyServer, cfg config.Exchanger) *Client {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	exchange.RegisterExchangeServiceServer(s, srv)
+	rates.RegisterRateServiceServer(s, srv)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return New(conn, cfg, nopLogger{})
+}
+
+func TestClientRetriesTransientErrors(t *testing.T) {
+	srv := &faultyServer{faults: []codes.Code{codes.Unavailable, codes.Unavailable}}
+	c := newTestClient(t, srv, config.Exchanger{Max_retries: 2, Retry_backoff_ms: 1, Retry_backoff_max_ms: 5})
+
+	res, err := c.GetExchangeRates(context.Background(), &exchange.Empty{})
+	require.NoError(t, err)
+	assert.Equal(t, float32(0.92), res.Rates["EUR"])
+	assert.Equal(t, 3, srv.Calls())
+}
+
+func TestClientRetriesPairsRequest(t *testing.T) {
+	srv := &faultyServer{faults: []codes.Code{codes.Unavailable}}
+	c := newTestClient(t, srv, config.Exchanger{Max_retries: 1, Retry_backoff_ms: 1})
+
+	res, err := c.GetExchangeRatesForPairs(context.Background(), &rates.PairsRequest{Pairs: []*rates.CurrencyPair{
+		{FromCurrency: "USD", ToCurrency: "EUR"},
+		{FromCurrency: "RUB", ToCurrency: "EUR"},
+	}})
+	require.NoError(t, err)
+	require.Len(t, res.Rates, 2)
+	assert.Equal(t, "RUB", res.Rates[1].FromCurrency)
+	assert.Equal(t, 2, srv.Calls())
+}
+
+func TestClientGivesUpAfterMaxRetries(t *testing.T) {
+	srv := &faultyServer{faults: []codes.Code{codes.Unavailable, codes.Unavailable, codes.Unavailable}}
+	c := newTestClient(t, srv, config.Exchanger{Max_retries: 1, Retry_backoff_ms: 1})
+
+	_, err := c.GetExchangeRateForCurrency(context.Background(), &exchange.CurrencyRequest{FromCurrency: "USD", ToCurrency: "EUR"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 2, srv.Calls())
+}
+
+func TestClientDoesNotRetryRequestErrors(t *testing.T) {
+	srv := &faultyServer{faults: []codes.Code{codes.InvalidArgument}}
+	c := newTestClient(t, srv, config.Exchanger{Max_retries: 3, Retry_backoff_ms: 1})
+
+	_, err := c.GetExchangeRateForCurrency(context.Background(), &exchange.CurrencyRequest{FromCurrency: "USD", ToCurrency: "USD"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, 1, srv.Calls())
+}
+
+func TestClientCallDeadline(t *testing.T) {
+	srv := &faultyServer{hang: true}
+	c := newTestClient(t, srv, config.Exchanger{Call_timeout_ms: 30, Max_retries: 1, Retry_backoff_ms: 1})
+
+	start := time.Now()
+	_, err := c.GetExchangeRates(context.Background(), &exchange.Empty{})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, 2, srv.Calls())
+}
+
+func TestClientCircuitBreaker(t *testing.T) {
+	srv := &faultyServer{faults: []codes.Code{codes.Unavailable, codes.Unavailable}}
+	c := newTestClient(t, srv, config.Exchanger{Breaker_failures: 2, Breaker_open_sec: 60})
+	now := time.Now()
+	c.breaker.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		_, err := c.GetExchangeRates(context.Background(), &exchange.Empty{})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	}
+	_, err := c.GetExchangeRates(context.Background(), &exchange.Empty{})
+	assert.Equal(t, ErrCircuitOpen, err)
+	assert.Equal(t, 2, srv.Calls(), "open breaker must not reach the server")
+
+	now = now.Add(time.Minute)
+	_, err = c.GetExchangeRates(context.Background(), &exchange.Empty{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, srv.Calls())
+
+	_, err = c.GetExchangeRates(context.Background(), &exchange.Empty{})
+	assert.NoError(t, err)
+}
+
+func TestWatchRatesReconnects(t *testing.T) {
+	srv := new(faultyServer)
+	c := newTestClient(t, srv, config.Exchanger{Retry_backoff_ms: 1, Retry_backoff_max_ms: 5})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	updates := c.WatchRates(ctx)
+	for i := 0; i < 2; i++ {
+		select {
+		case <-updates:
+		case <-time.After(time.Second):
+			t.Fatal("no rate update")
+		}
+	}
+	assert.GreaterOrEqual(t, srv.Calls(), 2, "stream is reopened after a reset")
+
+	cancel()
+	for range updates {
+	}
+}
@@ -0,0 +1,56 @@
+package exchanger
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseServiceToken(t *testing.T, md map[string]string, secret string) *jwt.StandardClaims {
+	t.Helper()
+	header := md["authorization"]
+	require.True(t, strings.HasPrefix(header, "Bearer "))
+	claims := new(jwt.StandardClaims)
+	_, err := jwt.ParseWithClaims(strings.TrimPrefix(header, "Bearer "), claims, func(*jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	})
+	require.NoError(t, err)
+	return claims
+}
+
+func TestServiceTokenClaims(t *testing.T) {
+	now := time.Now()
+	tok := NewServiceToken("gw-currency-wallet", "secret", time.Minute)
+	tok.now = func() time.Time { return now }
+	md, err := tok.GetRequestMetadata(context.Background())
+	require.NoError(t, err)
+
+	claims := parseServiceToken(t, md, "secret")
+	assert.Equal(t, "gw-currency-wallet", claims.Subject)
+	assert.True(t, claims.VerifyAudience("gw-exchanger", true))
+	assert.Equal(t, now.Add(time.Minute).Unix(), claims.ExpiresAt)
+}
+
+func TestServiceTokenRenewal(t *testing.T) {
+	now := time.Now()
+	tok := NewServiceToken("gw-currency-wallet", "secret", time.Minute)
+	tok.now = func() time.Time { return now }
+
+	first, err := tok.GetRequestMetadata(context.Background())
+	require.NoError(t, err)
+	now = now.Add(20 * time.Second)
+	second, err := tok.GetRequestMetadata(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, first, second, "token is reused within half of its ttl")
+
+	now = now.Add(10 * time.Second)
+	third, err := tok.GetRequestMetadata(context.Background())
+	require.NoError(t, err)
+	assert.NotEqual(t, first, third)
+	assert.Equal(t, now.Add(time.Minute).Unix(), parseServiceToken(t, third, "secret").ExpiresAt)
+}
@@ -0,0 +1,58 @@
+package exchanger
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gw-currency-wallet/api/rates"
+)
+
+// WatchRates подписывается на изменения курсов gw-exchanger и сигналит в
+// возвращаемый канал после каждого обновления. Сигналы не копятся: пока
+// предыдущий не прочитан, новые отбрасываются. При обрыве потока клиент
+// переподключается с той же паузой, что и между повторами вызовов.
+// Канал закрывается после отмены ctx.
+func (c *Client) WatchRates(ctx context.Context) <-chan struct{} {
+	updates := make(chan struct{}, 1)
+	go func() {
+		defer close(updates)
+		for attempt := 0; ; {
+			received, err := c.watch(ctx, updates)
+			if ctx.Err() != nil {
+				return
+			}
+			if received {
+				attempt = 0
+			}
+			wait := c.retryDelay(attempt)
+			attempt++
+			c.lg.WarnCtx(ctx, fmt.Sprintf("exchanger rate stream closed, reconnecting in %s: %v", wait, err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+	}()
+	return updates
+}
+
+// watch читает один поток обновлений до ошибки и сообщает, пришло ли хоть одно.
+func (c *Client) watch(ctx context.Context, updates chan<- struct{}) (bool, error) {
+	stream, err := c.rates.SubscribeRates(ctx, &rates.SubscribeRatesRequest{})
+	if err != nil {
+		return false, err
+	}
+	received := false
+	for {
+		if _, err := stream.Recv(); err != nil {
+			return received, err
+		}
+		received = true
+		select {
+		case updates <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"gw-currency-wallet/internal/render"
+	"gw-currency-wallet/internal/storages"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+type AccountStatusRequest struct {
+	Status string `json:"status"`
+}
+
+type AccountStatusResponse struct {
+	Message string `json:"message"`
+	UserId  int    `json:"user_id"`
+	Status  string `json:"status"`
+}
+
+// @Summary Изменение статуса аккаунта
+// @Description Позволяет комплаенсу заморозить, закрыть или снова активировать аккаунт пользователя вместе с его кошельком без удаления данных.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param user_id path int true "ID пользователя"
+// @Param status body AccountStatusRequest true "Новый статус: active, frozen или closed"
+// @Success 200 {object} AccountStatusResponse
+// @Failure 400 {object} render.ErrorResponse "Invalid user id or status"
+// @Failure 401 {object} render.ErrorResponse "Invalid admin token"
+// @Failure 404 {object} render.ErrorResponse "Wallet not found"
+// @Failure 500 {object} render.ErrorResponse "Could not change account status"
+// @Router /admin/accounts/{user_id}/status [put]
+func (s *ServerWallet) SetAccountStatus(w http.ResponseWriter, r *http.Request) {
+	var req AccountStatusRequest
+	user_id, err := strconv.Atoi(chi.URLParam(r, "user_id"))
+	if err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error parsing user id: %v", err))
+		render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Invalid user id or status")
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error decoding: %v", err))
+		render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Invalid user id or status")
+		return
+	}
+
+	err = s.db.SetAccountStatus(user_id, req.Status, r.Context())
+	if err != nil {
+		if err == storages.ErrStatus {
+			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Invalid status: %v", err))
+			render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Invalid user id or status")
+			return
+		} else if err == storages.ErrWalletid {
+			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Wallet not found: %v", err))
+			render.Error(w, r, http.StatusNotFound, render.CodeNotFound, "Wallet not found")
+			return
+		} else {
+			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error changing account status: %v", err))
+			render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Could not change account status")
+			return
+		}
+	}
+
+	render.JSON(w, http.StatusOK, AccountStatusResponse{Message: "Account status changed", UserId: user_id, Status: req.Status})
+	s.lg.InfoCtx(r.Context(), fmt.Sprintf("User %d status changed to %s", user_id, req.Status))
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"gw-currency-wallet/internal/middleware"
+	"gw-currency-wallet/internal/render"
+	"gw-currency-wallet/internal/storages"
 	"net/http"
 	"time"
 
@@ -13,76 +15,129 @@ import (
 
 var jwtSecret = []byte("your_secret_key")
 
+// dummyHash сравнивается с паролем, когда пользователь не найден.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password for timing"), bcrypt.DefaultCost)
+
 type LoginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token             string `json:"token"`
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
 }
 
 // @Summary Авторизация пользователя
-// @Description Позволяет пользователю войти в систему и получить JWT-токен для дальнейшей аутентификации.
+// @Description Позволяет пользователю войти в систему и получить JWT-токен для дальнейшей аутентификации. Если включена 2FA, возвращается токен предварительной авторизации для /login/2fa. После серии неудачных попыток имя пользователя временно блокируется.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param login body LoginRequest true "Данные для авторизации"
 // @Success 200 {object} LoginResponse
-// @Failure 400 {object} ErrorResponse "Error decoding LoginResponse"
-// @Failure 401 {object} ErrorResponse "User not found"
-// @Failure 401 {object} ErrorResponse "Invalid password"
-// @Failure 500 {object} ErrorResponse "Could not generate token"
+// @Failure 400 {object} render.ErrorResponse "Error decoding LoginResponse"
+// @Failure 401 {object} render.ErrorResponse "Invalid username or password"
+// @Failure 403 {object} render.ErrorResponse "Email is not verified"
+// @Failure 429 {object} render.ErrorResponse "Too many failed attempts"
+// @Failure 500 {object} render.ErrorResponse "Could not generate token"
 // @Router /login [post]
 
 func (s *ServerWallet) Autherisation(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
-	errRes := new(ErrorResponse)
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error decoding: %v", err))
-		errRes.message = "Error decoding LoginRespons"
-		json.NewEncoder(w).Encode(errRes)
-		http.Error(w, "Invalid input", http.StatusBadRequest)
+		render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Invalid input")
 		return
 	}
 
+	if s.lockout != nil {
+		wait, err := s.lockout.Check(r.Context(), req.Username)
+		if err != nil {
+			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("error checking lockout: %v", err))
+		}
+		if wait > 0 {
+			s.lg.WarnCtx(r.Context(), fmt.Sprintf("login for %s is locked", req.Username))
+			middleware.TooManyRequests(w, r, wait)
+			return
+		}
+	}
+
 	user, err := s.db.GetUser(req.Username, r.Context())
-	if err != nil {
+	if err != nil && err != storages.ErrNoUser {
 		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("error getting user: %v", err))
-		errRes.message = "User not found"
-		json.NewEncoder(w).Encode(errRes)
-		http.Error(w, "User not found", http.StatusUnauthorized)
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Internal server error")
 		return
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
-	if err != nil {
-		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Invalid credentials"))
-		errRes.message = "Invalid password"
-		json.NewEncoder(w).Encode(errRes)
-		http.Error(w, "Invalid password", http.StatusUnauthorized)
+	// bcrypt выполняется и для несуществующего пользователя, чтобы время
+	// ответа не выдавало, зарегистрировано ли имя.
+	hash := dummyHash
+	if err == nil {
+		hash = []byte(user.Password)
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(req.Password)) != nil || err != nil {
+		s.lg.ErrorCtx(r.Context(), "Invalid credentials")
+		s.loginFailed(r, req.Username)
+		render.Error(w, r, http.StatusUnauthorized, render.CodeInvalidCredentials, "Invalid username or password")
 		return
 	}
 
-	token, err := generateToken(user.Id, user.Username)
+	if s.email.Require_verified && !user.EmailVerified {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("User %s has not verified email", user.Username))
+		render.Error(w, r, http.StatusForbidden, render.CodeEmailNotVerified, "Email is not verified")
+		return
+	}
+
+	res := LoginResponse{}
+	if user.TOTPEnabled {
+		res.TwoFactorRequired = true
+		res.Token, err = generatePreAuthToken(user.Id, user.Username, s.preAuthTTL())
+	} else {
+		res.Token, err = generateToken(user.Id, user.Username)
+	}
 	if err != nil {
 		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error generating token: %v", err))
-		errRes.message = "Could not generate token"
-		json.NewEncoder(w).Encode(errRes)
-		http.Error(w, "Could not generate token", http.StatusInternalServerError)
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Could not generate token")
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(LoginResponse{Token: token})
+	if s.lockout != nil {
+		if err := s.lockout.Success(r.Context(), req.Username); err != nil {
+			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("error resetting lockout: %v", err))
+		}
+	}
+	render.JSON(w, http.StatusOK, res)
+	if res.TwoFactorRequired {
+		s.lg.InfoCtx(r.Context(), fmt.Sprintf("User %s passed password check, waiting for 2fa", user.Username))
+		return
+	}
 	s.lg.InfoCtx(r.Context(), fmt.Sprintf("User %s logged in successfully", user.Username))
 }
 
+func (s *ServerWallet) loginFailed(r *http.Request, username string) {
+	if s.lockout == nil {
+		return
+	}
+	if err := s.lockout.Fail(r.Context(), username); err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("error registering failed login: %v", err))
+	}
+}
+
 func generateToken(user_id int, username string) (string, error) {
-	expirationTime := time.Now().Add(50 * time.Minute)
+	return signToken(user_id, username, "", 50*time.Minute)
+}
+
+// generatePreAuthToken выдает короткоживущий токен, который принимает только /login/2fa.
+func generatePreAuthToken(user_id int, username string, ttl time.Duration) (string, error) {
+	return signToken(user_id, username, middleware.ScopePreAuth, ttl)
+}
+
+func signToken(user_id int, username, scope string, ttl time.Duration) (string, error) {
+	expirationTime := time.Now().Add(ttl)
 	claims := new(middleware.Claims)
 	claims.Id = user_id
 	claims.Username = username
+	claims.Scope = scope
 	claims.StandardClaims = jwt.StandardClaims{
 		ExpiresAt: expirationTime.Unix(),
 	}
@@ -3,11 +3,13 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"gw-currency-wallet/internal/limiter"
 	"gw-currency-wallet/internal/storages"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -84,3 +86,39 @@ func TestAutherisation(t *testing.T) {
 		})
 	}
 }
+
+func TestAutherisationUniformError(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	mockDB := new(MockRepository)
+	mockDB.On("GetUser", "testuser", mock.Anything).Return(storages.User{Id: 1, Username: "testuser", Password: string(hashedPassword)}, nil)
+	mockDB.On("GetUser", "ghost", mock.Anything).Return(storages.User{}, storages.ErrNoUser)
+	mockLogger := new(MockLogger)
+	mockLogger.On("ErrorCtx", mock.Anything, mock.Anything).Return(nil)
+	mockLogger.On("WarnCtx", mock.Anything, mock.Anything).Return(nil)
+
+	s := &ServerWallet{
+		db:      mockDB,
+		lg:      mockLogger,
+		lockout: limiter.NewLockout(limiter.NewMemoryStore(), 2, time.Minute, time.Minute, time.Hour),
+	}
+	login := func(username, password string) *http.Response {
+		body, _ := json.Marshal(LoginRequest{Username: username, Password: password})
+		w := httptest.NewRecorder()
+		s.Autherisation(w, httptest.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(body)))
+		return w.Result()
+	}
+
+	wrongPassword := login("testuser", "wrong")
+	unknownUser := login("ghost", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, wrongPassword.StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, unknownUser.StatusCode)
+	wrongBody, _ := ioutil.ReadAll(wrongPassword.Body)
+	unknownBody, _ := ioutil.ReadAll(unknownUser.Body)
+	assert.Equal(t, string(wrongBody), string(unknownBody))
+
+	// Вторая неудача подряд блокирует имя даже для верного пароля.
+	assert.Equal(t, http.StatusUnauthorized, login("testuser", "wrong").StatusCode)
+	locked := login("testuser", "password123")
+	assert.Equal(t, http.StatusTooManyRequests, locked.StatusCode)
+	assert.NotEmpty(t, locked.Header.Get("Retry-After"))
+}
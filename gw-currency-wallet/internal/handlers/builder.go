@@ -2,34 +2,65 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"gw-currency-wallet/internal/config"
+	"gw-currency-wallet/internal/events"
+	"gw-currency-wallet/internal/exchanger"
+	"gw-currency-wallet/internal/holds"
+	"gw-currency-wallet/internal/limiter"
 	"gw-currency-wallet/internal/logger"
+	"gw-currency-wallet/internal/mailer"
+	"gw-currency-wallet/internal/middleware"
+	"gw-currency-wallet/internal/orders"
+	"gw-currency-wallet/internal/render"
+	"gw-currency-wallet/internal/scheduler"
 	"gw-currency-wallet/internal/storages"
+	"gw-currency-wallet/internal/validation"
+	"gw-currency-wallet/internal/webhooks"
 	"net/http"
+	"time"
 
 	exchange "github.com/IlyaBroo/exchange_grpc/exchange"
+	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/lib/pq"
-	"google.golang.org/grpc"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type ServerWallet struct {
-	HttpClient *http.Client
-	db         storages.RepositoryInterface
-	lg         logger.Logger
-	grpcclient exchange.ExchangeServiceClient
+	HttpClient  *http.Client
+	db          storages.RepositoryInterface
+	lg          logger.Logger
+	grpcclient  exchange.ExchangeServiceClient
+	rates       pairRater
+	lockout     *limiter.Lockout
+	ipLimiter   *limiter.Limiter
+	userLimiter *limiter.Limiter
+	twoFactor   config.TwoFactor
+	holds       config.Holds
+	email       config.Email
+	mailer      mailer.Mailer
+
+	passwordPolicy  *validation.PasswordPolicy
+	exchangerHealth healthChecker
 }
 
-type ErrorResponse struct {
-	message string `json:"error"`
+const loginStoreConns = 4
+
+// LimitDetails — детали ошибки превышения лимита.
+type LimitDetails struct {
+	Limit     string          `json:"limit"`
+	Currency  string          `json:"currency"`
+	Remaining decimal.Decimal `json:"remaining"`
 }
 
 func NewServerWallet(httpClient *http.Client, lg logger.Logger, cfg *config.ConfigAdr, ctx context.Context) (*ServerWallet, error) {
 
-	conn, err := grpc.Dial(cfg.Grpc_Adr, grpc.WithInsecure())
+	grpcClient, err := exchanger.Dial(ctx, cfg, lg)
 	if err != nil {
 		return nil, err
 	}
-	grpcClient := exchange.NewExchangeServiceClient(conn)
 
 	db := storages.NewRepository(lg, ctx, cfg)
 	s := new(ServerWallet)
@@ -37,5 +68,112 @@ func NewServerWallet(httpClient *http.Client, lg logger.Logger, cfg *config.Conf
 	s.lg = lg
 	s.db = db
 	s.grpcclient = grpcClient
+	s.rates = grpcClient
+	s.exchangerHealth = grpcClient.Health()
+	s.mailer = mailer.NewMailer(cfg.Email.Smtp)
+	pub, err := events.NewPublisher(cfg.Events, lg)
+	if err != nil {
+		return nil, err
+	}
+	s.twoFactor = cfg.Two_factor
+	s.holds = cfg.Holds
+	s.email = cfg.Email
+	s.passwordPolicy, err = validation.NewPasswordPolicy(cfg.Password_policy)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Email.Smtp.Host == "" {
+		lg.WarnCtx(ctx, "SMTP is not configured, emails are kept in memory")
+	}
+
+	store, err := newLoginStore(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	lim := cfg.Login_limit
+	window := time.Duration(lim.Window_sec) * time.Second
+	s.ipLimiter = limiter.NewLimiter(store, lim.Ip_limit, window)
+	s.userLimiter = limiter.NewLimiter(store, lim.Username_limit, window)
+	s.lockout = limiter.NewLockout(store, lim.Max_failures,
+		time.Duration(lim.Failure_window)*time.Second,
+		time.Duration(lim.Lockout_base_sec)*time.Second,
+		time.Duration(lim.Lockout_max_sec)*time.Second)
+
+	// Фоновые задачи запускаются, когда сервер уже точно собран.
+	// Несколько реплик могут исполнять заявки одновременно: заявка закрывается
+	// одним UPDATE по status = 'open', поэтому исполнится только однажды.
+	worker := orders.NewWorker(db, orders.NewExchangerRates(grpcClient, lg), lg,
+		time.Duration(cfg.Orders.Match_interval_sec)*time.Second)
+	go worker.Run(ctx, grpcClient.WatchRates(ctx))
+	go holds.NewWorker(db, lg, time.Duration(cfg.Holds.Expire_interval_sec)*time.Second).Run(ctx)
+	sched := scheduler.New(db, storages.NewLeader(cfg.Database_url, cfg.Scheduler.Lock_key, lg), grpcClient, s.mailer, lg, cfg.Scheduler)
+	go sched.Run(ctx)
+	go webhooks.New(db, lg, cfg.Webhooks).Run(ctx)
+	if pub != nil {
+		go events.NewRelay(db, pub, lg, cfg.Events).Run(ctx)
+	}
 	return s, nil
 }
+
+// AuthRateLimit возвращает middleware, ограничивающее частоту запросов к /login и /register.
+func (s *ServerWallet) AuthRateLimit() func(http.Handler) http.Handler {
+	return middleware.RateLimit(s.ipLimiter, s.userLimiter, s.lg)
+}
+
+func newLoginStore(ctx context.Context, cfg *config.ConfigAdr) (limiter.Store, error) {
+	if cfg.Login_limit.Store != "postgres" {
+		return limiter.NewMemoryStore(), nil
+	}
+	conf, err := pgxpool.ParseConfig(cfg.Database_url)
+	if err != nil {
+		return nil, err
+	}
+	conf.MaxConns = loginStoreConns
+	pool, err := pgxpool.NewWithConfig(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	return limiter.NewPostgresStore(pool), nil
+}
+
+// writeExchangerError отвечает 503, если gw-exchanger недоступен или не успел
+// ответить, и 500 на остальные ошибки.
+func writeExchangerError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		render.Error(w, r, http.StatusServiceUnavailable, render.CodeUnavailable, "Exchange service is unavailable")
+	default:
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, message)
+	}
+}
+
+// writeLimitError отвечает 422 с оставшимся лимитом, если err — превышение лимита.
+func writeLimitError(w http.ResponseWriter, r *http.Request, err error) bool {
+	var limitErr *storages.LimitError
+	if !errors.As(err, &limitErr) {
+		return false
+	}
+	render.ErrorDetails(w, r, http.StatusUnprocessableEntity, render.CodeLimitExceeded, "Limit exceeded", LimitDetails{
+		Limit:     limitErr.Limit,
+		Currency:  limitErr.Currency,
+		Remaining: limitErr.Remaining,
+	})
+	return true
+}
+
+// writeValidationError отвечает 400 со списком ошибок по полям.
+func writeValidationError(w http.ResponseWriter, r *http.Request, fields []validation.FieldError) {
+	render.ErrorDetails(w, r, http.StatusBadRequest, render.CodeValidation, "Invalid input", fields)
+}
+
+// validateAmount проверяет валюту и сумму операции с кошельком.
+func validateAmount(field, currency string, amount decimal.Decimal) []validation.FieldError {
+	var fields []validation.FieldError
+	if !storages.ValidCurrency(currency) {
+		fields = append(fields, validation.FieldError{Field: field, Message: "must be one of USD, RUB, EUR"})
+	}
+	if !amount.IsPositive() {
+		fields = append(fields, validation.FieldError{Field: "amount", Message: "must be positive"})
+	}
+	return fields
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"gw-currency-wallet/internal/middleware"
+	"gw-currency-wallet/internal/render"
 	"gw-currency-wallet/internal/storages"
 	"net/http"
 
@@ -27,62 +28,56 @@ type DepositResponse struct {
 // @Param Authorization header string true "Bearer JWT_TOKEN"
 // @Param deposit body DepositRequest true "Данные для пополнения счета"
 // @Success 200 {object} DepositResponse
-// @Failure 400 {object} ErrorResponse "Invalid amount or currency"
-// @Failure 400 {object} ErrorResponse "Amount cannot have more than two decimal places"
-// @Failure 500 {object} ErrorResponse "Error depositing funds or getting balance"
-// @Failure 500 {object} ErrorResponse "Error getting balance from db"
+// @Failure 400 {object} render.ErrorResponse "Invalid amount or currency"
+// @Failure 400 {object} render.ErrorResponse "Invalid input: unknown currency or non-positive amount"
+// @Failure 400 {object} render.ErrorResponse "Amount cannot have more than two decimal places"
+// @Failure 403 {object} render.ErrorResponse "Account is frozen or closed"
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Error depositing funds"
 // @Router /deposit [post]
 func (s *ServerWallet) Deposit(w http.ResponseWriter, r *http.Request) {
 	var req DepositRequest
 	res := new(DepositResponse)
-	errRes := new(ErrorResponse)
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error decoding: %v", err))
-		errRes.message = "Invalid amount or currency"
-		json.NewEncoder(w).Encode(errRes)
-		http.Error(w, "Invalid amount or currency", http.StatusBadRequest)
+		render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Invalid amount or currency")
 		return
 	}
 
+	if fields := validateAmount("currency", req.Currency, req.Amount); len(fields) > 0 {
+		s.lg.ErrorCtx(r.Context(), "Invalid amount or currency")
+		writeValidationError(w, r, fields)
+		return
+	}
 	if req.Amount.Exponent() < -2 {
 		s.lg.ErrorCtx(r.Context(), "Amount cannot have more than two decimal places")
-		errRes.message = "Amount cannot have more than two decimal places"
-		json.NewEncoder(w).Encode(errRes)
-		http.Error(w, "Amount cannot have more than two decimal places", http.StatusBadRequest)
+		render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Amount cannot have more than two decimal places")
 		return
 	}
 
 	ctx := r.Context()
 	user_id := ctx.Value(middleware.User_id).(int)
 
-	err := s.db.Deposit(user_id, req.Amount, req.Currency, r.Context())
+	balance, err := s.db.Deposit(user_id, req.Amount, req.Currency, r.Context())
 	if err != nil {
 		if err == storages.ErrWalletid {
 			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Invalid amount or currency: %v", err))
-			errRes.message = "Invalid amount or currency"
-			json.NewEncoder(w).Encode(errRes)
-			http.Error(w, "Invalid amount or currency", http.StatusBadRequest)
+			render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Invalid amount or currency")
 			return
 
+		} else if err == storages.ErrInactive {
+			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("deposit refused: %v", err))
+			render.Error(w, r, http.StatusForbidden, render.CodeAccountInactive, "Account is frozen or closed")
+			return
 		} else {
 
 			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("error depositing funds: %v", err))
-			errRes.message = "Error depositing funds"
-			json.NewEncoder(w).Encode(errRes)
-			http.Error(w, "Error depositing funds", http.StatusInternalServerError)
+			render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Error depositing funds")
 			return
 		}
 	}
-	res.NewBalance, err = s.db.GetBalance(user_id, r.Context())
-	if err != nil {
-		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("error getting balance: %v", err))
-		errRes.message = "Error getting balance  from db"
-		json.NewEncoder(w).Encode(errRes)
-		http.Error(w, "Error getting balance", http.StatusInternalServerError)
-		return
-	}
+	res.NewBalance = balance
 	res.Message = "Account topped up successfully"
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(res)
+	render.JSON(w, http.StatusOK, res)
 	s.lg.InfoCtx(r.Context(), fmt.Sprintf("User %d deposited successfully", user_id))
 }
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gw-currency-wallet/internal/middleware"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDepositRejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedBody string
+	}{
+		{
+			name: "Negative amount",
+			body: `{"amount":-100,"currency":"USD"}`,
+			expectedBody: `{"code":"validation_failed","message":"Invalid input",
+				"details":[{"field":"amount","message":"must be positive"}]}`,
+		},
+		{
+			name: "Zero amount and unknown currency",
+			body: `{"amount":0,"currency":"USD = 0, RUB"}`,
+			expectedBody: `{"code":"validation_failed","message":"Invalid input",
+				"details":[{"field":"currency","message":"must be one of USD, RUB, EUR"},{"field":"amount","message":"must be positive"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockLogger := new(MockLogger)
+			mockLogger.On("ErrorCtx", mock.Anything, mock.Anything).Return(nil)
+			s := &ServerWallet{db: mockRepo, lg: mockLogger}
+
+			req := httptest.NewRequest(http.MethodPost, "/deposit", bytes.NewBufferString(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), middleware.User_id, 1))
+			w := httptest.NewRecorder()
+
+			s.Deposit(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			mockRepo.AssertNotCalled(t, "Deposit", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
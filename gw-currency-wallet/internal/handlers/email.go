@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"gw-currency-wallet/internal/mailer"
+	"gw-currency-wallet/internal/render"
+	"gw-currency-wallet/internal/storages"
+	"gw-currency-wallet/internal/validation"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	emailTokenBytes   = 32
+	defaultVerifyTTL  = 24 * time.Hour
+	defaultResetTTL   = time.Hour
+	emailSendTimeout  = 30 * time.Second
+	acceptedEmailText = "If the email is registered, a message has been sent"
+)
+
+type EmailRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// @Summary Подтверждение почты
+// @Description Подтверждает адрес электронной почты по одноразовому токену из письма.
+// @Tags auth
+// @Produce json
+// @Param token query string true "Токен из письма"
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} render.ErrorResponse "Invalid or expired token"
+// @Failure 500 {object} render.ErrorResponse "Could not verify email"
+// @Router /verify-email [get]
+func (s *ServerWallet) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	err := s.db.VerifyEmail(hashEmailToken(token), r.Context())
+	if err != nil {
+		if err == storages.ErrToken {
+			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error verifying email: %v", err))
+			render.Error(w, r, http.StatusBadRequest, render.CodeInvalidToken, "Invalid or expired token")
+			return
+		}
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error verifying email: %v", err))
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Could not verify email")
+		return
+	}
+	render.JSON(w, http.StatusOK, MessageResponse{Message: "Email verified"})
+	s.lg.InfoCtx(r.Context(), "Email verified")
+}
+
+// @Summary Повторная отправка письма подтверждения
+// @Description Отправляет новое письмо со ссылкой подтверждения. Ответ не зависит от того, зарегистрирована ли почта.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param email body EmailRequest true "Адрес электронной почты"
+// @Success 202 {object} MessageResponse
+// @Failure 400 {object} render.ErrorResponse "Invalid input"
+// @Router /verify-email/resend [post]
+func (s *ServerWallet) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	s.acceptEmailRequest(w, r, s.sendVerification)
+}
+
+// @Summary Забыли пароль
+// @Description Отправляет письмо со ссылкой для сброса пароля. Ответ не зависит от того, зарегистрирована ли почта.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param email body EmailRequest true "Адрес электронной почты"
+// @Success 202 {object} MessageResponse
+// @Failure 400 {object} render.ErrorResponse "Invalid input"
+// @Router /password/forgot [post]
+func (s *ServerWallet) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	s.acceptEmailRequest(w, r, s.sendPasswordReset)
+}
+
+// @Summary Сброс пароля
+// @Description Устанавливает новый пароль по одноразовому токену из письма. После использования токен и остальные токены сброса становятся недействительными.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param reset body ResetPasswordRequest true "Токен и новый пароль"
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} render.ErrorResponse "Invalid or expired token"
+// @Failure 400 {object} render.ErrorResponse "Invalid input"
+// @Failure 500 {object} render.ErrorResponse "Could not reset password"
+// @Router /password/reset [post]
+func (s *ServerWallet) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" || req.Password == "" {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error decoding: %v", err))
+		render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Invalid input")
+		return
+	}
+
+	if fields := s.passwordPolicy.Password(req.Password, ""); len(fields) > 0 {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Invalid new password: %v", fields))
+		writeValidationError(w, r, fields)
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error hashing password: %v", err))
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Could not reset password")
+		return
+	}
+	err = s.db.ResetPassword(hashEmailToken(req.Token), string(hashedPassword), r.Context())
+	if err != nil {
+		if err == storages.ErrToken {
+			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error resetting password: %v", err))
+			render.Error(w, r, http.StatusBadRequest, render.CodeInvalidToken, "Invalid or expired token")
+			return
+		}
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error resetting password: %v", err))
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Could not reset password")
+		return
+	}
+	render.JSON(w, http.StatusOK, MessageResponse{Message: "Password has been reset"})
+	s.lg.InfoCtx(r.Context(), "Password has been reset")
+}
+
+// acceptEmailRequest отвечает 202 независимо от существования почты, а письмо
+// отправляет в фоне, чтобы ни ответ, ни время ответа не раскрывали, зарегистрирован ли адрес.
+func (s *ServerWallet) acceptEmailRequest(w http.ResponseWriter, r *http.Request, send func(ctx context.Context, email string) error) {
+	var req EmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error decoding: %v", err))
+		render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Invalid input")
+		return
+	}
+
+	req.Email = validation.NormalizeEmail(req.Email)
+	ctx := context.WithoutCancel(r.Context())
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, emailSendTimeout)
+		defer cancel()
+		if err := send(ctx, req.Email); err != nil && err != storages.ErrNoUser {
+			s.lg.ErrorCtx(ctx, fmt.Sprintf("Error sending email: %v", err))
+		}
+	}()
+
+	render.JSON(w, http.StatusAccepted, MessageResponse{Message: acceptedEmailText})
+}
+
+func (s *ServerWallet) sendVerification(ctx context.Context, email string) error {
+	return s.sendTokenEmail(ctx, email, storages.TokenVerifyEmail, s.ttl(s.email.Verify_ttl_sec, defaultVerifyTTL),
+		"Confirm your email", "/verify-email",
+		"Hello, %s!\n\nTo confirm your email open the link:\n%s\n\nThe link is valid until %s.")
+}
+
+func (s *ServerWallet) sendPasswordReset(ctx context.Context, email string) error {
+	return s.sendTokenEmail(ctx, email, storages.TokenResetPassword, s.ttl(s.email.Reset_ttl_sec, defaultResetTTL),
+		"Password reset", "/password/reset",
+		"Hello, %s!\n\nTo set a new password open the link:\n%s\n\nThe link is valid until %s. If you did not request a reset, ignore this message.")
+}
+
+func (s *ServerWallet) sendTokenEmail(ctx context.Context, email, kind string, ttl time.Duration, subject, path, body string) error {
+	if s.mailer == nil {
+		return nil
+	}
+	token, err := newEmailToken()
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(ttl)
+	user, err := s.db.CreateUserToken(email, kind, hashEmailToken(token), expiresAt, ctx)
+	if err != nil {
+		return err
+	}
+	link := s.email.Base_url + path + "?token=" + url.QueryEscape(token)
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: subject,
+		Body:    fmt.Sprintf(body, user.Username, link, expiresAt.UTC().Format(time.RFC1123)),
+	})
+}
+
+func (s *ServerWallet) ttl(sec int, def time.Duration) time.Duration {
+	if sec <= 0 {
+		return def
+	}
+	return time.Duration(sec) * time.Second
+}
+
+func newEmailToken() (string, error) {
+	buf := make([]byte, emailTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashEmailToken — в базе хранятся только хеши токенов, сами токены есть лишь в письмах.
+func hashEmailToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
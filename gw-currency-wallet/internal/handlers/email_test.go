@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"gw-currency-wallet/internal/config"
+	"gw-currency-wallet/internal/mailer"
+	"gw-currency-wallet/internal/storages"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPasswordResetFlow(t *testing.T) {
+	outbox := mailer.NewOutbox()
+	mockDB := new(MockRepository)
+	mockDB.On("CreateUserToken", "user@example.com", storages.TokenResetPassword, mock.Anything, mock.Anything, mock.Anything).
+		Return(storages.User{Id: 1, Username: "user", Email: "user@example.com"}, nil)
+	mockDB.On("CreateUserToken", "ghost@example.com", storages.TokenResetPassword, mock.Anything, mock.Anything, mock.Anything).
+		Return(storages.User{}, storages.ErrNoUser)
+	mockLogger := new(MockLogger)
+	mockLogger.On("InfoCtx", mock.Anything, mock.Anything).Return(nil)
+	mockLogger.On("ErrorCtx", mock.Anything, mock.Anything).Return(nil)
+
+	s := &ServerWallet{
+		db:     mockDB,
+		lg:     mockLogger,
+		mailer: outbox,
+		email:  config.Email{Base_url: "https://wallet.example"},
+	}
+	forgot := func(email string) int {
+		body, _ := json.Marshal(EmailRequest{Email: email})
+		w := httptest.NewRecorder()
+		s.ForgotPassword(w, httptest.NewRequest(http.MethodPost, "/password/forgot", bytes.NewBuffer(body)))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusAccepted, forgot("ghost@example.com"), "unknown emails are not disclosed")
+	assert.Equal(t, http.StatusAccepted, forgot("user@example.com"))
+	assert.Eventually(t, func() bool { return len(outbox.Messages()) == 1 }, time.Second, 10*time.Millisecond)
+
+	msg := outbox.Messages()[0]
+	assert.Equal(t, "user@example.com", msg.To)
+	start := strings.Index(msg.Body, "https://wallet.example/password/reset?token=")
+	assert.NotEqual(t, -1, start)
+	link, err := url.Parse(strings.Fields(msg.Body[start:])[0])
+	assert.NoError(t, err)
+	token := link.Query().Get("token")
+
+	mockDB.On("ResetPassword", hashEmailToken(token), mock.Anything, mock.Anything).Return(nil).Once()
+	mockDB.On("ResetPassword", hashEmailToken(token), mock.Anything, mock.Anything).Return(storages.ErrToken)
+	reset := func() int {
+		body, _ := json.Marshal(ResetPasswordRequest{Token: token, Password: "new-password"})
+		w := httptest.NewRecorder()
+		s.ResetPassword(w, httptest.NewRequest(http.MethodPost, "/password/reset", bytes.NewBuffer(body)))
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, reset())
+	assert.Equal(t, http.StatusBadRequest, reset(), "reset token is single-use")
+}
+
+func TestLoginRequiresVerifiedEmail(t *testing.T) {
+	mockDB := new(MockRepository)
+	mockDB.On("GetUser", "testuser", mock.Anything).Return(storages.User{Id: 1, Username: "testuser", Password: string(dummyHash)}, nil)
+	mockLogger := new(MockLogger)
+	mockLogger.On("ErrorCtx", mock.Anything, mock.Anything).Return(nil)
+
+	s := &ServerWallet{db: mockDB, lg: mockLogger, email: config.Email{Require_verified: true}}
+	body, _ := json.Marshal(LoginRequest{Username: "testuser", Password: "dummy password for timing"})
+	w := httptest.NewRecorder()
+	s.Autherisation(w, httptest.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(body)))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"gw-currency-wallet/internal/middleware"
+	"gw-currency-wallet/internal/render"
 	"gw-currency-wallet/internal/storages"
+	"gw-currency-wallet/internal/validation"
 	"net/http"
 
 	exchange "github.com/IlyaBroo/exchange_grpc/exchange"
@@ -35,11 +37,12 @@ type ExchangeResponseForCurrency struct {
 // @Produce json
 // @Param Authorization header string true "Bearer JWT_TOKEN"
 // @Success 200 {object} ExchangeResponse "rates:"
-// @Failure 500 {object} ErrorResponse "Failed to retrieve exchange rates"
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Failed to retrieve exchange rates"
+// @Failure 503 {object} render.ErrorResponse "Exchange service is unavailable"
 // @Router /rates [get]
 func (s *ServerWallet) ExchangeRates(w http.ResponseWriter, r *http.Request) {
 	var exchangeRes ExchangeResponse
-	errRes := new(ErrorResponse)
 	reqId := r.Context().Value("requestID").(string)
 	ctx := metadata.AppendToOutgoingContext(r.Context(), "requestID", reqId)
 	in := new(exchange.Empty)
@@ -47,15 +50,12 @@ func (s *ServerWallet) ExchangeRates(w http.ResponseWriter, r *http.Request) {
 	res, err := s.grpcclient.GetExchangeRates(ctx, in)
 	if err != nil {
 		s.lg.ErrorCtx(ctx, err.Error())
-		errRes.message = "Failed to retrieve exchange rates"
-		json.NewEncoder(w).Encode(errRes)
-		http.Error(w, "Failed to retrieve exchange rates", http.StatusInternalServerError)
+		writeExchangerError(w, r, err, "Failed to retrieve exchange rates")
 		return
 	}
 	exchangeRes.Rates = res.Rates
 	s.lg.InfoCtx(ctx, fmt.Sprintf("rates: %v", res.Rates))
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(exchangeRes)
+	render.JSON(w, http.StatusOK, exchangeRes)
 }
 
 // @Summary Обмен валют
@@ -64,16 +64,22 @@ func (s *ServerWallet) ExchangeRates(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param Authorization header string true "Bearer JWT_TOKEN"
+// @Param X-TOTP-Code header string false "TOTP-код для сумм от порога two_factor.threshold"
 // @Param exchange body ExchangeForCurrencyReq true "Данные для обмена валют"
 // @Success 200 {object} ExchangeResponseForCurrency "Successfully exchanged currency"
-// @Failure 400 {object} ErrorResponse "Error decoding currency request"
-// @Failure 400 {object} ErrorResponse "Insufficient funds or invalid amount"
-// @Failure 400 {object} ErrorResponse "Amount cannot have more than two decimal places"
-// @Failure 500 {object} ErrorResponse "Error fetching exchange rate"
-// @Failure 500 {object} ErrorResponse "Error exchanging currency"
+// @Failure 400 {object} render.ErrorResponse "Error decoding currency request"
+// @Failure 400 {object} render.ErrorResponse "Insufficient funds or invalid amount"
+// @Failure 400 {object} render.ErrorResponse "Invalid input: unknown currency or non-positive amount"
+// @Failure 400 {object} render.ErrorResponse "Amount cannot have more than two decimal places"
+// @Failure 401 {object} render.ErrorResponse "TOTP code required"
+// @Failure 403 {object} render.ErrorResponse "Account is frozen or closed"
+// @Failure 422 {object} render.ErrorResponse "Limit exceeded"
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Error fetching exchange rate"
+// @Failure 503 {object} render.ErrorResponse "Exchange service is unavailable"
+// @Failure 500 {object} render.ErrorResponse "Error exchanging currency"
 // @Router /exchange [post]
 func (s *ServerWallet) ExchangeRatesForCurrency(w http.ResponseWriter, r *http.Request) {
-	errRes := new(ErrorResponse)
 	s.lg.InfoCtx(r.Context(), "Exchange rates for currency")
 	reqId := r.Context().Value("requestID").(string)
 	user_id := r.Context().Value(middleware.User_id).(int)
@@ -84,16 +90,26 @@ func (s *ServerWallet) ExchangeRatesForCurrency(w http.ResponseWriter, r *http.R
 
 	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
 		s.lg.ErrorCtx(ctx, fmt.Sprintf("error decoding json: %v", err))
-		errRes.message = "Error decoding currency request"
-		json.NewEncoder(w).Encode(errRes)
-		http.Error(w, "Error decoding currency request", http.StatusBadRequest)
+		render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Error decoding currency request")
+		return
+	}
+	fields := validateAmount("from_currency", req.From, req.Amount)
+	if !storages.ValidCurrency(req.To) {
+		fields = append(fields, validation.FieldError{Field: "to_currency", Message: "must be one of USD, RUB, EUR"})
+	} else if req.To == req.From {
+		fields = append(fields, validation.FieldError{Field: "to_currency", Message: "must differ from from_currency"})
+	}
+	if len(fields) > 0 {
+		s.lg.ErrorCtx(ctx, "Invalid amount or currency")
+		writeValidationError(w, r, fields)
 		return
 	}
 	if req.Amount.Exponent() < -2 {
 		s.lg.ErrorCtx(r.Context(), "Amount cannot have more than two decimal places")
-		errRes.message = "Amount cannot have more than two decimal places"
-		json.NewEncoder(w).Encode(errRes)
-		http.Error(w, "Amount cannot have more than two decimal places", http.StatusBadRequest)
+		render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Amount cannot have more than two decimal places")
+		return
+	}
+	if !s.requireTOTP(w, r, user_id, req.From, req.Amount) {
 		return
 	}
 	in.FromCurrency = req.From
@@ -101,33 +117,32 @@ func (s *ServerWallet) ExchangeRatesForCurrency(w http.ResponseWriter, r *http.R
 	resp, err := s.grpcclient.GetExchangeRateForCurrency(ctx, in)
 	if err != nil {
 		s.lg.ErrorCtx(ctx, fmt.Sprintf("error getting exchange rate: %v", err))
-		errRes.message = "Error fetching exchange rate"
-		json.NewEncoder(w).Encode(errRes)
-		http.Error(w, "Error fetching exchange rate", http.StatusInternalServerError)
+		writeExchangerError(w, r, err, "Error fetching exchange rate")
 		return
 	}
 	exchangeRes := new(ExchangeResponseForCurrency)
 	mapres, err := s.db.ExchangeForCurrency(ctx, req.From, req.To, req.Amount, resp.Rate, user_id)
 	if err != nil {
-		if err == storages.ErrExch {
+		if writeLimitError(w, r, err) {
+			s.lg.ErrorCtx(ctx, fmt.Sprintf("exchange refused: %v", err))
+			return
+		} else if err == storages.ErrExch {
 			s.lg.ErrorCtx(ctx, fmt.Sprintf("error : %v", err))
-			errRes.message = "Insufficient funds or invalid amount"
-			json.NewEncoder(w).Encode(errRes)
-			http.Error(w, "Insufficient funds or invalid amount", http.StatusBadRequest)
+			render.Error(w, r, http.StatusBadRequest, render.CodeInsufficientFunds, "Insufficient funds or invalid amount")
+			return
+		} else if err == storages.ErrInactive {
+			s.lg.ErrorCtx(ctx, fmt.Sprintf("exchange refused: %v", err))
+			render.Error(w, r, http.StatusForbidden, render.CodeAccountInactive, "Account is frozen or closed")
 			return
 		} else {
 			s.lg.ErrorCtx(ctx, fmt.Sprintf("error exchanging currency: %v", err))
-			errRes.message = "Error exchanging currency"
-			json.NewEncoder(w).Encode(errRes)
-			http.Error(w, "Error exchanging currency", http.StatusInternalServerError)
+			render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Error exchanging currency")
 			return
 		}
 	}
 	exchangeRes.New_balance = mapres
 	exchangeRes.Amount = req.Amount
 	exchangeRes.Message = "Successfully exchanged currency"
-	w.WriteHeader(http.StatusOK)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(exchangeRes)
+	render.JSON(w, http.StatusOK, exchangeRes)
 	s.lg.InfoCtx(ctx, fmt.Sprintf("User newbalance %v ", exchangeRes.New_balance))
 }
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gw-currency-wallet/internal/middleware"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestExchangeRejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedBody string
+	}{
+		{
+			name: "Negative amount",
+			body: `{"from_currency":"USD","to_currency":"EUR","amount":-100}`,
+			expectedBody: `{"code":"validation_failed","message":"Invalid input","request_id":"req-1",
+				"details":[{"field":"amount","message":"must be positive"}]}`,
+		},
+		{
+			name: "Unknown currencies",
+			body: `{"from_currency":"GBP","to_currency":"JPY","amount":10}`,
+			expectedBody: `{"code":"validation_failed","message":"Invalid input","request_id":"req-1",
+				"details":[{"field":"from_currency","message":"must be one of USD, RUB, EUR"},{"field":"to_currency","message":"must be one of USD, RUB, EUR"}]}`,
+		},
+		{
+			name: "Same currency",
+			body: `{"from_currency":"USD","to_currency":"USD","amount":10}`,
+			expectedBody: `{"code":"validation_failed","message":"Invalid input","request_id":"req-1",
+				"details":[{"field":"to_currency","message":"must differ from from_currency"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockLogger := new(MockLogger)
+			mockLogger.On("InfoCtx", mock.Anything, mock.Anything).Return(nil)
+			mockLogger.On("ErrorCtx", mock.Anything, mock.Anything).Return(nil)
+			s := &ServerWallet{db: mockRepo, lg: mockLogger}
+
+			req := httptest.NewRequest(http.MethodPost, "/exchange", bytes.NewBufferString(tt.body))
+			ctx := context.WithValue(req.Context(), middleware.User_id, 1)
+			ctx = context.WithValue(ctx, "requestID", "req-1")
+			w := httptest.NewRecorder()
+
+			s.ExchangeRatesForCurrency(w, req.WithContext(ctx))
+
+			assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			mockRepo.AssertNotCalled(t, "ExchangeForCurrency", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
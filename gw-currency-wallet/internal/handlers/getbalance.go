@@ -1,33 +1,125 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"gw-currency-wallet/api/rates"
 	"gw-currency-wallet/internal/middleware"
+	"gw-currency-wallet/internal/render"
+	"gw-currency-wallet/internal/storages"
+	"gw-currency-wallet/internal/validation"
 	"net/http"
+	"slices"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
+// ratesSource — префикс источника курсов в ответе оценки баланса.
+const ratesSource = "gw-exchanger"
+
+// pairRater — пакетный запрос курсов к gw-exchanger.
+type pairRater interface {
+	GetExchangeRatesForPairs(ctx context.Context, in *rates.PairsRequest, opts ...grpc.CallOption) (*rates.PairsResponse, error)
+}
+
+// BalanceValuation — баланс, пересчитанный в базовую валюту Base.
+// Converted и Rates — по валютам кошелька, Total — сумма Converted.
+type BalanceValuation struct {
+	Base        string                     `json:"base"`
+	Balance     storages.Balance           `json:"balance"`
+	Converted   map[string]decimal.Decimal `json:"converted"`
+	Rates       map[string]decimal.Decimal `json:"rates"`
+	Total       decimal.Decimal            `json:"total"`
+	RatesAsOf   time.Time                  `json:"rates_as_of"`
+	RatesSource string                     `json:"rates_source"`
+}
+
 // @Summary Получение баланса пользователя
 // @Description Позволяет пользователю получить информацию о своем балансе по всем валютам.
+// @Description held — сумма действующих холдов, available — остаток за их вычетом, доступный для вывода и обмена.
+// @Description С параметром in дополнительно пересчитывает каждую валюту и итог в базовую валюту по курсам gw-exchanger.
 // @Tags wallet
 // @Accept json
 // @Produce json
 // @Param Authorization header string true "Bearer JWT_TOKEN"
-// @Success 200 {object} storages.Balance
-// @Failure 500 {object} ErrorResponse "Could not get balance"
+// @Param in query string false "Базовая валюта оценки: USD, RUB или EUR"
+// @Success 200 {object} BalanceValuation "С параметром in; без него — storages.Balance"
+// @Failure 400 {object} render.ErrorResponse "Invalid input"
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Could not get balance"
+// @Failure 503 {object} render.ErrorResponse "Exchange service is unavailable"
 // @Router /balance [get]
 func (s *ServerWallet) GetBalance(w http.ResponseWriter, r *http.Request) {
 	user_id := r.Context().Value(middleware.User_id).(int)
-	errRes := new(ErrorResponse)
+	base := r.URL.Query().Get("in")
+	if r.URL.Query().Has("in") && !slices.Contains(storages.Currencies, base) {
+		writeValidationError(w, r, []validation.FieldError{{Field: "in", Message: "must be one of USD, RUB, EUR"}})
+		return
+	}
 	balance, err := s.db.GetBalance(user_id, r.Context())
 	if err != nil {
 		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("error getting balance: %v", err))
-		errRes.message = "Could not get balance"
-		json.NewEncoder(w).Encode(errRes)
-		http.Error(w, "Could not get balance", http.StatusInternalServerError)
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Could not get balance")
+		return
+	}
+	if base == "" {
+		render.JSON(w, http.StatusOK, balance)
+		s.lg.InfoCtx(r.Context(), fmt.Sprintf("User %d requested their balance", user_id))
+		return
+	}
+
+	valuation, err := s.valueBalance(r.Context(), balance, base)
+	if err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("error valuing balance in %s: %v", base, err))
+		writeExchangerError(w, r, err, "Could not get exchange rates")
 		return
 	}
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(balance)
-	s.lg.InfoCtx(r.Context(), fmt.Sprintf("User %d requested their balance", user_id))
+	render.JSON(w, http.StatusOK, valuation)
+	s.lg.InfoCtx(r.Context(), fmt.Sprintf("User %d requested their balance in %s", user_id, base))
+}
+
+// valueBalance пересчитывает баланс в base по курсам одного снимка gw-exchanger.
+// Каждая сумма округляется до копеек, итог складывается из округленных сумм.
+func (s *ServerWallet) valueBalance(ctx context.Context, balance storages.Balance, base string) (*BalanceValuation, error) {
+	req := new(rates.PairsRequest)
+	for _, c := range storages.Currencies {
+		if c != base {
+			req.Pairs = append(req.Pairs, &rates.CurrencyPair{FromCurrency: c, ToCurrency: base})
+		}
+	}
+	if reqId, ok := ctx.Value("requestID").(string); ok {
+		ctx = metadata.AppendToOutgoingContext(ctx, "requestID", reqId)
+	}
+	res, err := s.rates.GetExchangeRatesForPairs(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &BalanceValuation{
+		Base:        base,
+		Balance:     balance,
+		Converted:   make(map[string]decimal.Decimal),
+		Rates:       map[string]decimal.Decimal{base: decimal.NewFromInt(1)},
+		RatesAsOf:   res.AsOf.AsTime(),
+		RatesSource: ratesSource + "/" + res.Source,
+	}
+	for _, p := range res.Rates {
+		if p.Error != "" {
+			return nil, fmt.Errorf("rate %s/%s: %s", p.FromCurrency, p.ToCurrency, p.Error)
+		}
+		v.Rates[p.FromCurrency] = decimal.NewFromFloat32(p.Rate)
+	}
+	for currency, amount := range balance.Amounts() {
+		rate, ok := v.Rates[currency]
+		if !ok {
+			return nil, fmt.Errorf("no rate for %s/%s", currency, base)
+		}
+		converted := amount.Mul(rate).Round(2)
+		v.Converted[currency] = converted
+		v.Total = v.Total.Add(converted)
+	}
+	return v, nil
 }
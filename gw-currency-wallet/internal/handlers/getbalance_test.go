@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gw-currency-wallet/api/rates"
+	"gw-currency-wallet/internal/middleware"
+	"gw-currency-wallet/internal/storages"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type fakeRates struct {
+	rates map[string]float32 // курс к базовой валюте, ключ — from_currency
+	errs  map[string]string
+	err   error
+	req   *rates.PairsRequest
+}
+
+func (f *fakeRates) GetExchangeRatesForPairs(ctx context.Context, in *rates.PairsRequest, opts ...grpc.CallOption) (*rates.PairsResponse, error) {
+	f.req = in
+	if f.err != nil {
+		return nil, f.err
+	}
+	res := &rates.PairsResponse{AsOf: timestamppb.New(time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)), Source: "cache"}
+	for _, p := range in.Pairs {
+		res.Rates = append(res.Rates, &rates.PairRate{
+			FromCurrency: p.FromCurrency,
+			ToCurrency:   p.ToCurrency,
+			Rate:         f.rates[p.FromCurrency],
+			Error:        f.errs[p.FromCurrency],
+		})
+	}
+	return res, nil
+}
+
+func TestGetBalance(t *testing.T) {
+	balance := storages.Balance{
+		USD: decimal.NewFromInt(100),
+		RUB: decimal.NewFromInt(1000),
+		EUR: decimal.RequireFromString("10.50"),
+	}
+	tests := []struct {
+		name           string
+		query          string
+		rates          *fakeRates
+		expectBalance  bool
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Without base currency",
+			rates:          &fakeRates{},
+			expectBalance:  true,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"USD":"100","RUB":"1000","EUR":"10.5"}`,
+		},
+		{
+			name:           "Valued in USD",
+			query:          "?in=USD",
+			rates:          &fakeRates{rates: map[string]float32{"RUB": 0.011, "EUR": 1.05}},
+			expectBalance:  true,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{
+				"base":"USD",
+				"balance":{"USD":"100","RUB":"1000","EUR":"10.5"},
+				"converted":{"USD":"100","RUB":"11","EUR":"11.03"},
+				"rates":{"USD":"1","RUB":"0.011","EUR":"1.05"},
+				"total":"122.03",
+				"rates_as_of":"2025-03-01T12:00:00Z",
+				"rates_source":"gw-exchanger/cache"}`,
+		},
+		{
+			name:           "Unknown base currency",
+			query:          "?in=GBP",
+			rates:          &fakeRates{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Exchanger unavailable",
+			query:          "?in=EUR",
+			rates:          &fakeRates{err: status.Error(codes.Unavailable, "down")},
+			expectBalance:  true,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "Rate missing for one currency",
+			query:          "?in=EUR",
+			rates:          &fakeRates{rates: map[string]float32{"USD": 0.95}, errs: map[string]string{"RUB": "unknown or disabled currency"}},
+			expectBalance:  true,
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockLogger := new(MockLogger)
+			if tt.expectBalance {
+				mockRepo.On("GetBalance", 1, mock.Anything).Return(balance, nil)
+			}
+			mockLogger.On("InfoCtx", mock.Anything, mock.Anything).Return(nil).Maybe()
+			mockLogger.On("ErrorCtx", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			s := &ServerWallet{db: mockRepo, lg: mockLogger, rates: tt.rates}
+			req := httptest.NewRequest(http.MethodGet, "/balance"+tt.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.User_id, 1))
+			w := httptest.NewRecorder()
+
+			s.GetBalance(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetBalanceRequestsPairsToBase(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockLogger := new(MockLogger)
+	mockRepo.On("GetBalance", 1, mock.Anything).Return(storages.Balance{}, nil)
+	mockLogger.On("InfoCtx", mock.Anything, mock.Anything).Return(nil)
+	fr := &fakeRates{rates: map[string]float32{"USD": 90, "EUR": 95}}
+
+	s := &ServerWallet{db: mockRepo, lg: mockLogger, rates: fr}
+	req := httptest.NewRequest(http.MethodGet, "/balance?in=RUB", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.User_id, 1))
+	s.GetBalance(httptest.NewRecorder(), req)
+
+	if assert.Len(t, fr.req.Pairs, 2) {
+		assert.Equal(t, "USD", fr.req.Pairs[0].FromCurrency)
+		assert.Equal(t, "EUR", fr.req.Pairs[1].FromCurrency)
+		assert.Equal(t, "RUB", fr.req.Pairs[0].ToCurrency)
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"gw-currency-wallet/internal/render"
+	"net/http"
+	"time"
+
+	exchange "github.com/IlyaBroo/exchange_grpc/exchange"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+type ReadinessResponse struct {
+	Status    string `json:"status"`
+	Database  string `json:"database"`
+	Exchanger string `json:"exchanger"`
+}
+
+// healthChecker — часть клиента grpc.health.v1, которой достаточно для проверки готовности.
+type healthChecker interface {
+	Check(ctx context.Context, in *healthpb.HealthCheckRequest, opts ...grpc.CallOption) (*healthpb.HealthCheckResponse, error)
+}
+
+const (
+	statusReady       = "ready"
+	statusUnavailable = "unavailable"
+	statusOK          = "ok"
+
+	readinessTimeout = 2 * time.Second
+)
+
+// @Summary Проверка живости
+// @Description Отвечает 200, пока процесс обрабатывает HTTP-запросы. Зависимости не проверяются.
+// @Tags health
+// @Produce json
+// @Success 200 {object} HealthResponse
+// @Router /healthz [get]
+func (s *ServerWallet) Healthz(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, http.StatusOK, HealthResponse{Status: statusOK})
+}
+
+// @Summary Готовность сервиса
+// @Description Проверяет пул соединений с базой и gRPC-соединение с gw-exchanger. Пока одна из зависимостей недоступна, сервис отвечает 503 и не получает трафик.
+// @Tags health
+// @Produce json
+// @Success 200 {object} ReadinessResponse
+// @Failure 503 {object} ReadinessResponse
+// @Router /readyz [get]
+func (s *ServerWallet) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	res := ReadinessResponse{Status: statusReady, Database: statusOK, Exchanger: statusOK}
+	if err := s.db.Ready(ctx); err != nil {
+		s.lg.WarnCtx(ctx, fmt.Sprintf("readiness: database is not ready: %v", err))
+		res.Status = statusUnavailable
+		res.Database = statusUnavailable
+	}
+	if err := s.exchangerReady(ctx); err != nil {
+		s.lg.WarnCtx(ctx, fmt.Sprintf("readiness: exchanger is not ready: %v", err))
+		res.Status = statusUnavailable
+		res.Exchanger = statusUnavailable
+	}
+	if res.Status != statusReady {
+		render.JSON(w, http.StatusServiceUnavailable, res)
+		return
+	}
+	render.JSON(w, http.StatusOK, res)
+}
+
+func (s *ServerWallet) exchangerReady(ctx context.Context) error {
+	res, err := s.exchangerHealth.Check(ctx, &healthpb.HealthCheckRequest{Service: exchange.ExchangeService_ServiceDesc.ServiceName})
+	if err != nil {
+		return err
+	}
+	if res.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("exchanger status is %s", res.Status)
+	}
+	return nil
+}
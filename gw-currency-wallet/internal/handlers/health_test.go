@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type fakeHealth struct {
+	status healthpb.HealthCheckResponse_ServingStatus
+	err    error
+}
+
+func (f fakeHealth) Check(ctx context.Context, in *healthpb.HealthCheckRequest, opts ...grpc.CallOption) (*healthpb.HealthCheckResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &healthpb.HealthCheckResponse{Status: f.status}, nil
+}
+
+func TestHealthz(t *testing.T) {
+	s := &ServerWallet{}
+	w := httptest.NewRecorder()
+	s.Healthz(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestReadyz(t *testing.T) {
+	serving := fakeHealth{status: healthpb.HealthCheckResponse_SERVING}
+	tests := []struct {
+		name           string
+		dbErr          error
+		exchanger      fakeHealth
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "All dependencies are up",
+			exchanger:      serving,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"ready","database":"ok","exchanger":"ok"}`,
+		},
+		{
+			name:           "Database is down",
+			dbErr:          errors.New("connection refused"),
+			exchanger:      serving,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `{"status":"unavailable","database":"unavailable","exchanger":"ok"}`,
+		},
+		{
+			name:           "Exchanger is not serving",
+			exchanger:      fakeHealth{status: healthpb.HealthCheckResponse_NOT_SERVING},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `{"status":"unavailable","database":"ok","exchanger":"unavailable"}`,
+		},
+		{
+			name:           "Exchanger is unreachable",
+			exchanger:      fakeHealth{err: errors.New("connection refused")},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `{"status":"unavailable","database":"ok","exchanger":"unavailable"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockLogger := new(MockLogger)
+			mockRepo.On("Ready", mock.Anything).Return(tt.dbErr)
+			mockLogger.On("WarnCtx", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			s := &ServerWallet{db: mockRepo, lg: mockLogger, exchangerHealth: tt.exchanger}
+			w := httptest.NewRecorder()
+			s.Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/middleware"
+	"gw-currency-wallet/internal/render"
+	"gw-currency-wallet/internal/storages"
+	"gw-currency-wallet/internal/validation"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	defaultHoldTTL     = 15 * time.Minute
+	maxHoldReferenceLn = 128
+)
+
+// CreateHoldRequest — резерв Amount в Currency на Ttl_sec секунд.
+// Reference — произвольный идентификатор заказа вызывающей стороны.
+type CreateHoldRequest struct {
+	Currency  string          `json:"currency"`
+	Amount    decimal.Decimal `json:"amount"`
+	Reference string          `json:"reference,omitempty"`
+	Ttl_sec   int             `json:"ttl_sec,omitempty"`
+}
+
+// CaptureHoldRequest — сумма списания. Без Amount списывается весь холд.
+type CaptureHoldRequest struct {
+	Amount decimal.Decimal `json:"amount"`
+}
+
+type HoldsResponse struct {
+	Holds []storages.Hold `json:"holds"`
+}
+
+var holdStatuses = []string{storages.HoldActive, storages.HoldCaptured, storages.HoldReleased, storages.HoldExpired}
+
+// @Summary Создание холда
+// @Description Резервирует сумму на кошельке: она остается в балансе, но недоступна для вывода, обмена и новых холдов,
+// @Description пока холд не списан, не снят или не истек. Холд проверяется по лимитам вывода и сразу засчитывается в них.
+// @Tags holds
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer JWT_TOKEN"
+// @Param X-TOTP-Code header string false "TOTP-код для сумм от порога two_factor.threshold"
+// @Param hold body CreateHoldRequest true "Данные холда"
+// @Success 201 {object} storages.Hold
+// @Failure 400 {object} render.ErrorResponse "Invalid input"
+// @Failure 400 {object} render.ErrorResponse "Insufficient funds or invalid amount"
+// @Failure 401 {object} render.ErrorResponse "TOTP code required"
+// @Failure 403 {object} render.ErrorResponse "Account is frozen or closed"
+// @Failure 422 {object} render.ErrorResponse "Limit exceeded"
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Error creating hold"
+// @Router /holds [post]
+func (s *ServerWallet) CreateHold(w http.ResponseWriter, r *http.Request) {
+	var req CreateHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error decoding: %v", err))
+		render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Error decoding hold request")
+		return
+	}
+	if fields := s.validateHold(req); len(fields) > 0 {
+		writeValidationError(w, r, fields)
+		return
+	}
+
+	user_id := r.Context().Value(middleware.User_id).(int)
+	if !s.requireTOTP(w, r, user_id, req.Currency, req.Amount) {
+		return
+	}
+
+	ttl := time.Duration(req.Ttl_sec) * time.Second
+	if ttl == 0 {
+		ttl = s.defaultHoldTTL()
+	}
+	hold, err := s.db.CreateHold(r.Context(), storages.Hold{
+		UserID:    user_id,
+		Currency:  req.Currency,
+		Amount:    req.Amount,
+		Reference: req.Reference,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		if writeLimitError(w, r, err) {
+			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("hold refused: %v", err))
+		} else if err == storages.ErrWithdraw {
+			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("hold refused: %v", err))
+			render.Error(w, r, http.StatusBadRequest, render.CodeInsufficientFunds, "Insufficient funds or invalid amount")
+		} else if err == storages.ErrInactive {
+			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("hold refused: %v", err))
+			render.Error(w, r, http.StatusForbidden, render.CodeAccountInactive, "Account is frozen or closed")
+		} else {
+			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error creating hold: %v", err))
+			render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Error creating hold")
+		}
+		return
+	}
+	render.JSON(w, http.StatusCreated, hold)
+	s.lg.InfoCtx(r.Context(), fmt.Sprintf("User %d created hold %d", user_id, hold.ID))
+}
+
+// @Summary Список холдов
+// @Description Возвращает холды пользователя, новые первыми.
+// @Tags holds
+// @Produce json
+// @Param Authorization header string true "Bearer JWT_TOKEN"
+// @Param status query string false "active, captured, released или expired"
+// @Success 200 {object} HoldsResponse
+// @Failure 400 {object} render.ErrorResponse "Invalid input"
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Could not list holds"
+// @Router /holds [get]
+func (s *ServerWallet) ListHolds(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && !slices.Contains(holdStatuses, status) {
+		writeValidationError(w, r, []validation.FieldError{{Field: "status", Message: "must be one of active, captured, released, expired"}})
+		return
+	}
+	user_id := r.Context().Value(middleware.User_id).(int)
+	holds, err := s.db.ListHolds(r.Context(), user_id, status)
+	if err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("error listing holds: %v", err))
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Could not list holds")
+		return
+	}
+	if holds == nil {
+		holds = []storages.Hold{}
+	}
+	render.JSON(w, http.StatusOK, HoldsResponse{Holds: holds})
+}
+
+// @Summary Списание холда
+// @Description Списывает с кошелька весь холд или его часть; остаток холда освобождается.
+// @Tags holds
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer JWT_TOKEN"
+// @Param id path int true "ID холда"
+// @Param capture body CaptureHoldRequest false "Сумма списания"
+// @Success 200 {object} storages.Hold
+// @Failure 400 {object} render.ErrorResponse "Invalid input"
+// @Failure 403 {object} render.ErrorResponse "Account is frozen or closed"
+// @Failure 404 {object} render.ErrorResponse "Hold not found, closed or expired"
+// @Failure 422 {object} render.ErrorResponse "Capture amount exceeds hold"
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Error capturing hold"
+// @Router /holds/{id}/capture [post]
+func (s *ServerWallet) CaptureHold(w http.ResponseWriter, r *http.Request) {
+	id, ok := holdID(w, r)
+	if !ok {
+		return
+	}
+	var req CaptureHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Error decoding capture request")
+		return
+	}
+	if req.Amount.IsNegative() || req.Amount.Exponent() < -2 {
+		writeValidationError(w, r, []validation.FieldError{{Field: "amount", Message: "must be positive with at most two decimal places"}})
+		return
+	}
+
+	user_id := r.Context().Value(middleware.User_id).(int)
+	hold, err := s.db.CaptureHold(r.Context(), user_id, id, req.Amount)
+	switch {
+	case err == storages.ErrNoHold:
+		render.Error(w, r, http.StatusNotFound, render.CodeNotFound, "Hold not found, closed or expired")
+	case err == storages.ErrHoldAmount:
+		render.Error(w, r, http.StatusUnprocessableEntity, render.CodeInvalidInput, "Capture amount exceeds hold")
+	case err == storages.ErrInactive:
+		render.Error(w, r, http.StatusForbidden, render.CodeAccountInactive, "Account is frozen or closed")
+	case err != nil:
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error capturing hold: %v", err))
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Error capturing hold")
+	default:
+		render.JSON(w, http.StatusOK, hold)
+		s.lg.InfoCtx(r.Context(), fmt.Sprintf("User %d captured hold %d", user_id, id))
+	}
+}
+
+// @Summary Снятие холда
+// @Description Освобождает действующий холд без списания.
+// @Tags holds
+// @Produce json
+// @Param Authorization header string true "Bearer JWT_TOKEN"
+// @Param id path int true "ID холда"
+// @Success 200 {object} storages.Hold
+// @Failure 400 {object} render.ErrorResponse "Invalid hold id"
+// @Failure 404 {object} render.ErrorResponse "Hold not found, closed or expired"
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Error releasing hold"
+// @Router /holds/{id}/release [post]
+func (s *ServerWallet) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	id, ok := holdID(w, r)
+	if !ok {
+		return
+	}
+	user_id := r.Context().Value(middleware.User_id).(int)
+	hold, err := s.db.ReleaseHold(r.Context(), user_id, id)
+	if err == storages.ErrNoHold {
+		render.Error(w, r, http.StatusNotFound, render.CodeNotFound, "Hold not found, closed or expired")
+		return
+	}
+	if err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error releasing hold: %v", err))
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Error releasing hold")
+		return
+	}
+	render.JSON(w, http.StatusOK, hold)
+	s.lg.InfoCtx(r.Context(), fmt.Sprintf("User %d released hold %d", user_id, id))
+}
+
+func holdID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Invalid hold id")
+		return 0, false
+	}
+	return id, true
+}
+
+func (s *ServerWallet) defaultHoldTTL() time.Duration {
+	if s.holds.Default_ttl_sec > 0 {
+		return time.Duration(s.holds.Default_ttl_sec) * time.Second
+	}
+	return defaultHoldTTL
+}
+
+func (s *ServerWallet) validateHold(req CreateHoldRequest) []validation.FieldError {
+	var fields []validation.FieldError
+	if !storages.ValidCurrency(req.Currency) {
+		fields = append(fields, validation.FieldError{Field: "currency", Message: "must be one of USD, RUB, EUR"})
+	}
+	if !req.Amount.IsPositive() || req.Amount.Exponent() < -2 {
+		fields = append(fields, validation.FieldError{Field: "amount", Message: "must be positive with at most two decimal places"})
+	}
+	if len(req.Reference) > maxHoldReferenceLn {
+		fields = append(fields, validation.FieldError{Field: "reference", Message: fmt.Sprintf("must be at most %d characters", maxHoldReferenceLn)})
+	}
+	if req.Ttl_sec < 0 {
+		fields = append(fields, validation.FieldError{Field: "ttl_sec", Message: "must be positive"})
+	} else if s.holds.Max_ttl_sec > 0 && req.Ttl_sec > s.holds.Max_ttl_sec {
+		fields = append(fields, validation.FieldError{Field: "ttl_sec", Message: fmt.Sprintf("must be at most %d", s.holds.Max_ttl_sec)})
+	}
+	return fields
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gw-currency-wallet/internal/config"
+	"gw-currency-wallet/internal/middleware"
+	"gw-currency-wallet/internal/storages"
+
+	"github.com/go-chi/chi"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateHold(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		mockRepo       func(m *MockRepository)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Hold created with default ttl",
+			body: `{"currency":"USD","amount":"40","reference":"checkout-42"}`,
+			mockRepo: func(m *MockRepository) {
+				m.On("CreateHold", mock.Anything, mock.MatchedBy(func(h storages.Hold) bool {
+					ttl := time.Until(h.ExpiresAt)
+					return h.UserID == 1 && h.Currency == "USD" && h.Amount.Equal(decimal.NewFromInt(40)) &&
+						h.Reference == "checkout-42" && ttl > 14*time.Minute && ttl <= 15*time.Minute
+				})).Return(storages.Hold{ID: 3, Currency: "USD", Amount: decimal.NewFromInt(40), Status: storages.HoldActive}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Ttl above maximum",
+			body:           `{"currency":"USD","amount":"40","ttl_sec":7200}`,
+			mockRepo:       func(m *MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"code":"validation_failed","message":"Invalid input",
+				"details":[{"field":"ttl_sec","message":"must be at most 3600"}]}`,
+		},
+		{
+			name: "Amount above available balance",
+			body: `{"currency":"USD","amount":"40"}`,
+			mockRepo: func(m *MockRepository) {
+				m.On("CreateHold", mock.Anything, mock.Anything).Return(storages.Hold{}, storages.ErrWithdraw)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"code":"insufficient_funds","message":"Insufficient funds or invalid amount"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockLogger := new(MockLogger)
+			tt.mockRepo(mockRepo)
+			mockLogger.On("InfoCtx", mock.Anything, mock.Anything).Return(nil)
+			mockLogger.On("ErrorCtx", mock.Anything, mock.Anything).Return(nil)
+			s := &ServerWallet{db: mockRepo, lg: mockLogger, holds: config.Holds{Default_ttl_sec: 900, Max_ttl_sec: 3600}}
+
+			req := httptest.NewRequest(http.MethodPost, "/holds", bytes.NewBufferString(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), middleware.User_id, 1))
+			w := httptest.NewRecorder()
+
+			s.CreateHold(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCaptureHold(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		amount         decimal.Decimal
+		err            error
+		expectedStatus int
+	}{
+		{name: "Full capture without body", amount: decimal.Zero, expectedStatus: http.StatusOK},
+		{name: "Partial capture", body: `{"amount":"25"}`, amount: decimal.NewFromInt(25), expectedStatus: http.StatusOK},
+		{name: "More than held", body: `{"amount":"50"}`, amount: decimal.NewFromInt(50), err: storages.ErrHoldAmount, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Expired", amount: decimal.Zero, err: storages.ErrNoHold, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockLogger := new(MockLogger)
+			mockRepo.On("CaptureHold", mock.Anything, 1, int64(3), mock.MatchedBy(tt.amount.Equal)).
+				Return(storages.Hold{ID: 3, Status: storages.HoldCaptured}, tt.err)
+			mockLogger.On("InfoCtx", mock.Anything, mock.Anything).Return(nil)
+			s := &ServerWallet{db: mockRepo, lg: mockLogger}
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "3")
+			ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+			req := httptest.NewRequest(http.MethodPost, "/holds/3/capture", bytes.NewBufferString(tt.body))
+			req = req.WithContext(context.WithValue(ctx, middleware.User_id, 1))
+			w := httptest.NewRecorder()
+
+			s.CaptureHold(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"gw-currency-wallet/internal/middleware"
+	"gw-currency-wallet/internal/render"
+	"gw-currency-wallet/internal/storages"
+	"gw-currency-wallet/internal/validation"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/shopspring/decimal"
+)
+
+// PlaceOrderRequest — лимитная заявка: обменять Amount из From в To, когда
+// курс gw-exchanger станет не ниже TargetRate. Без ExpiresAt заявка бессрочна.
+type PlaceOrderRequest struct {
+	From       string          `json:"from_currency"`
+	To         string          `json:"to_currency"`
+	Amount     decimal.Decimal `json:"amount"`
+	TargetRate decimal.Decimal `json:"target_rate"`
+	ExpiresAt  *time.Time      `json:"expires_at,omitempty"`
+}
+
+type OrdersResponse struct {
+	Orders []storages.Order `json:"orders"`
+}
+
+var orderStatuses = []string{storages.OrderOpen, storages.OrderFilled, storages.OrderCancelled, storages.OrderExpired}
+
+// @Summary Создание лимитной заявки на обмен
+// @Description Списывает сумму заявки с кошелька и держит ее в резерве, пока курс не достигнет целевого,
+// @Description заявку не отменят или не истечет ее срок. Резерв сразу засчитывается в дневной лимит обмена.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer JWT_TOKEN"
+// @Param X-TOTP-Code header string false "TOTP-код для сумм от порога two_factor.threshold"
+// @Param order body PlaceOrderRequest true "Данные заявки"
+// @Success 201 {object} storages.Order
+// @Failure 400 {object} render.ErrorResponse "Invalid input"
+// @Failure 400 {object} render.ErrorResponse "Insufficient funds or invalid amount"
+// @Failure 401 {object} render.ErrorResponse "TOTP code required"
+// @Failure 403 {object} render.ErrorResponse "Account is frozen or closed"
+// @Failure 422 {object} render.ErrorResponse "Limit exceeded"
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Error placing order"
+// @Router /orders [post]
+func (s *ServerWallet) PlaceOrder(w http.ResponseWriter, r *http.Request) {
+	var req PlaceOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error decoding: %v", err))
+		render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Error decoding order request")
+		return
+	}
+	if fields := validateOrder(req, time.Now()); len(fields) > 0 {
+		writeValidationError(w, r, fields)
+		return
+	}
+
+	user_id := r.Context().Value(middleware.User_id).(int)
+	if !s.requireTOTP(w, r, user_id, req.From, req.Amount) {
+		return
+	}
+
+	order, err := s.db.PlaceOrder(r.Context(), storages.Order{
+		UserID:     user_id,
+		From:       req.From,
+		To:         req.To,
+		Amount:     req.Amount,
+		TargetRate: req.TargetRate,
+		ExpiresAt:  req.ExpiresAt,
+	})
+	if err != nil {
+		if writeLimitError(w, r, err) {
+			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("order refused: %v", err))
+		} else if err == storages.ErrExch {
+			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("order refused: %v", err))
+			render.Error(w, r, http.StatusBadRequest, render.CodeInsufficientFunds, "Insufficient funds or invalid amount")
+		} else if err == storages.ErrInactive {
+			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("order refused: %v", err))
+			render.Error(w, r, http.StatusForbidden, render.CodeAccountInactive, "Account is frozen or closed")
+		} else {
+			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error placing order: %v", err))
+			render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Error placing order")
+		}
+		return
+	}
+	render.JSON(w, http.StatusCreated, order)
+	s.lg.InfoCtx(r.Context(), fmt.Sprintf("User %d placed order %d", user_id, order.ID))
+}
+
+// @Summary Список лимитных заявок
+// @Description Возвращает заявки пользователя, новые первыми.
+// @Tags orders
+// @Produce json
+// @Param Authorization header string true "Bearer JWT_TOKEN"
+// @Param status query string false "open, filled, cancelled или expired"
+// @Success 200 {object} OrdersResponse
+// @Failure 400 {object} render.ErrorResponse "Invalid input"
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Could not list orders"
+// @Router /orders [get]
+func (s *ServerWallet) ListOrders(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && !slices.Contains(orderStatuses, status) {
+		writeValidationError(w, r, []validation.FieldError{{Field: "status", Message: "must be one of open, filled, cancelled, expired"}})
+		return
+	}
+	user_id := r.Context().Value(middleware.User_id).(int)
+	orders, err := s.db.ListOrders(r.Context(), user_id, status)
+	if err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("error listing orders: %v", err))
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Could not list orders")
+		return
+	}
+	if orders == nil {
+		orders = []storages.Order{}
+	}
+	render.JSON(w, http.StatusOK, OrdersResponse{Orders: orders})
+}
+
+// @Summary Отмена лимитной заявки
+// @Description Отменяет открытую заявку и возвращает зарезервированную сумму на кошелек.
+// @Tags orders
+// @Produce json
+// @Param Authorization header string true "Bearer JWT_TOKEN"
+// @Param id path int true "ID заявки"
+// @Success 200 {object} storages.Order
+// @Failure 400 {object} render.ErrorResponse "Invalid order id"
+// @Failure 404 {object} render.ErrorResponse "Order not found or already closed"
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Error cancelling order"
+// @Router /orders/{id} [delete]
+func (s *ServerWallet) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Invalid order id")
+		return
+	}
+	user_id := r.Context().Value(middleware.User_id).(int)
+	order, err := s.db.CancelOrder(r.Context(), user_id, id)
+	if err == storages.ErrNoOrder {
+		render.Error(w, r, http.StatusNotFound, render.CodeNotFound, "Order not found or already closed")
+		return
+	}
+	if err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error cancelling order: %v", err))
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Error cancelling order")
+		return
+	}
+	render.JSON(w, http.StatusOK, order)
+	s.lg.InfoCtx(r.Context(), fmt.Sprintf("User %d cancelled order %d", user_id, id))
+}
+
+func validateOrder(req PlaceOrderRequest, now time.Time) []validation.FieldError {
+	var fields []validation.FieldError
+	if !storages.ValidCurrency(req.From) {
+		fields = append(fields, validation.FieldError{Field: "from_currency", Message: "must be one of USD, RUB, EUR"})
+	}
+	if !storages.ValidCurrency(req.To) {
+		fields = append(fields, validation.FieldError{Field: "to_currency", Message: "must be one of USD, RUB, EUR"})
+	} else if req.To == req.From {
+		fields = append(fields, validation.FieldError{Field: "to_currency", Message: "must differ from from_currency"})
+	}
+	if !req.Amount.IsPositive() || req.Amount.Exponent() < -2 {
+		fields = append(fields, validation.FieldError{Field: "amount", Message: "must be positive with at most two decimal places"})
+	}
+	if !req.TargetRate.IsPositive() {
+		fields = append(fields, validation.FieldError{Field: "target_rate", Message: "must be positive"})
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		fields = append(fields, validation.FieldError{Field: "expires_at", Message: "must be in the future"})
+	}
+	return fields
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gw-currency-wallet/internal/middleware"
+	"gw-currency-wallet/internal/storages"
+
+	"github.com/go-chi/chi"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPlaceOrder(t *testing.T) {
+	created := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		body           string
+		mockRepo       func(m *MockRepository)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Order placed",
+			body: `{"from_currency":"USD","to_currency":"EUR","amount":"100","target_rate":"0.95"}`,
+			mockRepo: func(m *MockRepository) {
+				m.On("PlaceOrder", mock.Anything, storages.Order{UserID: 1, From: "USD", To: "EUR",
+					Amount: decimal.NewFromInt(100), TargetRate: decimal.RequireFromString("0.95")}).
+					Return(storages.Order{ID: 7, UserID: 1, From: "USD", To: "EUR", Amount: decimal.NewFromInt(100),
+						TargetRate: decimal.RequireFromString("0.95"), Status: storages.OrderOpen, CreatedAt: created}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody: `{"id":7,"from_currency":"USD","to_currency":"EUR","amount":"100","target_rate":"0.95",
+				"status":"open","created_at":"2026-10-19T12:00:00Z"}`,
+		},
+		{
+			name:           "Invalid fields",
+			body:           `{"from_currency":"USD","to_currency":"USD","amount":"1.005","target_rate":"0","expires_at":"2000-01-01T00:00:00Z"}`,
+			mockRepo:       func(m *MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"code":"validation_failed","message":"Invalid input","details":[
+				{"field":"to_currency","message":"must differ from from_currency"},
+				{"field":"amount","message":"must be positive with at most two decimal places"},
+				{"field":"target_rate","message":"must be positive"},
+				{"field":"expires_at","message":"must be in the future"}]}`,
+		},
+		{
+			name: "Insufficient funds",
+			body: `{"from_currency":"USD","to_currency":"EUR","amount":"100","target_rate":"0.95"}`,
+			mockRepo: func(m *MockRepository) {
+				m.On("PlaceOrder", mock.Anything, mock.Anything).Return(storages.Order{}, storages.ErrExch)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"code":"insufficient_funds","message":"Insufficient funds or invalid amount"}`,
+		},
+		{
+			name: "Frozen account",
+			body: `{"from_currency":"USD","to_currency":"EUR","amount":"100","target_rate":"0.95"}`,
+			mockRepo: func(m *MockRepository) {
+				m.On("PlaceOrder", mock.Anything, mock.Anything).Return(storages.Order{}, storages.ErrInactive)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockLogger := new(MockLogger)
+			tt.mockRepo(mockRepo)
+			mockLogger.On("InfoCtx", mock.Anything, mock.Anything).Return(nil)
+			mockLogger.On("ErrorCtx", mock.Anything, mock.Anything).Return(nil)
+			s := &ServerWallet{db: mockRepo, lg: mockLogger}
+
+			req := httptest.NewRequest(http.MethodPost, "/orders", bytes.NewBufferString(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), middleware.User_id, 1))
+			w := httptest.NewRecorder()
+
+			s.PlaceOrder(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestListOrdersRejectsUnknownStatus(t *testing.T) {
+	s := &ServerWallet{db: new(MockRepository), lg: new(MockLogger)}
+	req := httptest.NewRequest(http.MethodGet, "/orders?status=pending", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.User_id, 1))
+	w := httptest.NewRecorder()
+
+	s.ListOrders(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestCancelOrder(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{name: "Cancelled", expectedStatus: http.StatusOK},
+		{name: "Already closed", err: storages.ErrNoOrder, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockLogger := new(MockLogger)
+			mockRepo.On("CancelOrder", mock.Anything, 1, int64(7)).
+				Return(storages.Order{ID: 7, Status: storages.OrderCancelled}, tt.err)
+			mockLogger.On("InfoCtx", mock.Anything, mock.Anything).Return(nil)
+			s := &ServerWallet{db: mockRepo, lg: mockLogger}
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "7")
+			ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+			req := httptest.NewRequest(http.MethodDelete, "/orders/7", nil)
+			req = req.WithContext(context.WithValue(ctx, middleware.User_id, 1))
+			w := httptest.NewRecorder()
+
+			s.CancelOrder(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -3,79 +3,69 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
+	"gw-currency-wallet/internal/render"
 	"gw-currency-wallet/internal/storages"
+	"gw-currency-wallet/internal/validation"
 	"net/http"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
 // @Summary Регистрация пользователя
-// @Description Позволяет зарегистрировать нового пользователя. Проверяется уникальность имени пользователя и адреса электронной почты. Пароль должен быть зашифрован перед сохранением в базе данных.
+// @Description Позволяет зарегистрировать нового пользователя. Поля проверяются по отдельности: имя — 3-32 символа из букв, цифр, '_', '.' и '-', почта приводится к нижнему регистру, пароль проверяется по политике паролей. Уникальность имени и почты обеспечивает база данных. Пароль должен быть зашифрован перед сохранением в базе данных. На почту отправляется ссылка для ее подтверждения.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param register body storages.RegisterRequest true "Данные для регистрации пользователя"
 // @Success 201 {object} map[string]string "User  registered successfully"
-// @Failure 400 {object} ErrorResponse "Username or email already exists"
-// @Failure 400 {object} ErrorResponse "Invalid input"
-// @Failure 500 {object} ErrorResponse "Internal server error"
-// @Failure 500 {object} ErrorResponse "Could not hash password"
-// @Failure 500 {object} ErrorResponse "Could not create user"
+// @Failure 400 {object} render.ErrorResponse "Invalid input"
+// @Failure 409 {object} render.ErrorResponse "Username or email already exists"
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Could not hash password"
+// @Failure 500 {object} render.ErrorResponse "Could not create user"
 // @Router /register [post]
 func (s *ServerWallet) RegisterUser(w http.ResponseWriter, r *http.Request) {
-	errRes := new(ErrorResponse)
 	var req storages.RegisterRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error decoding: %v", err))
-		errRes.message = "Invalid input"
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(errRes)
-		http.Error(w, "Invalid input", http.StatusBadRequest)
+		render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Invalid input")
 		return
 	}
 
-	exists, err := s.db.CheckUser(req.Username, req.Email, r.Context())
-	if err != nil {
-		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("error checking: %v", err))
-		errRes.message = "Internal server error"
-		json.NewEncoder(w).Encode(errRes)
-		w.WriteHeader(http.StatusInternalServerError)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	if exists {
-		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Username or email already exists"))
-		errRes.message = "Username or email already exists"
-		json.NewEncoder(w).Encode(errRes)
-		w.WriteHeader(http.StatusBadRequest)
-		http.Error(w, "Username or email already exists", http.StatusBadRequest)
+	req.Email = validation.NormalizeEmail(req.Email)
+	fields := append(validation.Username(req.Username), validation.Email(req.Email)...)
+	fields = append(fields, s.passwordPolicy.Password(req.Password, req.Username)...)
+	if len(fields) > 0 {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Invalid registration: %v", fields))
+		writeValidationError(w, r, fields)
 		return
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error hashing password: %v", err))
-		errRes.message = "Could not hash password"
-		json.NewEncoder(w).Encode(errRes)
-		w.WriteHeader(http.StatusInternalServerError)
-		http.Error(w, "Could not hash password", http.StatusInternalServerError)
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Could not hash password")
 		return
 	}
 
 	req.Password = string(hashedPassword)
 
 	if err := s.db.AddUser(req, r.Context()); err != nil {
+		if err == storages.ErrUserExists {
+			s.lg.ErrorCtx(r.Context(), "Username or email already exists")
+			render.Error(w, r, http.StatusConflict, render.CodeUserExists, "Username or email already exists")
+			return
+		}
 		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error adding user: %v", err))
-		errRes.message = "Could not create user"
-		json.NewEncoder(w).Encode(errRes)
-		w.WriteHeader(http.StatusInternalServerError)
-		http.Error(w, "Could not create user", http.StatusInternalServerError)
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Could not create user")
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("User %s registered successfully", req.Username)})
+	if err := s.sendVerification(r.Context(), req.Email); err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error sending verification email: %v", err))
+	}
+
+	render.JSON(w, http.StatusCreated, map[string]string{"message": fmt.Sprintf("User %s registered successfully", req.Username)})
 	s.lg.InfoCtx(r.Context(), fmt.Sprintf("User %s registered successfully", req.Username))
 }
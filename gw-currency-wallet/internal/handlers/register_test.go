@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"gw-currency-wallet/internal/storages"
 
@@ -43,11 +44,6 @@ type MockRepository struct {
 	mock.Mock
 }
 
-func (m *MockRepository) CheckUser(username, email string, ctx context.Context) (bool, error) {
-	args := m.Called(username, email, ctx)
-	return args.Bool(0), args.Error(1)
-}
-
 func (m *MockRepository) AddUser(req storages.RegisterRequest, ctx context.Context) error {
 	args := m.Called(req, ctx)
 	return args.Error(0)
@@ -63,14 +59,24 @@ func (m *MockRepository) ExchangeForCurrency(ctx context.Context, from, to strin
 	return args.Get(0).(map[string]decimal.Decimal), args.Error(1)
 }
 
-func (m *MockRepository) Deposit(user_id int, amount decimal.Decimal, currency string, ctx context.Context) error {
+func (m *MockRepository) DepositAndExchange(ctx context.Context, from, to string, amount decimal.Decimal, kurs float32, user_id int) (map[string]decimal.Decimal, error) {
+	args := m.Called(ctx, from, to, amount, kurs, user_id)
+	return args.Get(0).(map[string]decimal.Decimal), args.Error(1)
+}
+
+func (m *MockRepository) Transfer(ctx context.Context, user_id, recipient_id int, currency string, amount decimal.Decimal) (storages.Balance, error) {
+	args := m.Called(ctx, user_id, recipient_id, currency, amount)
+	return args.Get(0).(storages.Balance), args.Error(1)
+}
+
+func (m *MockRepository) Deposit(user_id int, amount decimal.Decimal, currency string, ctx context.Context) (storages.Balance, error) {
 	args := m.Called(user_id, amount, currency, ctx)
-	return args.Error(0)
+	return args.Get(0).(storages.Balance), args.Error(1)
 }
 
-func (m *MockRepository) Withdraw(user_id int, amount decimal.Decimal, currency string, ctx context.Context) error {
+func (m *MockRepository) Withdraw(user_id int, amount decimal.Decimal, currency string, ctx context.Context) (storages.Balance, error) {
 	args := m.Called(user_id, amount, currency, ctx)
-	return args.Error(0)
+	return args.Get(0).(storages.Balance), args.Error(1)
 }
 
 func (m *MockRepository) GetBalance(user_id int, ctx context.Context) (storages.Balance, error) {
@@ -78,13 +84,212 @@ func (m *MockRepository) GetBalance(user_id int, ctx context.Context) (storages.
 	return args.Get(0).(storages.Balance), args.Error(1)
 }
 
+func (m *MockRepository) Ready(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *MockRepository) SetAccountStatus(user_id int, status string, ctx context.Context) error {
+	args := m.Called(user_id, status, ctx)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetTOTP(user_id int, ctx context.Context) (storages.TOTP, error) {
+	args := m.Called(user_id, ctx)
+	return args.Get(0).(storages.TOTP), args.Error(1)
+}
+
+func (m *MockRepository) SetTOTPSecret(user_id int, secret string, ctx context.Context) error {
+	args := m.Called(user_id, secret, ctx)
+	return args.Error(0)
+}
+
+func (m *MockRepository) EnableTOTP(user_id int, recoveryHashes []string, ctx context.Context) error {
+	args := m.Called(user_id, recoveryHashes, ctx)
+	return args.Error(0)
+}
+
+func (m *MockRepository) UseTOTPStep(user_id int, step int64, ctx context.Context) (bool, error) {
+	args := m.Called(user_id, step, ctx)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) UseRecoveryCode(user_id int, codeHash string, ctx context.Context) (bool, error) {
+	args := m.Called(user_id, codeHash, ctx)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) CreateUserToken(email, kind, tokenHash string, expiresAt time.Time, ctx context.Context) (storages.User, error) {
+	args := m.Called(email, kind, tokenHash, expiresAt, ctx)
+	return args.Get(0).(storages.User), args.Error(1)
+}
+
+func (m *MockRepository) VerifyEmail(tokenHash string, ctx context.Context) error {
+	args := m.Called(tokenHash, ctx)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ResetPassword(tokenHash, passwordHash string, ctx context.Context) error {
+	args := m.Called(tokenHash, passwordHash, ctx)
+	return args.Error(0)
+}
+
+func (m *MockRepository) PlaceOrder(ctx context.Context, o storages.Order) (storages.Order, error) {
+	args := m.Called(ctx, o)
+	return args.Get(0).(storages.Order), args.Error(1)
+}
+
+func (m *MockRepository) ListOrders(ctx context.Context, user_id int, status string) ([]storages.Order, error) {
+	args := m.Called(ctx, user_id, status)
+	return args.Get(0).([]storages.Order), args.Error(1)
+}
+
+func (m *MockRepository) CancelOrder(ctx context.Context, user_id int, id int64) (storages.Order, error) {
+	args := m.Called(ctx, user_id, id)
+	return args.Get(0).(storages.Order), args.Error(1)
+}
+
+func (m *MockRepository) OpenOrderPairs(ctx context.Context) ([]storages.Pair, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]storages.Pair), args.Error(1)
+}
+
+func (m *MockRepository) FillOrders(ctx context.Context, pair storages.Pair, rate decimal.Decimal) ([]storages.Order, error) {
+	args := m.Called(ctx, pair, rate)
+	return args.Get(0).([]storages.Order), args.Error(1)
+}
+
+func (m *MockRepository) ExpireOrders(ctx context.Context) ([]storages.Order, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]storages.Order), args.Error(1)
+}
+
+func (m *MockRepository) CreateHold(ctx context.Context, h storages.Hold) (storages.Hold, error) {
+	args := m.Called(ctx, h)
+	return args.Get(0).(storages.Hold), args.Error(1)
+}
+
+func (m *MockRepository) ListHolds(ctx context.Context, user_id int, status string) ([]storages.Hold, error) {
+	args := m.Called(ctx, user_id, status)
+	return args.Get(0).([]storages.Hold), args.Error(1)
+}
+
+func (m *MockRepository) CaptureHold(ctx context.Context, user_id int, id int64, amount decimal.Decimal) (storages.Hold, error) {
+	args := m.Called(ctx, user_id, id, amount)
+	return args.Get(0).(storages.Hold), args.Error(1)
+}
+
+func (m *MockRepository) ReleaseHold(ctx context.Context, user_id int, id int64) (storages.Hold, error) {
+	args := m.Called(ctx, user_id, id)
+	return args.Get(0).(storages.Hold), args.Error(1)
+}
+
+func (m *MockRepository) ExpireHolds(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) CreateSchedule(ctx context.Context, sch storages.Schedule) (storages.Schedule, error) {
+	args := m.Called(ctx, sch)
+	return args.Get(0).(storages.Schedule), args.Error(1)
+}
+
+func (m *MockRepository) ListSchedules(ctx context.Context, user_id int) ([]storages.Schedule, error) {
+	args := m.Called(ctx, user_id)
+	return args.Get(0).([]storages.Schedule), args.Error(1)
+}
+
+func (m *MockRepository) GetSchedule(ctx context.Context, user_id int, id int64) (storages.Schedule, error) {
+	args := m.Called(ctx, user_id, id)
+	return args.Get(0).(storages.Schedule), args.Error(1)
+}
+
+func (m *MockRepository) SetScheduleStatus(ctx context.Context, user_id int, id int64, status string, nextRun time.Time) (storages.Schedule, error) {
+	args := m.Called(ctx, user_id, id, status, nextRun)
+	return args.Get(0).(storages.Schedule), args.Error(1)
+}
+
+func (m *MockRepository) ListScheduleRuns(ctx context.Context, user_id int, id int64, limit int) ([]storages.ScheduleRun, error) {
+	args := m.Called(ctx, user_id, id, limit)
+	return args.Get(0).([]storages.ScheduleRun), args.Error(1)
+}
+
+func (m *MockRepository) DueSchedules(ctx context.Context, limit int) ([]storages.Schedule, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).([]storages.Schedule), args.Error(1)
+}
+
+func (m *MockRepository) ClaimSchedule(ctx context.Context, id int64, prevRun, nextRun time.Time) (bool, error) {
+	args := m.Called(ctx, id, prevRun, nextRun)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) RecordScheduleRun(ctx context.Context, run storages.ScheduleRun, maxFailures int) (bool, error) {
+	args := m.Called(ctx, run, maxFailures)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) CreateWebhook(ctx context.Context, sub storages.Webhook) (storages.Webhook, error) {
+	args := m.Called(ctx, sub)
+	return args.Get(0).(storages.Webhook), args.Error(1)
+}
+
+func (m *MockRepository) ListWebhooks(ctx context.Context, owner int) ([]storages.Webhook, error) {
+	args := m.Called(ctx, owner)
+	return args.Get(0).([]storages.Webhook), args.Error(1)
+}
+
+func (m *MockRepository) DeleteWebhook(ctx context.Context, owner int, id int64) error {
+	args := m.Called(ctx, owner, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListWebhookDeliveries(ctx context.Context, owner int, id int64, status string, limit int) ([]storages.WebhookDelivery, error) {
+	args := m.Called(ctx, owner, id, status, limit)
+	return args.Get(0).([]storages.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockRepository) ReplayDelivery(ctx context.Context, owner int, id int64) (storages.WebhookDelivery, error) {
+	args := m.Called(ctx, owner, id)
+	return args.Get(0).(storages.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockRepository) FanOutEvents(ctx context.Context, limit int) (int64, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]storages.WebhookDelivery, error) {
+	args := m.Called(ctx, limit, lease)
+	return args.Get(0).([]storages.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockRepository) MarkDelivered(ctx context.Context, id int64, status int) error {
+	args := m.Called(ctx, id, status)
+	return args.Error(0)
+}
+
+func (m *MockRepository) MarkDeliveryFailed(ctx context.Context, id int64, status int, reason string, retryAt *time.Time) error {
+	args := m.Called(ctx, id, status, reason, retryAt)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ClaimEvents(ctx context.Context, lockKey int64, limit int, lease time.Duration) ([]storages.Event, error) {
+	args := m.Called(ctx, lockKey, limit, lease)
+	return args.Get(0).([]storages.Event), args.Error(1)
+}
+
+func (m *MockRepository) FinishEvents(ctx context.Context, claimed, published []int64) error {
+	args := m.Called(ctx, claimed, published)
+	return args.Error(0)
+}
+
 func (m *MockRepository) Close() {}
 
 func TestRegisterUser(t *testing.T) {
 	tests := []struct {
 		name           string
 		input          storages.RegisterRequest
-		mockCheckUser  func(m *MockRepository)
 		mockAddUser    func(m *MockRepository)
 		mockInfoCtx    func(m *MockLogger)
 		mockErrorCtx   func(m *MockLogger)
@@ -98,9 +303,6 @@ func TestRegisterUser(t *testing.T) {
 				Email:    "newuser@example.com",
 				Password: "password123",
 			},
-			mockCheckUser: func(m *MockRepository) {
-				m.On("CheckUser", "newuser", "newuser@example.com", mock.Anything).Return(false, nil)
-			},
 			mockAddUser: func(m *MockRepository) {
 				m.On("AddUser", mock.Anything, mock.Anything).Return(nil)
 			},
@@ -110,6 +312,56 @@ func TestRegisterUser(t *testing.T) {
 			expectedStatus: http.StatusCreated,
 			expectedBody:   `{"message":"User newuser registered successfully"}`,
 		},
+		{
+			name: "Email is normalised",
+			input: storages.RegisterRequest{
+				Username: "newuser",
+				Email:    "  NewUser@Example.COM ",
+				Password: "password123",
+			},
+			mockAddUser: func(m *MockRepository) {
+				m.On("AddUser", mock.MatchedBy(func(req storages.RegisterRequest) bool {
+					return req.Email == "newuser@example.com"
+				}), mock.Anything).Return(nil)
+			},
+			mockInfoCtx: func(m *MockLogger) {
+				m.On("InfoCtx", mock.Anything, "User newuser registered successfully").Return(nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"message":"User newuser registered successfully"}`,
+		},
+		{
+			name: "Invalid fields",
+			input: storages.RegisterRequest{
+				Username: "",
+				Email:    "not-an-email",
+				Password: "1",
+			},
+			mockErrorCtx: func(m *MockLogger) {
+				m.On("ErrorCtx", mock.Anything, mock.Anything).Return(nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"code":"validation_failed","message":"Invalid input","details":[
+				{"field":"username","message":"is required"},
+				{"field":"email","message":"is not a valid email address"},
+				{"field":"password","message":"must be at least 8 characters"}]}`,
+		},
+		{
+			name: "Username or email already exists",
+			input: storages.RegisterRequest{
+				Username: "newuser",
+				Email:    "newuser@example.com",
+				Password: "password123",
+			},
+			mockAddUser: func(m *MockRepository) {
+				m.On("AddUser", mock.Anything, mock.Anything).Return(storages.ErrUserExists)
+			},
+			mockErrorCtx: func(m *MockLogger) {
+				m.On("ErrorCtx", mock.Anything, mock.Anything).Return(nil)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"code":"user_exists","message":"Username or email already exists"}`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -117,9 +369,6 @@ func TestRegisterUser(t *testing.T) {
 			mockRepo := new(MockRepository)
 			mockLogger := new(MockLogger)
 
-			if tt.mockCheckUser != nil {
-				tt.mockCheckUser(mockRepo)
-			}
 			if tt.mockAddUser != nil {
 				tt.mockAddUser(mockRepo)
 			}
@@ -144,7 +393,10 @@ func TestRegisterUser(t *testing.T) {
 			res := w.Result()
 			assert.Equal(t, tt.expectedStatus, res.StatusCode)
 
-			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+			mockRepo.AssertExpectations(t)
 		})
 	}
 }
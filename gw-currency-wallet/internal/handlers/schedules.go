@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"gw-currency-wallet/internal/middleware"
+	"gw-currency-wallet/internal/render"
+	"gw-currency-wallet/internal/scheduler"
+	"gw-currency-wallet/internal/storages"
+	"gw-currency-wallet/internal/validation"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	defaultRunsLimit = 20
+	maxRunsLimit     = 100
+)
+
+// CreateScheduleRequest — регулярная операция с Amount в From по расписанию
+// Cron в UTC, например "0 9 * * 1" — каждый понедельник в 09:00. Kind exchange
+// обменивает Amount в To, deposit_exchange сначала пополняет на него кошелек,
+// transfer переводит Amount пользователю Recipient, To для него не нужен.
+type CreateScheduleRequest struct {
+	Kind      string          `json:"kind"`
+	From      string          `json:"from_currency"`
+	To        string          `json:"to_currency"`
+	Recipient string          `json:"recipient"`
+	Amount    decimal.Decimal `json:"amount"`
+	Cron      string          `json:"cron"`
+}
+
+type SchedulesResponse struct {
+	Schedules []storages.Schedule `json:"schedules"`
+}
+
+type ScheduleRunsResponse struct {
+	Runs []storages.ScheduleRun `json:"runs"`
+}
+
+// @Summary Создание регулярной операции
+// @Description Создает регулярную операцию по расписанию cron (UTC): exchange — обмен, deposit_exchange —
+// @Description пополнение с обменом пополненной суммы в одной транзакции, transfer — перевод пользователю recipient
+// @Description в валюте from_currency. Обмен проходит те же проверки, что и обмен по запросу, а перевод — что и вывод:
+// @Description статус аккаунта, лимиты и доступный остаток. О неудачах приходит письмо,
+// @Description после scheduler.max_failures неудач подряд расписание приостанавливается.
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer JWT_TOKEN"
+// @Param X-TOTP-Code header string false "TOTP-код для сумм от порога two_factor.threshold"
+// @Param schedule body CreateScheduleRequest true "Данные расписания"
+// @Success 201 {object} storages.Schedule
+// @Failure 400 {object} render.ErrorResponse "Invalid input"
+// @Failure 401 {object} render.ErrorResponse "TOTP code required"
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Error creating schedule"
+// @Router /schedules [post]
+func (s *ServerWallet) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req CreateScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error decoding: %v", err))
+		render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Error decoding schedule request")
+		return
+	}
+	next, fields := validateSchedule(req, time.Now())
+	if len(fields) > 0 {
+		writeValidationError(w, r, fields)
+		return
+	}
+
+	user_id := r.Context().Value(middleware.User_id).(int)
+	sch := storages.Schedule{
+		UserID:    user_id,
+		Kind:      req.Kind,
+		From:      req.From,
+		To:        req.To,
+		Amount:    req.Amount,
+		Cron:      req.Cron,
+		NextRunAt: next,
+	}
+	if req.Kind == storages.ScheduleTransfer {
+		recipient, err := s.db.GetUser(req.Recipient, r.Context())
+		if err == storages.ErrNoUser || err == nil && recipient.Id == user_id {
+			writeValidationError(w, r, []validation.FieldError{{Field: "recipient", Message: "must be another existing user"}})
+			return
+		}
+		if err != nil {
+			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error getting recipient: %v", err))
+			render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Error creating schedule")
+			return
+		}
+		sch.To = req.From
+		sch.RecipientID = &recipient.Id
+	}
+	if !s.requireTOTP(w, r, user_id, req.From, req.Amount) {
+		return
+	}
+	sch, err := s.db.CreateSchedule(r.Context(), sch)
+	if err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error creating schedule: %v", err))
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Error creating schedule")
+		return
+	}
+	render.JSON(w, http.StatusCreated, sch)
+	s.lg.InfoCtx(r.Context(), fmt.Sprintf("User %d created schedule %d", user_id, sch.ID))
+}
+
+// @Summary Список регулярных операций
+// @Tags schedules
+// @Produce json
+// @Param Authorization header string true "Bearer JWT_TOKEN"
+// @Success 200 {object} SchedulesResponse
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Could not list schedules"
+// @Router /schedules [get]
+func (s *ServerWallet) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	user_id := r.Context().Value(middleware.User_id).(int)
+	schedules, err := s.db.ListSchedules(r.Context(), user_id)
+	if err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("error listing schedules: %v", err))
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Could not list schedules")
+		return
+	}
+	if schedules == nil {
+		schedules = []storages.Schedule{}
+	}
+	render.JSON(w, http.StatusOK, SchedulesResponse{Schedules: schedules})
+}
+
+// @Summary История запусков регулярной операции
+// @Description Возвращает последние запуски, новые первыми.
+// @Tags schedules
+// @Produce json
+// @Param Authorization header string true "Bearer JWT_TOKEN"
+// @Param id path int true "ID расписания"
+// @Param limit query int false "Число запусков, от 1 до 100, по умолчанию 20"
+// @Success 200 {object} ScheduleRunsResponse
+// @Failure 400 {object} render.ErrorResponse "Invalid input"
+// @Failure 404 {object} render.ErrorResponse "Schedule not found"
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Could not list runs"
+// @Router /schedules/{id}/runs [get]
+func (s *ServerWallet) ListScheduleRuns(w http.ResponseWriter, r *http.Request) {
+	id, ok := scheduleID(w, r)
+	if !ok {
+		return
+	}
+	limit := defaultRunsLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxRunsLimit {
+			writeValidationError(w, r, []validation.FieldError{{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", maxRunsLimit)}})
+			return
+		}
+		limit = n
+	}
+	user_id := r.Context().Value(middleware.User_id).(int)
+	runs, err := s.db.ListScheduleRuns(r.Context(), user_id, id, limit)
+	if err == storages.ErrNoSchedule {
+		render.Error(w, r, http.StatusNotFound, render.CodeNotFound, "Schedule not found")
+		return
+	}
+	if err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("error listing schedule runs: %v", err))
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Could not list runs")
+		return
+	}
+	if runs == nil {
+		runs = []storages.ScheduleRun{}
+	}
+	render.JSON(w, http.StatusOK, ScheduleRunsResponse{Runs: runs})
+}
+
+// @Summary Приостановка регулярной операции
+// @Tags schedules
+// @Produce json
+// @Param Authorization header string true "Bearer JWT_TOKEN"
+// @Param id path int true "ID расписания"
+// @Success 200 {object} storages.Schedule
+// @Failure 400 {object} render.ErrorResponse "Invalid schedule id"
+// @Failure 404 {object} render.ErrorResponse "Schedule not found"
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Error updating schedule"
+// @Router /schedules/{id}/pause [post]
+func (s *ServerWallet) PauseSchedule(w http.ResponseWriter, r *http.Request) {
+	s.setScheduleStatus(w, r, storages.SchedulePaused)
+}
+
+// @Summary Возобновление регулярной операции
+// @Description Следующий запуск назначается по расписанию от текущего времени, пропущенные запуски не выполняются.
+// @Description Счетчик неудач подряд сбрасывается.
+// @Tags schedules
+// @Produce json
+// @Param Authorization header string true "Bearer JWT_TOKEN"
+// @Param id path int true "ID расписания"
+// @Success 200 {object} storages.Schedule
+// @Failure 400 {object} render.ErrorResponse "Invalid schedule id"
+// @Failure 404 {object} render.ErrorResponse "Schedule not found"
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Error updating schedule"
+// @Router /schedules/{id}/resume [post]
+func (s *ServerWallet) ResumeSchedule(w http.ResponseWriter, r *http.Request) {
+	s.setScheduleStatus(w, r, storages.ScheduleActive)
+}
+
+func (s *ServerWallet) setScheduleStatus(w http.ResponseWriter, r *http.Request, status string) {
+	id, ok := scheduleID(w, r)
+	if !ok {
+		return
+	}
+	user_id := r.Context().Value(middleware.User_id).(int)
+	var next time.Time
+	if status == storages.ScheduleActive {
+		// Время следующего запуска зависит от расписания, поэтому сначала читаем его.
+		sch, err := s.db.GetSchedule(r.Context(), user_id, id)
+		if err == storages.ErrNoSchedule {
+			render.Error(w, r, http.StatusNotFound, render.CodeNotFound, "Schedule not found")
+			return
+		}
+		if err != nil {
+			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("error getting schedule: %v", err))
+			render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Error updating schedule")
+			return
+		}
+		cron, err := scheduler.ParseCron(sch.Cron)
+		if err != nil {
+			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("schedule %d: %v", id, err))
+			render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Error updating schedule")
+			return
+		}
+		next = cron.Next(time.Now().UTC())
+	}
+	sch, err := s.db.SetScheduleStatus(r.Context(), user_id, id, status, next)
+	if err == storages.ErrNoSchedule {
+		render.Error(w, r, http.StatusNotFound, render.CodeNotFound, "Schedule not found")
+		return
+	}
+	if err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error updating schedule: %v", err))
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Error updating schedule")
+		return
+	}
+	render.JSON(w, http.StatusOK, sch)
+	s.lg.InfoCtx(r.Context(), fmt.Sprintf("User %d set schedule %d to %s", user_id, id, status))
+}
+
+func scheduleID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Invalid schedule id")
+		return 0, false
+	}
+	return id, true
+}
+
+// validateSchedule проверяет запрос и возвращает время первого запуска.
+func validateSchedule(req CreateScheduleRequest, now time.Time) (time.Time, []validation.FieldError) {
+	var fields []validation.FieldError
+	switch req.Kind {
+	case storages.ScheduleExchange, storages.ScheduleDepositExchange, storages.ScheduleTransfer:
+	default:
+		fields = append(fields, validation.FieldError{Field: "kind", Message: "must be one of exchange, deposit_exchange, transfer"})
+	}
+	if !storages.ValidCurrency(req.From) {
+		fields = append(fields, validation.FieldError{Field: "from_currency", Message: "must be one of USD, RUB, EUR"})
+	}
+	if req.Kind == storages.ScheduleTransfer {
+		if req.To != "" && req.To != req.From {
+			fields = append(fields, validation.FieldError{Field: "to_currency", Message: "must be empty or equal to from_currency for transfers"})
+		}
+		if req.Recipient == "" {
+			fields = append(fields, validation.FieldError{Field: "recipient", Message: "is required for transfers"})
+		}
+	} else {
+		if !storages.ValidCurrency(req.To) {
+			fields = append(fields, validation.FieldError{Field: "to_currency", Message: "must be one of USD, RUB, EUR"})
+		} else if req.To == req.From {
+			fields = append(fields, validation.FieldError{Field: "to_currency", Message: "must differ from from_currency"})
+		}
+		if req.Recipient != "" {
+			fields = append(fields, validation.FieldError{Field: "recipient", Message: "is only allowed for transfers"})
+		}
+	}
+	if !req.Amount.IsPositive() || req.Amount.Exponent() < -2 {
+		fields = append(fields, validation.FieldError{Field: "amount", Message: "must be positive with at most two decimal places"})
+	}
+	var next time.Time
+	if cron, err := scheduler.ParseCron(req.Cron); err != nil {
+		fields = append(fields, validation.FieldError{Field: "cron", Message: err.Error()})
+	} else if next = cron.Next(now.UTC()); next.IsZero() {
+		fields = append(fields, validation.FieldError{Field: "cron", Message: "never fires"})
+	}
+	return next, fields
+}
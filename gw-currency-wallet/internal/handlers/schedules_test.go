@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gw-currency-wallet/internal/middleware"
+	"gw-currency-wallet/internal/storages"
+
+	"github.com/go-chi/chi"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateSchedule(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		mockRepo       func(m *MockRepository)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Weekly exchange",
+			body: `{"kind":"exchange","from_currency":"RUB","to_currency":"EUR","amount":"100","cron":"0 9 * * 1"}`,
+			mockRepo: func(m *MockRepository) {
+				m.On("CreateSchedule", mock.Anything, mock.MatchedBy(func(sch storages.Schedule) bool {
+					return sch.UserID == 1 && sch.Cron == "0 9 * * 1" && sch.Amount.Equal(decimal.NewFromInt(100)) &&
+						sch.NextRunAt.Weekday() == time.Monday && sch.NextRunAt.Hour() == 9 && sch.NextRunAt.After(time.Now())
+				})).Return(storages.Schedule{ID: 5, Status: storages.ScheduleActive}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "Weekly deposit with exchange",
+			body: `{"kind":"deposit_exchange","from_currency":"RUB","to_currency":"EUR","amount":"100","cron":"@weekly"}`,
+			mockRepo: func(m *MockRepository) {
+				m.On("CreateSchedule", mock.Anything, mock.MatchedBy(func(sch storages.Schedule) bool {
+					return sch.Kind == storages.ScheduleDepositExchange && sch.To == "EUR" && sch.RecipientID == nil
+				})).Return(storages.Schedule{ID: 6, Status: storages.ScheduleActive}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "Monthly transfer",
+			body: `{"kind":"transfer","from_currency":"RUB","recipient":"bob","amount":"100","cron":"0 9 1 * *"}`,
+			mockRepo: func(m *MockRepository) {
+				m.On("GetUser", "bob", mock.Anything).Return(storages.User{Id: 2, Username: "bob"}, nil)
+				m.On("CreateSchedule", mock.Anything, mock.MatchedBy(func(sch storages.Schedule) bool {
+					return sch.Kind == storages.ScheduleTransfer && sch.From == "RUB" && sch.To == "RUB" &&
+						sch.RecipientID != nil && *sch.RecipientID == 2
+				})).Return(storages.Schedule{ID: 7, Status: storages.ScheduleActive, Recipient: "bob"}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "Transfer to yourself",
+			body: `{"kind":"transfer","from_currency":"RUB","recipient":"alice","amount":"100","cron":"@weekly"}`,
+			mockRepo: func(m *MockRepository) {
+				m.On("GetUser", "alice", mock.Anything).Return(storages.User{Id: 1, Username: "alice"}, nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"code":"validation_failed","message":"Invalid input","details":[
+				{"field":"recipient","message":"must be another existing user"}]}`,
+		},
+		{
+			name: "Transfer to unknown user",
+			body: `{"kind":"transfer","from_currency":"RUB","recipient":"nobody","amount":"100","cron":"@weekly"}`,
+			mockRepo: func(m *MockRepository) {
+				m.On("GetUser", "nobody", mock.Anything).Return(storages.User{}, storages.ErrNoUser)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"code":"validation_failed","message":"Invalid input","details":[
+				{"field":"recipient","message":"must be another existing user"}]}`,
+		},
+		{
+			name:           "Transfer without recipient and bad cron",
+			body:           `{"kind":"transfer","from_currency":"RUB","to_currency":"EUR","amount":"100","cron":"0 0 30 2 *"}`,
+			mockRepo:       func(m *MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"code":"validation_failed","message":"Invalid input","details":[
+				{"field":"to_currency","message":"must be empty or equal to from_currency for transfers"},
+				{"field":"recipient","message":"is required for transfers"},
+				{"field":"cron","message":"never fires"}]}`,
+		},
+		{
+			name:           "Unknown kind and recipient on exchange",
+			body:           `{"kind":"withdraw","from_currency":"RUB","to_currency":"EUR","recipient":"bob","amount":"100","cron":"@weekly"}`,
+			mockRepo:       func(m *MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"code":"validation_failed","message":"Invalid input","details":[
+				{"field":"kind","message":"must be one of exchange, deposit_exchange, transfer"},
+				{"field":"recipient","message":"is only allowed for transfers"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockLogger := new(MockLogger)
+			tt.mockRepo(mockRepo)
+			mockLogger.On("InfoCtx", mock.Anything, mock.Anything).Return(nil)
+			s := &ServerWallet{db: mockRepo, lg: mockLogger}
+
+			req := httptest.NewRequest(http.MethodPost, "/schedules", bytes.NewBufferString(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), middleware.User_id, 1))
+			w := httptest.NewRecorder()
+
+			s.CreateSchedule(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestResumeScheduleReschedulesFromNow(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockLogger := new(MockLogger)
+	mockRepo.On("GetSchedule", mock.Anything, 1, int64(5)).
+		Return(storages.Schedule{ID: 5, Cron: "*/10 * * * *", Status: storages.SchedulePaused}, nil)
+	mockRepo.On("SetScheduleStatus", mock.Anything, 1, int64(5), storages.ScheduleActive, mock.MatchedBy(func(next time.Time) bool {
+		return next.After(time.Now()) && next.Before(time.Now().Add(10*time.Minute)) && next.Minute()%10 == 0
+	})).Return(storages.Schedule{ID: 5, Status: storages.ScheduleActive}, nil)
+	mockLogger.On("InfoCtx", mock.Anything, mock.Anything).Return(nil)
+	s := &ServerWallet{db: mockRepo, lg: mockLogger}
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "5")
+	ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+	req := httptest.NewRequest(http.MethodPost, "/schedules/5/resume", nil)
+	req = req.WithContext(context.WithValue(ctx, middleware.User_id, 1))
+	w := httptest.NewRecorder()
+
+	s.ResumeSchedule(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"gw-currency-wallet/internal/middleware"
+	"gw-currency-wallet/internal/render"
+	"gw-currency-wallet/internal/storages"
+	"gw-currency-wallet/internal/totp"
+	"net/http"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	recoveryCodesCount = 10
+	recoveryCodeBytes  = 10
+	defaultPreAuthTTL  = 5 * time.Minute
+)
+
+type TOTPEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type TOTPCodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+type TOTPConfirmResponse struct {
+	Message       string   `json:"message"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// @Summary Подключение 2FA
+// @Description Генерирует новый TOTP-секрет и otpauth-ссылку для приложения-аутентификатора. 2FA включается только после подтверждения кодом.
+// @Tags auth
+// @Produce json
+// @Param Authorization header string true "Bearer JWT_TOKEN"
+// @Success 200 {object} TOTPEnrollResponse
+// @Failure 409 {object} render.ErrorResponse "Two-factor authentication is already enabled"
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Could not enroll two-factor authentication"
+// @Router /2fa/enroll [post]
+func (s *ServerWallet) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	user_id := r.Context().Value(middleware.User_id).(int)
+	username, _ := r.Context().Value(middleware.UserNameconst).(string)
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error generating totp secret: %v", err))
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Could not enroll two-factor authentication")
+		return
+	}
+	if err := s.db.SetTOTPSecret(user_id, secret, r.Context()); err != nil {
+		if err == storages.ErrTOTPEnabled {
+			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error enrolling totp: %v", err))
+			render.Error(w, r, http.StatusConflict, render.CodeTwoFactorEnabled, "Two-factor authentication is already enabled")
+			return
+		}
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error enrolling totp: %v", err))
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Could not enroll two-factor authentication")
+		return
+	}
+
+	render.JSON(w, http.StatusOK, TOTPEnrollResponse{Secret: secret, URI: totp.URI(s.issuer(), username, secret)})
+	s.lg.InfoCtx(r.Context(), fmt.Sprintf("User %d started 2fa enrollment", user_id))
+}
+
+// @Summary Подтверждение 2FA
+// @Description Включает 2FA после проверки первого кода и один раз возвращает коды восстановления.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer JWT_TOKEN"
+// @Param code body TOTPCodeRequest true "Код из приложения-аутентификатора"
+// @Success 200 {object} TOTPConfirmResponse
+// @Failure 400 {object} render.ErrorResponse "Invalid input"
+// @Failure 401 {object} render.ErrorResponse "Invalid TOTP code"
+// @Failure 409 {object} render.ErrorResponse "Two-factor authentication is already enabled"
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Could not enable two-factor authentication"
+// @Router /2fa/confirm [post]
+func (s *ServerWallet) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	var req TOTPCodeRequest
+	user_id := r.Context().Value(middleware.User_id).(int)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error decoding: %v", err))
+		render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Invalid input")
+		return
+	}
+
+	state, err := s.db.GetTOTP(user_id, r.Context())
+	if err == nil && state.Enabled {
+		err = storages.ErrTOTPEnabled
+	}
+	if err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error getting totp: %v", err))
+		if err == storages.ErrTOTPEnabled {
+			render.Error(w, r, http.StatusConflict, render.CodeTwoFactorEnabled, "Two-factor authentication is already enabled")
+			return
+		}
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Could not enable two-factor authentication")
+		return
+	}
+	if ok, err := s.useTOTPCode(r.Context(), user_id, state.Secret, req.Code); err != nil || !ok {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Invalid totp code on confirm: %v", err))
+		render.Error(w, r, http.StatusUnauthorized, render.CodeInvalidTOTP, "Invalid TOTP code")
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err == nil {
+		err = s.db.EnableTOTP(user_id, hashes, r.Context())
+	}
+	if err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error enabling totp: %v", err))
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Could not enable two-factor authentication")
+		return
+	}
+
+	render.JSON(w, http.StatusOK, TOTPConfirmResponse{Message: "Two-factor authentication enabled", RecoveryCodes: codes})
+	s.lg.InfoCtx(r.Context(), fmt.Sprintf("User %d enabled 2fa", user_id))
+}
+
+// @Summary Вход со вторым фактором
+// @Description Обменивает токен предварительной авторизации и TOTP-код (или код восстановления) на полноценный JWT-токен.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer PRE_AUTH_TOKEN"
+// @Param code body TOTPCodeRequest true "TOTP-код или код восстановления"
+// @Success 200 {object} LoginResponse
+// @Failure 400 {object} render.ErrorResponse "Invalid input"
+// @Failure 401 {object} render.ErrorResponse "Invalid TOTP code"
+// @Failure 429 {object} render.ErrorResponse "Too many failed attempts"
+// @Failure 500 {object} render.ErrorResponse "Could not generate token"
+// @Router /login/2fa [post]
+func (s *ServerWallet) LoginTOTP(w http.ResponseWriter, r *http.Request) {
+	var req TOTPCodeRequest
+	user_id := r.Context().Value(middleware.User_id).(int)
+	username, _ := r.Context().Value(middleware.UserNameconst).(string)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error decoding: %v", err))
+		render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Invalid input")
+		return
+	}
+
+	if s.lockedOut(w, r, username) {
+		return
+	}
+
+	ok, err := s.verifySecondFactor(r.Context(), user_id, req)
+	if err != nil || !ok {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Invalid second factor: %v", err))
+		s.loginFailed(r, username)
+		render.Error(w, r, http.StatusUnauthorized, render.CodeInvalidTOTP, "Invalid TOTP code")
+		return
+	}
+
+	token, err := generateToken(user_id, username)
+	if err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error generating token: %v", err))
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Could not generate token")
+		return
+	}
+	if s.lockout != nil {
+		if err := s.lockout.Success(r.Context(), username); err != nil {
+			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("error resetting lockout: %v", err))
+		}
+	}
+	render.JSON(w, http.StatusOK, LoginResponse{Token: token})
+	s.lg.InfoCtx(r.Context(), fmt.Sprintf("User %s passed 2fa", username))
+}
+
+// requireTOTP проверяет свежий TOTP-код из заголовка X-TOTP-Code для операций
+// на сумму от порога two_factor.threshold. Пользователей без 2FA не затрагивает.
+// Неверные коды считаются вместе с неудачами /login/2fa, чтобы код нельзя было
+// подобрать через денежные маршруты. Возвращает false, если ответ уже записан.
+func (s *ServerWallet) requireTOTP(w http.ResponseWriter, r *http.Request, user_id int, currency string, amount decimal.Decimal) bool {
+	threshold, ok := s.twoFactor.Threshold[currency]
+	if !ok || amount.LessThan(decimal.NewFromFloat(threshold)) {
+		return true
+	}
+	state, err := s.db.GetTOTP(user_id, r.Context())
+	if err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("error getting totp: %v", err))
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Internal server error")
+		return false
+	}
+	if !state.Enabled {
+		return true
+	}
+	code := r.Header.Get("X-TOTP-Code")
+	if code == "" {
+		s.lg.ErrorCtx(r.Context(), "TOTP code required")
+		render.Error(w, r, http.StatusUnauthorized, render.CodeTOTPRequired, "TOTP code required")
+		return false
+	}
+	username, _ := r.Context().Value(middleware.UserNameconst).(string)
+	if s.lockedOut(w, r, username) {
+		return false
+	}
+	if ok, err := s.useTOTPCode(r.Context(), user_id, state.Secret, code); err != nil || !ok {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Invalid totp code: %v", err))
+		s.loginFailed(r, username)
+		render.Error(w, r, http.StatusUnauthorized, render.CodeInvalidTOTP, "Invalid TOTP code")
+		return false
+	}
+	return true
+}
+
+// lockedOut отвечает 429, если попытки входа пользователя заблокированы после
+// серии неудач. Возвращает true, если ответ уже записан.
+func (s *ServerWallet) lockedOut(w http.ResponseWriter, r *http.Request, username string) bool {
+	if s.lockout == nil {
+		return false
+	}
+	wait, err := s.lockout.Check(r.Context(), username)
+	if err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("error checking lockout: %v", err))
+	}
+	if wait > 0 {
+		s.lg.WarnCtx(r.Context(), fmt.Sprintf("login for %s is locked", username))
+		middleware.TooManyRequests(w, r, wait)
+		return true
+	}
+	return false
+}
+
+func (s *ServerWallet) verifySecondFactor(ctx context.Context, user_id int, req TOTPCodeRequest) (bool, error) {
+	if req.RecoveryCode != "" {
+		return s.db.UseRecoveryCode(user_id, hashRecoveryCode(req.RecoveryCode), ctx)
+	}
+	state, err := s.db.GetTOTP(user_id, ctx)
+	if err != nil {
+		return false, err
+	}
+	if !state.Enabled {
+		return false, nil
+	}
+	return s.useTOTPCode(ctx, user_id, state.Secret, req.Code)
+}
+
+// useTOTPCode проверяет код и помечает его шаг использованным.
+func (s *ServerWallet) useTOTPCode(ctx context.Context, user_id int, secret, code string) (bool, error) {
+	if secret == "" {
+		return false, nil
+	}
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return s.db.UseTOTPStep(user_id, step, ctx)
+}
+
+func (s *ServerWallet) issuer() string {
+	if s.twoFactor.Issuer == "" {
+		return "gw-currency-wallet"
+	}
+	return s.twoFactor.Issuer
+}
+
+func (s *ServerWallet) preAuthTTL() time.Duration {
+	if s.twoFactor.Pre_auth_ttl_sec <= 0 {
+		return defaultPreAuthTTL
+	}
+	return time.Duration(s.twoFactor.Pre_auth_ttl_sec) * time.Second
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+	for i := 0; i < recoveryCodesCount; i++ {
+		buf := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		code := recoveryEncoding.EncodeToString(buf)
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode хранит коды восстановления как SHA-256: у них 80 бит
+// случайности, поэтому медленный хеш не нужен.
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
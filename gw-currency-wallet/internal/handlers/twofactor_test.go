@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gw-currency-wallet/internal/config"
+	"gw-currency-wallet/internal/limiter"
+	"gw-currency-wallet/internal/middleware"
+	"gw-currency-wallet/internal/storages"
+	"gw-currency-wallet/internal/totp"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoginWithTOTP(t *testing.T) {
+	secret, _ := totp.GenerateSecret()
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	mockDB := new(MockRepository)
+	mockDB.On("GetUser", "testuser", mock.Anything).Return(storages.User{Id: 1, Username: "testuser", Password: string(hashedPassword), TOTPEnabled: true}, nil)
+	mockDB.On("GetTOTP", 1, mock.Anything).Return(storages.TOTP{Secret: secret, Enabled: true}, nil)
+	mockDB.On("UseTOTPStep", 1, mock.Anything, mock.Anything).Return(true, nil).Once()
+	mockDB.On("UseTOTPStep", 1, mock.Anything, mock.Anything).Return(false, nil)
+	mockLogger := new(MockLogger)
+	mockLogger.On("InfoCtx", mock.Anything, mock.Anything).Return(nil)
+	mockLogger.On("ErrorCtx", mock.Anything, mock.Anything).Return(nil)
+
+	s := &ServerWallet{db: mockDB, lg: mockLogger}
+	handler := middleware.ValidatePreAuthJWT(http.HandlerFunc(s.LoginTOTP))
+
+	body, _ := json.Marshal(LoginRequest{Username: "testuser", Password: "password123"})
+	w := httptest.NewRecorder()
+	s.Autherisation(w, httptest.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(body)))
+	var login LoginResponse
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&login))
+	assert.True(t, login.TwoFactorRequired)
+
+	// Токен предварительной авторизации не открывает защищенные маршруты.
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/balance", nil)
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	middleware.ValidateJWT(http.HandlerFunc(s.GetBalance)).ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	code, _ := totp.Code(secret, totp.Step(time.Now()), totp.Digits)
+	submit := func() *httptest.ResponseRecorder {
+		body, _ := json.Marshal(TOTPCodeRequest{Code: code})
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/login/2fa", bytes.NewBuffer(body))
+		req.Header.Set("Authorization", "Bearer "+login.Token)
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w = submit()
+	assert.Equal(t, http.StatusOK, w.Code)
+	var full LoginResponse
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&full))
+	assert.False(t, full.TwoFactorRequired)
+	assert.NotEmpty(t, full.Token)
+
+	assert.Equal(t, http.StatusUnauthorized, submit().Code, "a code cannot be replayed")
+}
+
+func TestRequireTOTPForHighValueWithdraw(t *testing.T) {
+	secret, _ := totp.GenerateSecret()
+	mockDB := new(MockRepository)
+	mockDB.On("GetTOTP", 1, mock.Anything).Return(storages.TOTP{Secret: secret, Enabled: true}, nil)
+	mockLogger := new(MockLogger)
+	mockLogger.On("ErrorCtx", mock.Anything, mock.Anything).Return(nil)
+
+	s := &ServerWallet{
+		db:        mockDB,
+		lg:        mockLogger,
+		twoFactor: config.TwoFactor{Threshold: map[string]float64{"USD": 1000}},
+	}
+
+	body, _ := json.Marshal(WithdrawRequest{Amount: decimal.NewFromInt(5000), Currency: "USD"})
+	req := httptest.NewRequest(http.MethodPost, "/withdraw", bytes.NewBuffer(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.User_id, 1))
+	w := httptest.NewRecorder()
+	s.Withdraw(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	mockDB.AssertNotCalled(t, "Withdraw", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRequireTOTPLocksOutWrongCodes(t *testing.T) {
+	secret, _ := totp.GenerateSecret()
+	mockDB := new(MockRepository)
+	mockDB.On("GetTOTP", 1, mock.Anything).Return(storages.TOTP{Secret: secret, Enabled: true}, nil)
+	mockLogger := new(MockLogger)
+	mockLogger.On("ErrorCtx", mock.Anything, mock.Anything).Return(nil)
+	mockLogger.On("WarnCtx", mock.Anything, mock.Anything).Return(nil)
+
+	s := &ServerWallet{
+		db:        mockDB,
+		lg:        mockLogger,
+		twoFactor: config.TwoFactor{Threshold: map[string]float64{"USD": 1000}},
+		lockout:   limiter.NewLockout(limiter.NewMemoryStore(), 2, time.Minute, time.Minute, time.Hour),
+	}
+	// Неверный код с другого шага заведомо не совпадает с текущим.
+	wrong, _ := totp.Code(secret, totp.Step(time.Now())+100, totp.Digits)
+	withdraw := func() int {
+		body, _ := json.Marshal(WithdrawRequest{Amount: decimal.NewFromInt(5000), Currency: "USD"})
+		req := httptest.NewRequest(http.MethodPost, "/withdraw", bytes.NewBuffer(body))
+		req.Header.Set("X-TOTP-Code", wrong)
+		ctx := context.WithValue(req.Context(), middleware.User_id, 1)
+		req = req.WithContext(context.WithValue(ctx, middleware.UserNameconst, "testuser"))
+		w := httptest.NewRecorder()
+		s.Withdraw(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, withdraw())
+	assert.Equal(t, http.StatusUnauthorized, withdraw())
+	assert.Equal(t, http.StatusTooManyRequests, withdraw(), "wrong codes on money routes lock the user out")
+
+	wait, err := s.lockout.Check(context.Background(), "testuser")
+	assert.NoError(t, err)
+	assert.Positive(t, wait, "the lock is shared with /login/2fa")
+	mockDB.AssertNotCalled(t, "Withdraw", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
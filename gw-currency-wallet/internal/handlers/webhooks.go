@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"gw-currency-wallet/internal/middleware"
+	"gw-currency-wallet/internal/render"
+	"gw-currency-wallet/internal/storages"
+	"gw-currency-wallet/internal/validation"
+	"gw-currency-wallet/internal/webhooks"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+const (
+	webhookSecretBytes    = 32
+	defaultDeliveryLimit  = 50
+	maxDeliveryLimit      = 200
+	webhookSecretPrefix   = "whsec_"
+	maxWebhookURLLength   = 2048
+	maxWebhookEventFilter = 16
+)
+
+// CreateWebhookRequest — подписка на события. Пустой EventTypes — все события.
+type CreateWebhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+}
+
+type WebhooksResponse struct {
+	Webhooks []storages.Webhook `json:"webhooks"`
+}
+
+type WebhookDeliveriesResponse struct {
+	Deliveries []storages.WebhookDelivery `json:"deliveries"`
+}
+
+// @Summary Создание подписки на вебхуки
+// @Description Подписывает URL на события кошелька: wallet.deposited, wallet.withdrawn, wallet.exchanged,
+// @Description wallet.transfer_sent, wallet.transfer_received, wallet.hold_captured, order.placed, order.filled, order.cancelled и order.expired.
+// @Description Подписка пользователя получает его события, подписка партнера (/admin/webhooks) — события всех пользователей.
+// @Description Запросы подписаны заголовком X-Wallet-Signature: t=<unix>,v1=<hex HMAC-SHA256(secret, "<t>.<тело>")>.
+// @Description Секрет возвращается только в этом ответе.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param Authorization header string false "Bearer JWT_TOKEN"
+// @Param X-Admin-Token header string false "Admin token для /admin/webhooks"
+// @Param webhook body CreateWebhookRequest true "Данные подписки"
+// @Success 201 {object} storages.Webhook
+// @Failure 400 {object} render.ErrorResponse "Invalid input"
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Error creating webhook"
+// @Router /webhooks [post]
+// @Router /admin/webhooks [post]
+func (s *ServerWallet) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error decoding: %v", err))
+		render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Error decoding webhook request")
+		return
+	}
+	if fields := validateWebhook(req); len(fields) > 0 {
+		writeValidationError(w, r, fields)
+		return
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error generating webhook secret: %v", err))
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Error creating webhook")
+		return
+	}
+	sub := storages.Webhook{URL: req.URL, Secret: secret, EventTypes: req.EventTypes}
+	if sub.EventTypes == nil {
+		sub.EventTypes = []string{}
+	}
+	owner := webhookOwner(r)
+	if owner != 0 {
+		sub.UserID = &owner
+	}
+	sub, err = s.db.CreateWebhook(r.Context(), sub)
+	if err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error creating webhook: %v", err))
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Error creating webhook")
+		return
+	}
+	render.JSON(w, http.StatusCreated, sub)
+	s.lg.InfoCtx(r.Context(), fmt.Sprintf("Owner %d created webhook %d", owner, sub.ID))
+}
+
+// @Summary Список подписок на вебхуки
+// @Tags webhooks
+// @Produce json
+// @Param Authorization header string false "Bearer JWT_TOKEN"
+// @Param X-Admin-Token header string false "Admin token для /admin/webhooks"
+// @Success 200 {object} WebhooksResponse
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Could not list webhooks"
+// @Router /webhooks [get]
+// @Router /admin/webhooks [get]
+func (s *ServerWallet) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := s.db.ListWebhooks(r.Context(), webhookOwner(r))
+	if err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("error listing webhooks: %v", err))
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Could not list webhooks")
+		return
+	}
+	if hooks == nil {
+		hooks = []storages.Webhook{}
+	}
+	render.JSON(w, http.StatusOK, WebhooksResponse{Webhooks: hooks})
+}
+
+// @Summary Удаление подписки на вебхуки
+// @Description Удаляет подписку вместе с историей доставок.
+// @Tags webhooks
+// @Param Authorization header string false "Bearer JWT_TOKEN"
+// @Param X-Admin-Token header string false "Admin token для /admin/webhooks"
+// @Param id path int true "ID подписки"
+// @Success 204
+// @Failure 400 {object} render.ErrorResponse "Invalid webhook id"
+// @Failure 404 {object} render.ErrorResponse "Webhook not found"
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Error deleting webhook"
+// @Router /webhooks/{id} [delete]
+// @Router /admin/webhooks/{id} [delete]
+func (s *ServerWallet) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookPathID(w, r, "Invalid webhook id")
+	if !ok {
+		return
+	}
+	err := s.db.DeleteWebhook(r.Context(), webhookOwner(r), id)
+	if err == storages.ErrNoWebhook {
+		render.Error(w, r, http.StatusNotFound, render.CodeNotFound, "Webhook not found")
+		return
+	}
+	if err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error deleting webhook: %v", err))
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Error deleting webhook")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+	s.lg.InfoCtx(r.Context(), fmt.Sprintf("Owner %d deleted webhook %d", webhookOwner(r), id))
+}
+
+// @Summary История доставок вебхука
+// @Description Возвращает последние доставки подписки, новые первыми. Доставки в статусе dead исчерпали попытки
+// @Description и отправляются снова только через replay.
+// @Tags webhooks
+// @Produce json
+// @Param Authorization header string false "Bearer JWT_TOKEN"
+// @Param X-Admin-Token header string false "Admin token для /admin/webhooks"
+// @Param id path int true "ID подписки"
+// @Param status query string false "pending, delivered или dead"
+// @Param limit query int false "Число доставок, от 1 до 200, по умолчанию 50"
+// @Success 200 {object} WebhookDeliveriesResponse
+// @Failure 400 {object} render.ErrorResponse "Invalid input"
+// @Failure 404 {object} render.ErrorResponse "Webhook not found"
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Could not list deliveries"
+// @Router /webhooks/{id}/deliveries [get]
+// @Router /admin/webhooks/{id}/deliveries [get]
+func (s *ServerWallet) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookPathID(w, r, "Invalid webhook id")
+	if !ok {
+		return
+	}
+	var fields []validation.FieldError
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", storages.DeliveryPending, storages.DeliveryDelivered, storages.DeliveryDead:
+	default:
+		fields = append(fields, validation.FieldError{Field: "status", Message: "must be one of pending, delivered, dead"})
+	}
+	limit := defaultDeliveryLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxDeliveryLimit {
+			fields = append(fields, validation.FieldError{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", maxDeliveryLimit)})
+		}
+		limit = n
+	}
+	if len(fields) > 0 {
+		writeValidationError(w, r, fields)
+		return
+	}
+	deliveries, err := s.db.ListWebhookDeliveries(r.Context(), webhookOwner(r), id, status, limit)
+	if err == storages.ErrNoWebhook {
+		render.Error(w, r, http.StatusNotFound, render.CodeNotFound, "Webhook not found")
+		return
+	}
+	if err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("error listing webhook deliveries: %v", err))
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Could not list deliveries")
+		return
+	}
+	if deliveries == nil {
+		deliveries = []storages.WebhookDelivery{}
+	}
+	render.JSON(w, http.StatusOK, WebhookDeliveriesResponse{Deliveries: deliveries})
+}
+
+// @Summary Повторная отправка вебхука
+// @Description Ставит доставку в очередь заново с обнулением попыток, в том числе доставку в статусе dead.
+// @Description Событие отправляется с тем же id, чтобы получатель мог отбросить дубль.
+// @Tags webhooks
+// @Produce json
+// @Param Authorization header string false "Bearer JWT_TOKEN"
+// @Param X-Admin-Token header string false "Admin token для /admin/webhooks"
+// @Param id path int true "ID доставки"
+// @Success 202 {object} storages.WebhookDelivery
+// @Failure 400 {object} render.ErrorResponse "Invalid delivery id"
+// @Failure 404 {object} render.ErrorResponse "Delivery not found"
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Error replaying delivery"
+// @Router /webhooks/deliveries/{id}/replay [post]
+// @Router /admin/webhooks/deliveries/{id}/replay [post]
+func (s *ServerWallet) ReplayWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookPathID(w, r, "Invalid delivery id")
+	if !ok {
+		return
+	}
+	delivery, err := s.db.ReplayDelivery(r.Context(), webhookOwner(r), id)
+	if err == storages.ErrNoDelivery {
+		render.Error(w, r, http.StatusNotFound, render.CodeNotFound, "Delivery not found")
+		return
+	}
+	if err != nil {
+		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error replaying delivery: %v", err))
+		render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Error replaying delivery")
+		return
+	}
+	render.JSON(w, http.StatusAccepted, delivery)
+	s.lg.InfoCtx(r.Context(), fmt.Sprintf("Owner %d replayed delivery %d", webhookOwner(r), id))
+}
+
+// webhookOwner — пользователь из JWT или 0 для партнерских маршрутов
+// /admin/webhooks, где пользователя нет.
+func webhookOwner(r *http.Request) int {
+	user_id, _ := r.Context().Value(middleware.User_id).(int)
+	return user_id
+}
+
+func webhookPathID(w http.ResponseWriter, r *http.Request, message string) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, message)
+		return 0, false
+	}
+	return id, true
+}
+
+func validateWebhook(req CreateWebhookRequest) []validation.FieldError {
+	var fields []validation.FieldError
+	if err := webhooks.CheckURL(req.URL); err == webhooks.ErrForbiddenAddress {
+		fields = append(fields, validation.FieldError{Field: "url", Message: "must not point to a local or private network"})
+	} else if err != nil || len(req.URL) > maxWebhookURLLength {
+		fields = append(fields, validation.FieldError{Field: "url", Message: "must be an absolute http or https URL"})
+	}
+	if len(req.EventTypes) > maxWebhookEventFilter {
+		fields = append(fields, validation.FieldError{Field: "event_types", Message: "too many event types"})
+	}
+	for _, t := range req.EventTypes {
+		if !slices.Contains(storages.EventTypes, t) {
+			fields = append(fields, validation.FieldError{Field: "event_types", Message: fmt.Sprintf("unknown event type %q", t)})
+			break
+		}
+	}
+	return fields
+}
+
+func newWebhookSecret() (string, error) {
+	buf := make([]byte, webhookSecretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + hex.EncodeToString(buf), nil
+}
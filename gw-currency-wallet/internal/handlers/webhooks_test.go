@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gw-currency-wallet/internal/middleware"
+	"gw-currency-wallet/internal/storages"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateWebhook(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		user_id        int
+		mockRepo       func(m *MockRepository)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:    "User subscription",
+			body:    `{"url":"https://example.com/hooks","event_types":["wallet.deposited"]}`,
+			user_id: 1,
+			mockRepo: func(m *MockRepository) {
+				m.On("CreateWebhook", mock.Anything, mock.MatchedBy(func(sub storages.Webhook) bool {
+					return sub.UserID != nil && *sub.UserID == 1 && sub.URL == "https://example.com/hooks" &&
+						strings.HasPrefix(sub.Secret, "whsec_") && len(sub.EventTypes) == 1
+				})).Return(storages.Webhook{ID: 3, Secret: "whsec_abc", Active: true}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "Partner subscription to all events",
+			body: `{"url":"http://partner.example.net/wallet"}`,
+			mockRepo: func(m *MockRepository) {
+				m.On("CreateWebhook", mock.Anything, mock.MatchedBy(func(sub storages.Webhook) bool {
+					return sub.UserID == nil && sub.EventTypes != nil && len(sub.EventTypes) == 0
+				})).Return(storages.Webhook{ID: 4, Secret: "whsec_def", Active: true}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Cloud metadata address",
+			body:           `{"url":"http://169.254.169.254/latest/meta-data"}`,
+			user_id:        1,
+			mockRepo:       func(m *MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"code":"validation_failed","message":"Invalid input","details":[
+				{"field":"url","message":"must not point to a local or private network"}]}`,
+		},
+		{
+			name:           "Internal service name",
+			body:           `{"url":"http://postgres:5432"}`,
+			user_id:        1,
+			mockRepo:       func(m *MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"code":"validation_failed","message":"Invalid input","details":[
+				{"field":"url","message":"must not point to a local or private network"}]}`,
+		},
+		{
+			name:           "Partner subscription to user.registered",
+			body:           `{"url":"https://partner.example.net/wallet","event_types":["user.registered"]}`,
+			mockRepo:       func(m *MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"code":"validation_failed","message":"Invalid input","details":[
+				{"field":"event_types","message":"unknown event type \"user.registered\""}]}`,
+		},
+		{
+			name:           "Relative URL and unknown event",
+			body:           `{"url":"/hooks","event_types":["wallet.stolen"]}`,
+			user_id:        1,
+			mockRepo:       func(m *MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"code":"validation_failed","message":"Invalid input","details":[
+				{"field":"url","message":"must be an absolute http or https URL"},
+				{"field":"event_types","message":"unknown event type \"wallet.stolen\""}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockLogger := new(MockLogger)
+			tt.mockRepo(mockRepo)
+			mockLogger.On("InfoCtx", mock.Anything, mock.Anything).Return(nil)
+			s := &ServerWallet{db: mockRepo, lg: mockLogger}
+
+			req := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewBufferString(tt.body))
+			if tt.user_id != 0 {
+				req = req.WithContext(context.WithValue(req.Context(), middleware.User_id, tt.user_id))
+			}
+			w := httptest.NewRecorder()
+
+			s.CreateWebhook(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+			if tt.expectedStatus == http.StatusCreated {
+				var sub storages.Webhook
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &sub))
+				assert.NotEmpty(t, sub.Secret, "secret is shown once on creation")
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReplayWebhookDelivery(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{name: "Dead delivery is queued again", expectedStatus: http.StatusAccepted},
+		{name: "Someone else's delivery", err: storages.ErrNoDelivery, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockLogger := new(MockLogger)
+			mockRepo.On("ReplayDelivery", mock.Anything, 1, int64(9)).
+				Return(storages.WebhookDelivery{ID: 9, Status: storages.DeliveryPending}, tt.err)
+			mockLogger.On("InfoCtx", mock.Anything, mock.Anything).Return(nil)
+			s := &ServerWallet{db: mockRepo, lg: mockLogger}
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "9")
+			ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/deliveries/9/replay", nil)
+			req = req.WithContext(context.WithValue(ctx, middleware.User_id, 1))
+			w := httptest.NewRecorder()
+
+			s.ReplayWebhookDelivery(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestListWebhookDeliveriesValidatesQuery(t *testing.T) {
+	mockRepo := new(MockRepository)
+	s := &ServerWallet{db: mockRepo, lg: new(MockLogger)}
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "3")
+	ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+	req := httptest.NewRequest(http.MethodGet, "/webhooks/3/deliveries?status=lost&limit=0", nil)
+	req = req.WithContext(context.WithValue(ctx, middleware.User_id, 1))
+	w := httptest.NewRecorder()
+
+	s.ListWebhookDeliveries(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	assert.JSONEq(t, `{"code":"validation_failed","message":"Invalid input","details":[
+		{"field":"status","message":"must be one of pending, delivered, dead"},
+		{"field":"limit","message":"must be between 1 and 200"}]}`, w.Body.String())
+	mockRepo.AssertExpectations(t)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"gw-currency-wallet/internal/middleware"
+	"gw-currency-wallet/internal/render"
 	"gw-currency-wallet/internal/storages"
 	"net/http"
 
@@ -25,63 +26,67 @@ type WithdrawResponse struct {
 // @Accept json
 // @Produce json
 // @Param Authorization header string true "Bearer JWT_TOKEN"
+// @Param X-TOTP-Code header string false "TOTP-код для сумм от порога two_factor.threshold"
 // @Param withdraw body WithdrawRequest true "Данные для вывода средств"
 // @Success 200 {object} WithdrawResponse
-// @Failure 400 {object} ErrorResponse "Error decoding WithdrawResponse"
-// @Failure 400 {object} ErrorResponse "Insufficient funds or invalid amount"
-// @Failure 400 {object} ErrorResponse "Amount cannot have more than two decimal places"
-// @Failure 500 {object} ErrorResponse "Error depositing funds"
-// @Failure 500 {object} ErrorResponse "Error getting balance from db"
+// @Failure 400 {object} render.ErrorResponse "Error decoding WithdrawResponse"
+// @Failure 400 {object} render.ErrorResponse "Insufficient funds or invalid amount"
+// @Failure 400 {object} render.ErrorResponse "Invalid input: unknown currency or non-positive amount"
+// @Failure 400 {object} render.ErrorResponse "Amount cannot have more than two decimal places"
+// @Failure 401 {object} render.ErrorResponse "TOTP code required"
+// @Failure 403 {object} render.ErrorResponse "Account is frozen or closed"
+// @Failure 422 {object} render.ErrorResponse "Limit exceeded"
+// @Failure 429 {object} render.ErrorResponse "Too many requests"
+// @Failure 500 {object} render.ErrorResponse "Error withdrawing funds"
 // @Router /withdraw [post]
 func (s *ServerWallet) Withdraw(w http.ResponseWriter, r *http.Request) {
 	var req WithdrawRequest
 	res := new(WithdrawResponse)
-	errRes := new(ErrorResponse)
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error decoding: %v", err))
-		errRes.message = "Error decoding WithdrawResponse"
-		json.NewEncoder(w).Encode(errRes)
-		http.Error(w, "Error decoding WithdrawResponse", http.StatusBadRequest)
+		render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Error decoding WithdrawResponse")
 		return
 	}
 
+	if fields := validateAmount("currency", req.Currency, req.Amount); len(fields) > 0 {
+		s.lg.ErrorCtx(r.Context(), "Invalid amount or currency")
+		writeValidationError(w, r, fields)
+		return
+	}
 	if req.Amount.Exponent() < -2 {
 		s.lg.ErrorCtx(r.Context(), "Amount cannot have more than two decimal places")
-		errRes.message = "Amount cannot have more than two decimal places"
-		json.NewEncoder(w).Encode(errRes)
-		http.Error(w, "Amount cannot have more than two decimal places", http.StatusBadRequest)
+		render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Amount cannot have more than two decimal places")
 		return
 	}
 
 	ctx := r.Context()
 	user_id := ctx.Value(middleware.User_id).(int)
 
-	err := s.db.Withdraw(user_id, req.Amount, req.Currency, r.Context())
+	if !s.requireTOTP(w, r, user_id, req.Currency, req.Amount) {
+		return
+	}
+
+	balance, err := s.db.Withdraw(user_id, req.Amount, req.Currency, r.Context())
 	if err != nil {
-		if err == storages.ErrWithdraw {
+		if writeLimitError(w, r, err) {
+			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("withdraw refused: %v", err))
+			return
+		} else if err == storages.ErrWithdraw {
 			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error insufficient funds or invalid amount: %v", err))
-			errRes.message = "Insufficient funds or invalid amount"
-			json.NewEncoder(w).Encode(errRes)
-			http.Error(w, "Insufficient funds or invalid amount", http.StatusBadRequest)
+			render.Error(w, r, http.StatusBadRequest, render.CodeInsufficientFunds, "Insufficient funds or invalid amount")
+			return
+		} else if err == storages.ErrInactive {
+			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("withdraw refused: %v", err))
+			render.Error(w, r, http.StatusForbidden, render.CodeAccountInactive, "Account is frozen or closed")
 			return
 		} else {
 			s.lg.ErrorCtx(r.Context(), fmt.Sprintf("Error withdrawing funds: %v", err))
-			errRes.message = "Error depositing funds"
-			json.NewEncoder(w).Encode(errRes)
-			http.Error(w, "Error depositing funds", http.StatusBadRequest)
+			render.Error(w, r, http.StatusInternalServerError, render.CodeInternal, "Error withdrawing funds")
 			return
 		}
 	}
-	res.NewBalance, err = s.db.GetBalance(user_id, r.Context())
-	if err != nil {
-		s.lg.ErrorCtx(r.Context(), fmt.Sprintf("error getting balance: %v", err))
-		errRes.message = "Error getting balance from db"
-		json.NewEncoder(w).Encode(errRes)
-		http.Error(w, "Error getting balance", http.StatusInternalServerError)
-		return
-	}
+	res.NewBalance = balance
 	res.Message = "Withdrawal successful"
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(res)
+	render.JSON(w, http.StatusOK, res)
 	s.lg.InfoCtx(r.Context(), fmt.Sprintf("User %d withdrew successfully", user_id))
 }
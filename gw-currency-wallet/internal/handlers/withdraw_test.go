@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gw-currency-wallet/internal/middleware"
+	"gw-currency-wallet/internal/storages"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestWithdraw(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          WithdrawRequest
+		mockWithdraw   func(m *MockRepository)
+		mockLogger     func(m *MockLogger)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "Successful withdraw",
+			input: WithdrawRequest{Amount: decimal.NewFromInt(10), Currency: "USD"},
+			mockWithdraw: func(m *MockRepository) {
+				m.On("Withdraw", 1, decimal.NewFromInt(10), "USD", mock.Anything).Return(storages.Balance{USD: decimal.NewFromInt(90)}, nil)
+			},
+			mockLogger: func(m *MockLogger) {
+				m.On("InfoCtx", mock.Anything, "User 1 withdrew successfully").Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"message":"Withdrawal successful","new_balance":{"USD":"90","RUB":"0","EUR":"0"}}`,
+		},
+		{
+			name:  "Frozen account",
+			input: WithdrawRequest{Amount: decimal.NewFromInt(10), Currency: "USD"},
+			mockWithdraw: func(m *MockRepository) {
+				m.On("Withdraw", 1, decimal.NewFromInt(10), "USD", mock.Anything).Return(storages.Balance{}, storages.ErrInactive)
+			},
+			mockLogger: func(m *MockLogger) {
+				m.On("ErrorCtx", mock.Anything, mock.Anything).Return(nil)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:  "Daily limit exceeded",
+			input: WithdrawRequest{Amount: decimal.NewFromInt(500), Currency: "USD"},
+			mockWithdraw: func(m *MockRepository) {
+				m.On("Withdraw", 1, decimal.NewFromInt(500), "USD", mock.Anything).Return(storages.Balance{}, &storages.LimitError{
+					Limit:     storages.LimitDailyWithdrawal,
+					Currency:  "USD",
+					Remaining: decimal.NewFromInt(200),
+				})
+			},
+			mockLogger: func(m *MockLogger) {
+				m.On("ErrorCtx", mock.Anything, mock.Anything).Return(nil)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody: `{"code":"limit_exceeded","message":"Limit exceeded",
+				"details":{"limit":"daily_withdrawal","currency":"USD","remaining":"200"}}`,
+		},
+		{
+			name:         "Negative amount",
+			input:        WithdrawRequest{Amount: decimal.NewFromInt(-100), Currency: "USD"},
+			mockWithdraw: func(m *MockRepository) {},
+			mockLogger: func(m *MockLogger) {
+				m.On("ErrorCtx", mock.Anything, mock.Anything).Return(nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"code":"validation_failed","message":"Invalid input",
+				"details":[{"field":"amount","message":"must be positive"}]}`,
+		},
+		{
+			name:         "Unknown currency",
+			input:        WithdrawRequest{Amount: decimal.NewFromInt(10), Currency: "GBP"},
+			mockWithdraw: func(m *MockRepository) {},
+			mockLogger: func(m *MockLogger) {
+				m.On("ErrorCtx", mock.Anything, mock.Anything).Return(nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"code":"validation_failed","message":"Invalid input",
+				"details":[{"field":"currency","message":"must be one of USD, RUB, EUR"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockLogger := new(MockLogger)
+			tt.mockWithdraw(mockRepo)
+			tt.mockLogger(mockLogger)
+
+			s := &ServerWallet{
+				db: mockRepo,
+				lg: mockLogger,
+			}
+
+			body, _ := json.Marshal(tt.input)
+			req := httptest.NewRequest(http.MethodPost, "/withdraw", bytes.NewBuffer(body))
+			req = req.WithContext(context.WithValue(req.Context(), middleware.User_id, 1))
+			w := httptest.NewRecorder()
+
+			s.Withdraw(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
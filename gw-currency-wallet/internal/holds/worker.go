@@ -0,0 +1,61 @@
+// Package holds закрывает холды с истекшим сроком.
+package holds
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gw-currency-wallet/internal/logger"
+)
+
+const defaultInterval = time.Minute
+
+// Store — операции репозитория, которые нужны обработчику холдов.
+type Store interface {
+	ExpireHolds(ctx context.Context) (int64, error)
+}
+
+// Worker по таймеру переводит просроченные холды в expired. Просроченный
+// холд перестает резервировать остаток сразу, поэтому интервал влияет только
+// на то, когда холд получит статус в базе.
+type Worker struct {
+	store    Store
+	lg       logger.Logger
+	interval time.Duration
+}
+
+func NewWorker(store Store, lg logger.Logger, interval time.Duration) *Worker {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return &Worker{store: store, lg: lg, interval: interval}
+}
+
+// Run выполняет Expire каждые interval до отмены ctx.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := w.Expire(ctx); err != nil && ctx.Err() == nil {
+			w.lg.WarnCtx(ctx, fmt.Sprintf("holds: expire: %v", err))
+		}
+	}
+}
+
+// Expire закрывает просроченные холды.
+func (w *Worker) Expire(ctx context.Context) error {
+	n, err := w.store.ExpireHolds(ctx)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		w.lg.InfoCtx(ctx, fmt.Sprintf("holds: %d holds expired", n))
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package holds
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) DebugCtx(ctx context.Context, msg string)            {}
+func (nopLogger) InfoCtx(ctx context.Context, msg string)             {}
+func (nopLogger) WarnCtx(ctx context.Context, msg string)             {}
+func (nopLogger) ErrorCtx(ctx context.Context, msg string)            {}
+func (nopLogger) FatalCtx(ctx context.Context, msg string, err error) {}
+
+type fakeStore struct {
+	mu    sync.Mutex
+	calls int
+	err   error
+}
+
+func (f *fakeStore) ExpireHolds(ctx context.Context) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	return 1, f.err
+}
+
+func (f *fakeStore) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func TestRunExpiresUntilCancelled(t *testing.T) {
+	store := &fakeStore{err: errors.New("connection refused")}
+	w := NewWorker(store, nopLogger{}, time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool { return store.count() >= 3 }, time.Second, time.Millisecond,
+		"an error does not stop the worker")
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not stop after ctx was cancelled")
+	}
+	stopped := store.count()
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, stopped, store.count(), "no expiry after Run returned")
+}
+
+func TestExpireReturnsStoreError(t *testing.T) {
+	store := &fakeStore{err: errors.New("connection refused")}
+	assert.Error(t, NewWorker(store, nopLogger{}, 0).Expire(context.Background()))
+	assert.NoError(t, NewWorker(&fakeStore{}, nopLogger{}, 0).Expire(context.Background()))
+}
@@ -0,0 +1,69 @@
+package limiter
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// TokenBucket выдает каждому ключу до capacity запросов подряд и пополняет
+// запас равномерно: capacity токенов за период per.
+type TokenBucket struct {
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	capacity float64
+	rate     float64
+	now      func() time.Time
+	calls    int
+}
+
+func NewTokenBucket(capacity int, per time.Duration) *TokenBucket {
+	b := new(TokenBucket)
+	b.buckets = make(map[string]*bucket)
+	b.capacity = float64(capacity)
+	b.rate = float64(capacity) / per.Seconds()
+	b.now = time.Now
+	return b
+}
+
+// Take забирает токен для key. Если токенов нет, возвращает false и время
+// до появления следующего токена.
+func (b *TokenBucket) Take(key string) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.prune(now)
+	bk, ok := b.buckets[key]
+	if !ok {
+		bk = &bucket{tokens: b.capacity, last: now}
+		b.buckets[key] = bk
+	}
+	bk.tokens = math.Min(b.capacity, bk.tokens+now.Sub(bk.last).Seconds()*b.rate)
+	bk.last = now
+	if bk.tokens >= 1 {
+		bk.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - bk.tokens) / b.rate * float64(time.Second))
+	return false, retryAfter(wait)
+}
+
+// prune периодически удаляет корзины, которые успели наполниться полностью:
+// они ничем не отличаются от новых.
+func (b *TokenBucket) prune(now time.Time) {
+	b.calls++
+	if b.calls%memoryPruneEvery != 0 {
+		return
+	}
+	for key, bk := range b.buckets {
+		if bk.tokens+now.Sub(bk.last).Seconds()*b.rate >= b.capacity {
+			delete(b.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucketTake(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	b := NewTokenBucket(10, time.Minute)
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 10; i++ {
+		ok, _ := b.Take("user:1")
+		assert.True(t, ok)
+	}
+	ok, wait := b.Take("user:1")
+	assert.False(t, ok)
+	assert.Equal(t, 7*time.Second, wait)
+
+	ok, _ = b.Take("user:2")
+	assert.True(t, ok, "other users have their own bucket")
+
+	now = now.Add(6 * time.Second)
+	ok, _ = b.Take("user:1")
+	assert.True(t, ok, "one token is refilled every 6 seconds")
+	ok, _ = b.Take("user:1")
+	assert.False(t, ok)
+}
@@ -0,0 +1,121 @@
+package limiter
+
+import (
+	"context"
+	"time"
+)
+
+// Store хранит счетчики попыток в фиксированных окнах и блокировки по ключу.
+type Store interface {
+	// Hit увеличивает счетчик key и возвращает его значение в текущем окне
+	// длиной window и момент окончания этого окна.
+	Hit(ctx context.Context, key string, window time.Duration) (int, time.Time, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	LockedUntil(ctx context.Context, key string) (time.Time, error)
+	Reset(ctx context.Context, key string) error
+}
+
+// Limiter ограничивает количество запросов по ключу в фиксированном окне.
+type Limiter struct {
+	store  Store
+	limit  int
+	window time.Duration
+	now    func() time.Time
+}
+
+func NewLimiter(store Store, limit int, window time.Duration) *Limiter {
+	l := new(Limiter)
+	l.store = store
+	l.limit = limit
+	l.window = window
+	l.now = time.Now
+	return l
+}
+
+// Allow учитывает запрос по key. Если лимит исчерпан, возвращает false и время,
+// через которое окно закончится.
+func (l *Limiter) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	if l.limit <= 0 {
+		return true, 0, nil
+	}
+	count, windowEnd, err := l.store.Hit(ctx, key, l.window)
+	if err != nil {
+		return true, 0, err
+	}
+	if count > l.limit {
+		return false, retryAfter(windowEnd.Sub(l.now())), nil
+	}
+	return true, 0, nil
+}
+
+// Lockout блокирует ключ после серии неудачных попыток. Каждая следующая
+// неудача после порога удваивает время блокировки, но не больше maxLock.
+type Lockout struct {
+	store       Store
+	maxFailures int
+	window      time.Duration
+	baseLock    time.Duration
+	maxLock     time.Duration
+	now         func() time.Time
+}
+
+func NewLockout(store Store, maxFailures int, window, baseLock, maxLock time.Duration) *Lockout {
+	l := new(Lockout)
+	l.store = store
+	l.maxFailures = maxFailures
+	l.window = window
+	l.baseLock = baseLock
+	l.maxLock = maxLock
+	l.now = time.Now
+	return l
+}
+
+// Check возвращает оставшееся время блокировки key или 0, если ключ свободен.
+func (l *Lockout) Check(ctx context.Context, key string) (time.Duration, error) {
+	until, err := l.store.LockedUntil(ctx, "lock:"+key)
+	if err != nil {
+		return 0, err
+	}
+	if d := until.Sub(l.now()); d > 0 {
+		return retryAfter(d), nil
+	}
+	return 0, nil
+}
+
+// Fail учитывает неудачную попытку и при превышении порога блокирует key.
+func (l *Lockout) Fail(ctx context.Context, key string) error {
+	if l.maxFailures <= 0 {
+		return nil
+	}
+	failures, _, err := l.store.Hit(ctx, "fail:"+key, l.window)
+	if err != nil {
+		return err
+	}
+	if failures < l.maxFailures {
+		return nil
+	}
+	lock := l.baseLock
+	for i := l.maxFailures; i < failures && lock < l.maxLock; i++ {
+		lock *= 2
+	}
+	if lock > l.maxLock {
+		lock = l.maxLock
+	}
+	return l.store.Lock(ctx, "lock:"+key, l.now().Add(lock))
+}
+
+// Success сбрасывает счетчик неудач и блокировку key.
+func (l *Lockout) Success(ctx context.Context, key string) error {
+	if err := l.store.Reset(ctx, "fail:"+key); err != nil {
+		return err
+	}
+	return l.store.Reset(ctx, "lock:"+key)
+}
+
+// retryAfter округляет d вверх до целых секунд для заголовка Retry-After.
+func retryAfter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return time.Second
+	}
+	return d.Truncate(time.Second) + time.Second
+}
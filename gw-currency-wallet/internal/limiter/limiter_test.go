@@ -0,0 +1,67 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	now := time.Unix(1700000000, 0)
+	store.now = func() time.Time { return now }
+	l := NewLimiter(store, 2, time.Minute)
+	l.now = store.now
+
+	for i := 0; i < 2; i++ {
+		ok, _, err := l.Allow(ctx, "ip:1.2.3.4")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	}
+	ok, wait, err := l.Allow(ctx, "ip:1.2.3.4")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, time.Minute+time.Second, wait)
+
+	ok, _, _ = l.Allow(ctx, "ip:5.6.7.8")
+	assert.True(t, ok, "other keys have their own budget")
+
+	now = now.Add(time.Minute)
+	ok, _, _ = l.Allow(ctx, "ip:1.2.3.4")
+	assert.True(t, ok, "budget is restored in the next window")
+}
+
+func TestLockoutProgressive(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	now := time.Unix(1700000000, 0)
+	store.now = func() time.Time { return now }
+	l := NewLockout(store, 3, time.Hour, 10*time.Second, 30*time.Second)
+	l.now = store.now
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, l.Fail(ctx, "alice"))
+	}
+	wait, err := l.Check(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Zero(t, wait)
+
+	assert.NoError(t, l.Fail(ctx, "alice"))
+	wait, _ = l.Check(ctx, "alice")
+	assert.Equal(t, 11*time.Second, wait)
+
+	assert.NoError(t, l.Fail(ctx, "alice"))
+	wait, _ = l.Check(ctx, "alice")
+	assert.Equal(t, 21*time.Second, wait)
+
+	assert.NoError(t, l.Fail(ctx, "alice"))
+	wait, _ = l.Check(ctx, "alice")
+	assert.Equal(t, 31*time.Second, wait, "lock is capped at maxLock")
+
+	assert.NoError(t, l.Success(ctx, "alice"))
+	wait, _ = l.Check(ctx, "alice")
+	assert.Zero(t, wait)
+}
@@ -0,0 +1,93 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	hits        int
+	windowEnd   time.Time
+	lockedUntil time.Time
+}
+
+// MemoryStore хранит счетчики в памяти процесса. Подходит для одного экземпляра сервиса.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	now     func() time.Time
+	calls   int
+}
+
+const memoryPruneEvery = 1000
+
+func NewMemoryStore() *MemoryStore {
+	m := new(MemoryStore)
+	m.entries = make(map[string]*memoryEntry)
+	m.now = time.Now
+	return m
+}
+
+func (m *MemoryStore) Hit(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.prune(now)
+	e, ok := m.entries[key]
+	if !ok {
+		e = new(memoryEntry)
+		m.entries[key] = e
+	}
+	if !now.Before(e.windowEnd) {
+		e.hits = 0
+		e.windowEnd = now.Add(window)
+	}
+	e.hits++
+	return e.hits, e.windowEnd, nil
+}
+
+func (m *MemoryStore) Lock(ctx context.Context, key string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[key]
+	if !ok {
+		e = new(memoryEntry)
+		m.entries[key] = e
+	}
+	e.lockedUntil = until
+	return nil
+}
+
+func (m *MemoryStore) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.entries[key]; ok {
+		return e.lockedUntil, nil
+	}
+	return time.Time{}, nil
+}
+
+func (m *MemoryStore) Reset(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, key)
+	return nil
+}
+
+// prune периодически удаляет записи с истекшими окном и блокировкой.
+func (m *MemoryStore) prune(now time.Time) {
+	m.calls++
+	if m.calls%memoryPruneEvery != 0 {
+		return
+	}
+	for key, e := range m.entries {
+		if now.After(e.windowEnd) && now.After(e.lockedUntil) {
+			delete(m.entries, key)
+		}
+	}
+}
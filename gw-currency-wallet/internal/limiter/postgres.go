@@ -0,0 +1,68 @@
+package limiter
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type DBPool interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// PostgresStore хранит счетчики в таблице rate_limits, чтобы лимиты
+// соблюдались сразу всеми экземплярами сервиса.
+type PostgresStore struct {
+	db DBPool
+}
+
+func NewPostgresStore(db DBPool) *PostgresStore {
+	p := new(PostgresStore)
+	p.db = db
+	return p
+}
+
+func (p *PostgresStore) Hit(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	var hits int
+	var windowEnd time.Time
+	err := p.db.QueryRow(ctx, `
+		INSERT INTO rate_limits (key, hits, window_end) VALUES ($1, 1, now() + $2 * interval '1 millisecond')
+		ON CONFLICT (key) DO UPDATE SET
+			hits = CASE WHEN rate_limits.window_end <= now() THEN 1 ELSE rate_limits.hits + 1 END,
+			window_end = CASE WHEN rate_limits.window_end <= now() THEN EXCLUDED.window_end ELSE rate_limits.window_end END
+		RETURNING hits, window_end`, key, window.Milliseconds()).Scan(&hits, &windowEnd)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return hits, windowEnd, nil
+}
+
+func (p *PostgresStore) Lock(ctx context.Context, key string, until time.Time) error {
+	_, err := p.db.Exec(ctx, `
+		INSERT INTO rate_limits (key, hits, window_end, locked_until) VALUES ($1, 0, now(), $2)
+		ON CONFLICT (key) DO UPDATE SET locked_until = EXCLUDED.locked_until`, key, until)
+	return err
+}
+
+func (p *PostgresStore) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+	var until *time.Time
+	err := p.db.QueryRow(ctx, "SELECT locked_until FROM rate_limits WHERE key = $1", key).Scan(&until)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	if until == nil {
+		return time.Time{}, nil
+	}
+	return *until, nil
+}
+
+func (p *PostgresStore) Reset(ctx context.Context, key string) error {
+	_, err := p.db.Exec(ctx, "DELETE FROM rate_limits WHERE key = $1", key)
+	return err
+}
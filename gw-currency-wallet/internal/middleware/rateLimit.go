@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gw-currency-wallet/internal/render"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gw-currency-wallet/internal/limiter"
+	"gw-currency-wallet/internal/logger"
+)
+
+const maxLimitedBody = 1 << 20
+
+// RateLimit ограничивает частоту запросов по IP клиента и по полю username
+// из JSON-тела. Тело запроса восстанавливается для следующего обработчика.
+// Ошибки хранилища не блокируют запрос, а только логируются.
+func RateLimit(ipLimiter, userLimiter *limiter.Limiter, lg logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if ipLimiter != nil {
+				ok, wait, err := ipLimiter.Allow(ctx, "ip:"+ClientIP(r))
+				if err != nil {
+					lg.ErrorCtx(ctx, fmt.Sprintf("rate limit by ip failed: %v", err))
+				}
+				if !ok {
+					TooManyRequests(w, r, wait)
+					return
+				}
+			}
+
+			if userLimiter != nil {
+				body, err := io.ReadAll(io.LimitReader(r.Body, maxLimitedBody))
+				if err != nil {
+					render.Error(w, r, http.StatusBadRequest, render.CodeInvalidInput, "Invalid input")
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+
+				var payload struct {
+					Username string `json:"username"`
+				}
+				if json.Unmarshal(body, &payload) == nil && payload.Username != "" {
+					ok, wait, err := userLimiter.Allow(ctx, "user:"+payload.Username)
+					if err != nil {
+						lg.ErrorCtx(ctx, fmt.Sprintf("rate limit by username failed: %v", err))
+					}
+					if !ok {
+						TooManyRequests(w, r, wait)
+						return
+					}
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// TooManyRequests отвечает 429 с заголовком Retry-After в секундах.
+func TooManyRequests(w http.ResponseWriter, r *http.Request, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())))
+	render.Error(w, r, http.StatusTooManyRequests, render.CodeTooManyRequests, "Too many requests")
+}
+
+// ClientIP возвращает IP-адрес клиента из RemoteAddr без порта.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
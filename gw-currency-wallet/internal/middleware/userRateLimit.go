@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"fmt"
+	"gw-currency-wallet/internal/render"
+	"net/http"
+
+	"gw-currency-wallet/internal/limiter"
+)
+
+// UserRateLimit ограничивает частоту запросов пользователя из JWT.
+// Должно стоять после ValidateJWT.
+func UserRateLimit(b *limiter.TokenBucket) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user_id, ok := r.Context().Value(User_id).(int)
+			if !ok {
+				render.Error(w, r, http.StatusUnauthorized, render.CodeUnauthorized, "Invalid token")
+				return
+			}
+			if ok, wait := b.Take(fmt.Sprintf("user:%d", user_id)); !ok {
+				TooManyRequests(w, r, wait)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
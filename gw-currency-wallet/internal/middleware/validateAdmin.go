@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"gw-currency-wallet/internal/render"
+	"net/http"
+)
+
+// ValidateAdminToken пропускает запрос только с заголовком X-Admin-Token,
+// совпадающим с token. Пустой token закрывает админские маршруты полностью.
+func ValidateAdminToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got := r.Header.Get("X-Admin-Token")
+			if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				render.Error(w, r, http.StatusUnauthorized, render.CodeUnauthorized, "Invalid admin token")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"context"
+	"gw-currency-wallet/internal/render"
+	"strings"
 
 	"net/http"
 
@@ -13,29 +15,43 @@ var jwtKey = []byte("your_secret_key")
 const UserNameconst = "username"
 const User_id = "user_id"
 
+// ScopePreAuth помечает токен, выданный после пароля, но до проверки второго фактора.
+const ScopePreAuth = "2fa"
+
 type Claims struct {
 	Username string `json:"username"`
 	Id       int    `json:"id"`
+	Scope    string `json:"scope,omitempty"`
 	jwt.StandardClaims
 }
 
 func ValidateJWT(next http.Handler) http.Handler {
+	return validateScope("", next)
+}
+
+// ValidatePreAuthJWT пропускает только токены предварительной авторизации
+// для завершения входа вторым фактором.
+func ValidatePreAuthJWT(next http.Handler) http.Handler {
+	return validateScope(ScopePreAuth, next)
+}
+
+func validateScope(scope string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		tokenStr := r.Header.Get("Authorization")
 		if tokenStr == "" {
-			http.Error(w, "Authorization header required", http.StatusUnauthorized)
+			render.Error(w, r, http.StatusUnauthorized, render.CodeUnauthorized, "Authorization header required")
 			return
 		}
 
-		tokenStr = tokenStr[len("Bearer "):]
+		tokenStr = strings.TrimPrefix(tokenStr, "Bearer ")
 		claims := new(Claims)
 		token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
 			return jwtKey, nil
 		})
 
-		if err != nil || !token.Valid {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+		if err != nil || !token.Valid || claims.Scope != scope {
+			render.Error(w, r, http.StatusUnauthorized, render.CodeUnauthorized, "Invalid token")
 			return
 		}
 
@@ -0,0 +1,127 @@
+// Package orders исполняет лимитные заявки на обмен, когда курс gw-exchanger
+// достигает целевого, и закрывает заявки с истекшим сроком.
+package orders
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gw-currency-wallet/api/rates"
+	"gw-currency-wallet/internal/logger"
+	"gw-currency-wallet/internal/storages"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc"
+)
+
+const defaultInterval = 30 * time.Second
+
+// Store — операции репозитория, которые нужны исполнителю заявок.
+type Store interface {
+	OpenOrderPairs(ctx context.Context) ([]storages.Pair, error)
+	FillOrders(ctx context.Context, pair storages.Pair, rate decimal.Decimal) ([]storages.Order, error)
+	ExpireOrders(ctx context.Context) ([]storages.Order, error)
+}
+
+// RateSource возвращает текущие курсы пар. Пары без курса в ответе пропускаются.
+type RateSource interface {
+	PairRates(ctx context.Context, pairs []storages.Pair) (map[storages.Pair]decimal.Decimal, error)
+}
+
+// Worker сверяет открытые заявки с курсами по таймеру и по сигналам об
+// изменении курсов.
+type Worker struct {
+	store    Store
+	rates    RateSource
+	lg       logger.Logger
+	interval time.Duration
+}
+
+func NewWorker(store Store, rates RateSource, lg logger.Logger, interval time.Duration) *Worker {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return &Worker{store: store, rates: rates, lg: lg, interval: interval}
+}
+
+// Run выполняет Match каждые interval и после каждого сигнала updates до отмены ctx.
+// Закрытый или nil updates оставляет только таймер.
+func (w *Worker) Run(ctx context.Context, updates <-chan struct{}) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		if err := w.Match(ctx); err != nil && ctx.Err() == nil {
+			w.lg.WarnCtx(ctx, fmt.Sprintf("orders: match: %v", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case _, ok := <-updates:
+			if !ok {
+				updates = nil
+			}
+		}
+	}
+}
+
+// Match закрывает истекшие заявки и исполняет те, чей целевой курс достигнут.
+// Сначала истечение, чтобы просроченная заявка не исполнилась по новому курсу.
+func (w *Worker) Match(ctx context.Context) error {
+	if _, err := w.store.ExpireOrders(ctx); err != nil {
+		return err
+	}
+	pairs, err := w.store.OpenOrderPairs(ctx)
+	if err != nil || len(pairs) == 0 {
+		return err
+	}
+	current, err := w.rates.PairRates(ctx, pairs)
+	if err != nil {
+		return err
+	}
+	for _, p := range pairs {
+		rate, ok := current[p]
+		if !ok {
+			continue
+		}
+		if _, err := w.store.FillOrders(ctx, p, rate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type pairRater interface {
+	GetExchangeRatesForPairs(ctx context.Context, in *rates.PairsRequest, opts ...grpc.CallOption) (*rates.PairsResponse, error)
+}
+
+// ExchangerRates берет курсы пар одним пакетным запросом к gw-exchanger.
+type ExchangerRates struct {
+	client pairRater
+	lg     logger.Logger
+}
+
+func NewExchangerRates(client pairRater, lg logger.Logger) *ExchangerRates {
+	return &ExchangerRates{client: client, lg: lg}
+}
+
+func (e *ExchangerRates) PairRates(ctx context.Context, pairs []storages.Pair) (map[storages.Pair]decimal.Decimal, error) {
+	req := new(rates.PairsRequest)
+	for _, p := range pairs {
+		req.Pairs = append(req.Pairs, &rates.CurrencyPair{FromCurrency: p.From, ToCurrency: p.To})
+	}
+	res, err := e.client.GetExchangeRatesForPairs(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[storages.Pair]decimal.Decimal, len(res.Rates))
+	for _, r := range res.Rates {
+		if r.Error != "" {
+			e.lg.WarnCtx(ctx, fmt.Sprintf("orders: no rate for %s/%s: %s", r.FromCurrency, r.ToCurrency, r.Error))
+			continue
+		}
+		out[storages.Pair{From: r.FromCurrency, To: r.ToCurrency}] = decimal.NewFromFloat32(r.Rate)
+	}
+	return out, nil
+}
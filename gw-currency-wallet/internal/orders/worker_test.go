@@ -0,0 +1,162 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) DebugCtx(ctx context.Context, msg string)            {}
+func (nopLogger) InfoCtx(ctx context.Context, msg string)             {}
+func (nopLogger) WarnCtx(ctx context.Context, msg string)             {}
+func (nopLogger) ErrorCtx(ctx context.Context, msg string)            {}
+func (nopLogger) FatalCtx(ctx context.Context, msg string, err error) {}
+
+// fakeStore исполняет заявки в памяти так же, как репозиторий: заявка
+// исполняется, если ее целевой курс не выше текущего.
+type fakeStore struct {
+	mu     sync.Mutex
+	orders []storages.Order
+	calls  []string
+}
+
+func (f *fakeStore) OpenOrderPairs(ctx context.Context) ([]storages.Pair, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, "pairs")
+	seen := make(map[storages.Pair]bool)
+	var pairs []storages.Pair
+	for _, o := range f.orders {
+		p := storages.Pair{From: o.From, To: o.To}
+		if o.Status == storages.OrderOpen && !seen[p] {
+			seen[p] = true
+			pairs = append(pairs, p)
+		}
+	}
+	return pairs, nil
+}
+
+func (f *fakeStore) FillOrders(ctx context.Context, pair storages.Pair, rate decimal.Decimal) ([]storages.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, "fill "+pair.From+pair.To)
+	var filled []storages.Order
+	for i, o := range f.orders {
+		if o.Status == storages.OrderOpen && o.From == pair.From && o.To == pair.To && o.TargetRate.LessThanOrEqual(rate) {
+			received := o.Amount.Mul(rate).Round(2)
+			f.orders[i].Status = storages.OrderFilled
+			f.orders[i].FilledRate = &rate
+			f.orders[i].Received = &received
+			filled = append(filled, f.orders[i])
+		}
+	}
+	return filled, nil
+}
+
+func (f *fakeStore) ExpireOrders(ctx context.Context) ([]storages.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, "expire")
+	return nil, nil
+}
+
+func (f *fakeStore) status(id int64) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, o := range f.orders {
+		if o.ID == id {
+			return o.Status
+		}
+	}
+	return ""
+}
+
+type fakeRates struct {
+	mu    sync.Mutex
+	rates map[storages.Pair]decimal.Decimal
+	err   error
+}
+
+func (f *fakeRates) PairRates(ctx context.Context, pairs []storages.Pair) (map[storages.Pair]decimal.Decimal, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	res := make(map[storages.Pair]decimal.Decimal)
+	for _, p := range pairs {
+		if r, ok := f.rates[p]; ok {
+			res[p] = r
+		}
+	}
+	return res, nil
+}
+
+func (f *fakeRates) set(p storages.Pair, rate string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rates[p] = decimal.RequireFromString(rate)
+}
+
+var usdEur = storages.Pair{From: "USD", To: "EUR"}
+
+func newOrder(id int64, from, to, target string) storages.Order {
+	return storages.Order{ID: id, From: from, To: to, Amount: decimal.NewFromInt(100),
+		TargetRate: decimal.RequireFromString(target), Status: storages.OrderOpen}
+}
+
+func TestMatchFillsOrdersAtTargetRate(t *testing.T) {
+	store := &fakeStore{orders: []storages.Order{
+		newOrder(1, "USD", "EUR", "0.95"),
+		newOrder(2, "USD", "EUR", "0.97"),
+		newOrder(3, "RUB", "USD", "0.02"),
+	}}
+	rates := &fakeRates{rates: map[storages.Pair]decimal.Decimal{usdEur: decimal.RequireFromString("0.96")}}
+	w := NewWorker(store, rates, nopLogger{}, time.Minute)
+
+	require.NoError(t, w.Match(context.Background()))
+	assert.Equal(t, storages.OrderFilled, store.status(1))
+	assert.Equal(t, storages.OrderOpen, store.status(2), "target above current rate")
+	assert.Equal(t, storages.OrderOpen, store.status(3), "no rate for the pair")
+	assert.Equal(t, "expire", store.calls[0], "expired orders are closed before matching")
+	assert.True(t, store.orders[0].Received.Equal(decimal.NewFromInt(96)))
+}
+
+func TestMatchRateSourceError(t *testing.T) {
+	store := &fakeStore{orders: []storages.Order{newOrder(1, "USD", "EUR", "0.95")}}
+	w := NewWorker(store, &fakeRates{err: errors.New("unavailable")}, nopLogger{}, time.Minute)
+
+	assert.Error(t, w.Match(context.Background()))
+	assert.Equal(t, storages.OrderOpen, store.status(1))
+}
+
+func TestRunMatchesOnRateUpdate(t *testing.T) {
+	store := &fakeStore{orders: []storages.Order{newOrder(1, "USD", "EUR", "0.95")}}
+	rates := &fakeRates{rates: map[storages.Pair]decimal.Decimal{usdEur: decimal.RequireFromString("0.90")}}
+	w := NewWorker(store, rates, nopLogger{}, time.Hour)
+	updates := make(chan struct{}, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx, updates)
+		close(done)
+	}()
+
+	rates.set(usdEur, "0.95")
+	updates <- struct{}{}
+	assert.Eventually(t, func() bool { return store.status(1) == storages.OrderFilled }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	<-done
+}
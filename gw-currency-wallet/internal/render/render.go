@@ -0,0 +1,102 @@
+// Package render формирует JSON-ответы сервиса, в том числе единый формат ошибок.
+package render
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const (
+	contentTypeJSON    = "application/json"
+	contentTypeProblem = "application/problem+json"
+	problemTypePrefix  = "https://gw-currency-wallet/errors/"
+)
+
+// Машиночитаемые коды ошибок поля code.
+const (
+	CodeInvalidInput       = "invalid_input"
+	CodeValidation         = "validation_failed"
+	CodeUnauthorized       = "unauthorized"
+	CodeInvalidCredentials = "invalid_credentials"
+	CodeEmailNotVerified   = "email_not_verified"
+	CodeInvalidToken       = "invalid_token"
+	CodeTOTPRequired       = "totp_required"
+	CodeInvalidTOTP        = "invalid_totp"
+	CodeTwoFactorEnabled   = "two_factor_enabled"
+	CodeAccountInactive    = "account_inactive"
+	CodeInsufficientFunds  = "insufficient_funds"
+	CodeLimitExceeded      = "limit_exceeded"
+	CodeUserExists         = "user_exists"
+	CodeNotFound           = "not_found"
+	CodeTooManyRequests    = "too_many_requests"
+	CodeUnavailable        = "unavailable"
+	CodeInternal           = "internal_error"
+)
+
+// ErrorResponse — тело любой ошибки API.
+type ErrorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+	Details   any    `json:"details,omitempty"`
+}
+
+// Problem — та же ошибка в формате RFC 7807 для клиентов,
+// запросивших application/problem+json.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+	Details   any    `json:"details,omitempty"`
+}
+
+// JSON пишет v с кодом status и заголовком Content-Type: application/json.
+func JSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Error пишет ошибку ровно один раз: в формате ErrorResponse или, если клиент
+// принимает application/problem+json, в формате RFC 7807.
+func Error(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	ErrorDetails(w, r, status, code, message, nil)
+}
+
+// ErrorDetails — Error с дополнительными данными в поле details.
+func ErrorDetails(w http.ResponseWriter, r *http.Request, status int, code, message string, details any) {
+	requestID, _ := r.Context().Value("requestID").(string)
+	if wantsProblem(r) {
+		w.Header().Set("Content-Type", contentTypeProblem)
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(Problem{
+			Type:      problemTypePrefix + code,
+			Title:     http.StatusText(status),
+			Status:    status,
+			Detail:    message,
+			Instance:  r.URL.Path,
+			Code:      code,
+			RequestID: requestID,
+			Details:   details,
+		})
+		return
+	}
+	JSON(w, status, ErrorResponse{Code: code, Message: message, RequestID: requestID, Details: details})
+}
+
+func wantsProblem(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+			if strings.EqualFold(strings.TrimSpace(mediaType), contentTypeProblem) {
+				return true
+			}
+		}
+	}
+	return false
+}
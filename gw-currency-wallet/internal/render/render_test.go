@@ -0,0 +1,35 @@
+package render
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/withdraw", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "requestID", "req-1"))
+	w := httptest.NewRecorder()
+
+	ErrorDetails(w, req, http.StatusUnprocessableEntity, CodeLimitExceeded, "Limit exceeded", map[string]string{"limit": "daily_withdrawal"})
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"code":"limit_exceeded","message":"Limit exceeded","request_id":"req-1","details":{"limit":"daily_withdrawal"}}`, w.Body.String())
+}
+
+func TestErrorProblemJSON(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/withdraw", nil)
+	req.Header.Set("Accept", "application/json;q=0.9, application/problem+json")
+	w := httptest.NewRecorder()
+
+	Error(w, req, http.StatusForbidden, CodeAccountInactive, "Account is frozen or closed")
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"type":"https://gw-currency-wallet/errors/account_inactive","title":"Forbidden","status":403,
+		"detail":"Account is frozen or closed","instance":"/withdraw","code":"account_inactive"}`, w.Body.String())
+}
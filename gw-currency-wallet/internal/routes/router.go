@@ -11,6 +11,7 @@ import (
 	_ "gw-currency-wallet/docs"
 	"gw-currency-wallet/internal/config"
 	"gw-currency-wallet/internal/handlers"
+	"gw-currency-wallet/internal/limiter"
 	"gw-currency-wallet/internal/logger"
 	"gw-currency-wallet/internal/middleware"
 	"net/http"
@@ -27,7 +28,7 @@ func Start(ctx context.Context, cfg *config.ConfigAdr, lg logger.Logger) {
 
 	defer func() {
 		if err := recover(); err != nil {
-			lg.ErrorCtx(ctx, fmt.Sprintf("Паника в функции Start: ", err))
+			lg.ErrorCtx(ctx, fmt.Sprintf("Паника в функции Start: %v", err))
 		}
 	}()
 
@@ -38,19 +39,64 @@ func Start(ctx context.Context, cfg *config.ConfigAdr, lg logger.Logger) {
 
 	r.Use(middleware.ContextRequestMiddleware)
 
+	r.Get("/healthz", h.Healthz)
+	r.Get("/readyz", h.Readyz)
+
 	r.Get("/swagger/*", httpSwagger.Handler(
 		httpSwagger.URL(cfg.Swagger_url),
 	))
 
-	r.Post("/register", h.RegisterUser)
-	r.Post("/login", h.Autherisation)
+	r.With(h.AuthRateLimit()).Post("/register", h.RegisterUser)
+	r.With(h.AuthRateLimit()).Post("/login", h.Autherisation)
+	r.With(middleware.ValidatePreAuthJWT).Post("/login/2fa", h.LoginTOTP)
+	r.Get("/verify-email", h.VerifyEmail)
+	r.With(h.AuthRateLimit()).Post("/verify-email/resend", h.ResendVerification)
+	r.With(h.AuthRateLimit()).Post("/password/forgot", h.ForgotPassword)
+	r.With(h.AuthRateLimit()).Post("/password/reset", h.ResetPassword)
 	r.Group(func(r chi.Router) {
 		r.Use(middleware.ValidateJWT)
-		r.Get("/balance", h.GetBalance)
-		r.Post("/deposit", h.Deposit)
-		r.Post("/withdraw", h.Withdraw)
-		r.Get("/rates", h.ExchangeRates)
-		r.Post("/exchange", h.ExchangeRatesForCurrency)
+		r.With(userLimit(cfg, "/balance")).Get("/balance", h.GetBalance)
+		r.With(userLimit(cfg, "/deposit")).Post("/deposit", h.Deposit)
+		r.With(userLimit(cfg, "/withdraw")).Post("/withdraw", h.Withdraw)
+		r.With(userLimit(cfg, "/rates")).Get("/rates", h.ExchangeRates)
+		r.With(userLimit(cfg, "/exchange")).Post("/exchange", h.ExchangeRatesForCurrency)
+		r.Route("/orders", func(r chi.Router) {
+			r.Use(userLimit(cfg, "/orders"))
+			r.Post("/", h.PlaceOrder)
+			r.Get("/", h.ListOrders)
+			r.Delete("/{id}", h.CancelOrder)
+		})
+		r.Route("/holds", func(r chi.Router) {
+			r.Use(userLimit(cfg, "/holds"))
+			r.Post("/", h.CreateHold)
+			r.Get("/", h.ListHolds)
+			r.Post("/{id}/capture", h.CaptureHold)
+			r.Post("/{id}/release", h.ReleaseHold)
+		})
+		r.Route("/schedules", func(r chi.Router) {
+			r.Use(userLimit(cfg, "/schedules"))
+			r.Post("/", h.CreateSchedule)
+			r.Get("/", h.ListSchedules)
+			r.Get("/{id}/runs", h.ListScheduleRuns)
+			r.Post("/{id}/pause", h.PauseSchedule)
+			r.Post("/{id}/resume", h.ResumeSchedule)
+		})
+		r.Route("/webhooks", func(r chi.Router) {
+			r.Use(userLimit(cfg, "/webhooks"))
+			webhookRoutes(r, h)
+		})
+		r.Route("/2fa", func(r chi.Router) {
+			r.Use(h.AuthRateLimit(), userLimit(cfg, "/2fa"))
+			r.Post("/enroll", h.EnrollTOTP)
+			r.Post("/confirm", h.ConfirmTOTP)
+		})
+	})
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.ValidateAdminToken(cfg.Admin_token))
+		r.Put("/admin/accounts/{user_id}/status", h.SetAccountStatus)
+		r.Route("/admin/webhooks", func(r chi.Router) {
+			webhookRoutes(r, h)
+		})
 	})
 
 	srv := &http.Server{
@@ -74,3 +120,23 @@ func Start(ctx context.Context, cfg *config.ConfigAdr, lg logger.Logger) {
 		lg.InfoCtx(ctx, "Сервер коректно завершен")
 	}
 }
+
+// userLimit возвращает ограничитель запросов пользователя для маршрута path
+// или пустой middleware, если лимит для маршрута не задан.
+func userLimit(cfg *config.ConfigAdr, path string) func(http.Handler) http.Handler {
+	l, ok := cfg.User_rate_limits[path]
+	if !ok || l.Requests <= 0 || l.Per_sec <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return middleware.UserRateLimit(limiter.NewTokenBucket(l.Requests, time.Duration(l.Per_sec)*time.Second))
+}
+
+// webhookRoutes — маршруты подписок на вебхуки. Под /webhooks ими управляет
+// пользователь, под /admin/webhooks — администратор для партнеров.
+func webhookRoutes(r chi.Router, h *handlers.ServerWallet) {
+	r.Post("/", h.CreateWebhook)
+	r.Get("/", h.ListWebhooks)
+	r.Delete("/{id}", h.DeleteWebhook)
+	r.Get("/{id}/deliveries", h.ListWebhookDeliveries)
+	r.Post("/deliveries/{id}/replay", h.ReplayWebhookDelivery)
+}
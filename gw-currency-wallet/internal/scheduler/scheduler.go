@@ -0,0 +1,203 @@
+// Package scheduler выполняет регулярные операции пользователей по расписанию
+// cron. Запуски выполняет только реплика, взявшая блокировку лидера.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gw-currency-wallet/internal/config"
+	"gw-currency-wallet/internal/logger"
+	"gw-currency-wallet/internal/mailer"
+	"gw-currency-wallet/internal/storages"
+
+	exchange "github.com/IlyaBroo/exchange_grpc/exchange"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc"
+)
+
+const (
+	defaultPollInterval = 30 * time.Second
+	defaultBatchSize    = 100
+)
+
+// Store — операции репозитория, которые нужны планировщику. Обмен идет через
+// ExchangeForCurrency, как и по запросу пользователя, с теми же проверками
+// статуса, лимитов и доступного остатка; DepositAndExchange перед тем же
+// обменом пополняет кошелек в той же транзакции.
+type Store interface {
+	DueSchedules(ctx context.Context, limit int) ([]storages.Schedule, error)
+	ClaimSchedule(ctx context.Context, id int64, prevRun, nextRun time.Time) (bool, error)
+	RecordScheduleRun(ctx context.Context, run storages.ScheduleRun, maxFailures int) (bool, error)
+	ExchangeForCurrency(ctx context.Context, from, to string, amount decimal.Decimal, kurs float32, user_id int) (map[string]decimal.Decimal, error)
+	DepositAndExchange(ctx context.Context, from, to string, amount decimal.Decimal, kurs float32, user_id int) (map[string]decimal.Decimal, error)
+	Transfer(ctx context.Context, user_id, recipient_id int, currency string, amount decimal.Decimal) (storages.Balance, error)
+}
+
+// Locker — выбор лидера среди реплик.
+type Locker interface {
+	TryLead(ctx context.Context) (bool, error)
+	Release()
+}
+
+type rater interface {
+	GetExchangeRateForCurrency(ctx context.Context, in *exchange.CurrencyRequest, opts ...grpc.CallOption) (*exchange.ExchangeRateResponse, error)
+}
+
+type Scheduler struct {
+	store       Store
+	leader      Locker
+	rates       rater
+	mailer      mailer.Mailer
+	lg          logger.Logger
+	interval    time.Duration
+	batch       int
+	maxFailures int
+	now         func() time.Time
+}
+
+func New(store Store, leader Locker, rates rater, m mailer.Mailer, lg logger.Logger, cfg config.Scheduler) *Scheduler {
+	s := &Scheduler{
+		store:       store,
+		leader:      leader,
+		rates:       rates,
+		mailer:      m,
+		lg:          lg,
+		interval:    time.Duration(cfg.Poll_interval_sec) * time.Second,
+		batch:       cfg.Batch_size,
+		maxFailures: cfg.Max_failures,
+		now:         time.Now,
+	}
+	if s.interval <= 0 {
+		s.interval = defaultPollInterval
+	}
+	if s.batch <= 0 {
+		s.batch = defaultBatchSize
+	}
+	return s
+}
+
+// Run раз в interval пытается стать лидером и, если удалось, выполняет
+// наступившие запуски. При отмене ctx отпускает лидерство.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	defer s.leader.Release()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		lead, err := s.leader.TryLead(ctx)
+		if err != nil {
+			s.lg.WarnCtx(ctx, fmt.Sprintf("scheduler: leader election: %v", err))
+			continue
+		}
+		if !lead {
+			continue
+		}
+		if err := s.RunDue(ctx); err != nil && ctx.Err() == nil {
+			s.lg.WarnCtx(ctx, fmt.Sprintf("scheduler: %v", err))
+		}
+	}
+}
+
+// RunDue выполняет наступившие запуски. Пропущенные за время простоя запуски
+// не догоняются: операция выполняется один раз, а следующий запуск
+// назначается по расписанию от текущего времени.
+func (s *Scheduler) RunDue(ctx context.Context) error {
+	due, err := s.store.DueSchedules(ctx, s.batch)
+	if err != nil {
+		return err
+	}
+	for _, sch := range due {
+		cron, err := ParseCron(sch.Cron)
+		if err != nil {
+			s.lg.ErrorCtx(ctx, fmt.Sprintf("scheduler: schedule %d: %v", sch.ID, err))
+			continue
+		}
+		next := cron.Next(s.now().UTC())
+		claimed, err := s.store.ClaimSchedule(ctx, sch.ID, sch.NextRunAt, next)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+		run := s.execute(ctx, sch)
+		paused, err := s.store.RecordScheduleRun(ctx, run, s.maxFailures)
+		if err != nil {
+			s.lg.ErrorCtx(ctx, fmt.Sprintf("scheduler: schedule %d: record run: %v", sch.ID, err))
+		}
+		if run.Status == storages.RunFailed {
+			s.notify(ctx, sch, run, paused)
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) execute(ctx context.Context, sch storages.Schedule) storages.ScheduleRun {
+	run := storages.ScheduleRun{ScheduleID: sch.ID, StartedAt: s.now(), Status: storages.RunFailed}
+	if sch.Kind == storages.ScheduleTransfer {
+		if sch.RecipientID == nil {
+			run.Error = storages.ErrRecipient.Error()
+			return run
+		}
+		if _, err := s.store.Transfer(ctx, sch.UserID, *sch.RecipientID, sch.From, sch.Amount); err != nil {
+			run.Error = err.Error()
+			return run
+		}
+		run.Status = storages.RunSucceeded
+		s.lg.InfoCtx(ctx, fmt.Sprintf("scheduler: schedule %d transferred %s %s to user %d", sch.ID, sch.Amount, sch.From, *sch.RecipientID))
+		return run
+	}
+	resp, err := s.rates.GetExchangeRateForCurrency(ctx, &exchange.CurrencyRequest{FromCurrency: sch.From, ToCurrency: sch.To})
+	if err != nil {
+		run.Error = fmt.Sprintf("exchange rate unavailable: %v", err)
+		return run
+	}
+	rate := decimal.NewFromFloat32(resp.Rate)
+	run.Rate = &rate
+	exchangeFn := s.store.ExchangeForCurrency
+	if sch.Kind == storages.ScheduleDepositExchange {
+		exchangeFn = s.store.DepositAndExchange
+	}
+	if _, err := exchangeFn(ctx, sch.From, sch.To, sch.Amount, resp.Rate, sch.UserID); err != nil {
+		run.Error = err.Error()
+		return run
+	}
+	run.Status = storages.RunSucceeded
+	s.lg.InfoCtx(ctx, fmt.Sprintf("scheduler: schedule %d exchanged %s %s to %s", sch.ID, sch.Amount, sch.From, sch.To))
+	return run
+}
+
+// describe называет операцию расписания для письма владельцу.
+func describe(sch storages.Schedule) string {
+	switch sch.Kind {
+	case storages.ScheduleTransfer:
+		return fmt.Sprintf("transfer of %s %s to %s", sch.Amount, sch.From, sch.Recipient)
+	case storages.ScheduleDepositExchange:
+		return fmt.Sprintf("deposit of %s %s with exchange to %s", sch.Amount, sch.From, sch.To)
+	default:
+		return fmt.Sprintf("exchange of %s %s to %s", sch.Amount, sch.From, sch.To)
+	}
+}
+
+func (s *Scheduler) notify(ctx context.Context, sch storages.Schedule, run storages.ScheduleRun, paused bool) {
+	s.lg.WarnCtx(ctx, fmt.Sprintf("scheduler: schedule %d failed: %s", sch.ID, run.Error))
+	if sch.Email == "" {
+		return
+	}
+	body := fmt.Sprintf("Scheduled %s (schedule %d) failed at %s: %s.\n",
+		describe(sch), sch.ID, run.StartedAt.UTC().Format(time.RFC1123), run.Error)
+	if paused {
+		body += fmt.Sprintf("The schedule was paused after %d failed runs in a row. Resume it with POST /schedules/%d/resume.\n",
+			s.maxFailures, sch.ID)
+	}
+	err := s.mailer.Send(ctx, mailer.Message{To: sch.Email, Subject: "Scheduled operation failed", Body: body})
+	if err != nil {
+		s.lg.ErrorCtx(ctx, fmt.Sprintf("scheduler: schedule %d: notify: %v", sch.ID, err))
+	}
+}
@@ -0,0 +1,172 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gw-currency-wallet/internal/config"
+	"gw-currency-wallet/internal/mailer"
+	"gw-currency-wallet/internal/storages"
+
+	exchange "github.com/IlyaBroo/exchange_grpc/exchange"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) DebugCtx(ctx context.Context, msg string)            {}
+func (nopLogger) InfoCtx(ctx context.Context, msg string)             {}
+func (nopLogger) WarnCtx(ctx context.Context, msg string)             {}
+func (nopLogger) ErrorCtx(ctx context.Context, msg string)            {}
+func (nopLogger) FatalCtx(ctx context.Context, msg string, err error) {}
+
+type fakeStore struct {
+	due       []storages.Schedule
+	claimed   map[int64]time.Time
+	taken     map[int64]bool
+	runs      []storages.ScheduleRun
+	exchanges int
+	deposits  int
+	transfers []int
+	exchErr   error
+	pause     bool
+}
+
+func (f *fakeStore) DueSchedules(ctx context.Context, limit int) ([]storages.Schedule, error) {
+	return f.due, nil
+}
+
+func (f *fakeStore) ClaimSchedule(ctx context.Context, id int64, prevRun, nextRun time.Time) (bool, error) {
+	if f.taken[id] {
+		return false, nil
+	}
+	f.claimed[id] = nextRun
+	return true, nil
+}
+
+func (f *fakeStore) RecordScheduleRun(ctx context.Context, run storages.ScheduleRun, maxFailures int) (bool, error) {
+	f.runs = append(f.runs, run)
+	return f.pause, nil
+}
+
+func (f *fakeStore) ExchangeForCurrency(ctx context.Context, from, to string, amount decimal.Decimal, kurs float32, user_id int) (map[string]decimal.Decimal, error) {
+	f.exchanges++
+	return nil, f.exchErr
+}
+
+func (f *fakeStore) DepositAndExchange(ctx context.Context, from, to string, amount decimal.Decimal, kurs float32, user_id int) (map[string]decimal.Decimal, error) {
+	f.deposits++
+	return nil, f.exchErr
+}
+
+func (f *fakeStore) Transfer(ctx context.Context, user_id, recipient_id int, currency string, amount decimal.Decimal) (storages.Balance, error) {
+	f.transfers = append(f.transfers, recipient_id)
+	return storages.Balance{}, f.exchErr
+}
+
+type fakeRater struct{ rate float32 }
+
+func (f fakeRater) GetExchangeRateForCurrency(ctx context.Context, in *exchange.CurrencyRequest, opts ...grpc.CallOption) (*exchange.ExchangeRateResponse, error) {
+	return &exchange.ExchangeRateResponse{FromCurrency: in.FromCurrency, ToCurrency: in.ToCurrency, Rate: f.rate}, nil
+}
+
+type fakeLeader struct{ lead bool }
+
+func (f *fakeLeader) TryLead(ctx context.Context) (bool, error) { return f.lead, nil }
+func (f *fakeLeader) Release()                                  {}
+
+// 2026-10-19 — понедельник.
+var monday = time.Date(2026, 10, 19, 9, 0, 30, 0, time.UTC)
+
+func newTestScheduler(store *fakeStore, outbox *mailer.Outbox) *Scheduler {
+	s := New(store, &fakeLeader{lead: true}, fakeRater{rate: 0.0105}, outbox, nopLogger{}, config.Scheduler{Max_failures: 3})
+	s.now = func() time.Time { return monday }
+	return s
+}
+
+func weekly(id int64) storages.Schedule {
+	return storages.Schedule{ID: id, UserID: 1, Email: "user@example.com", Kind: storages.ScheduleExchange,
+		From: "RUB", To: "EUR", Amount: decimal.NewFromInt(100), Cron: "0 9 * * 1", NextRunAt: monday.Truncate(time.Minute)}
+}
+
+func TestRunDueExchangesAndReschedules(t *testing.T) {
+	store := &fakeStore{due: []storages.Schedule{weekly(1)}, claimed: map[int64]time.Time{}}
+	outbox := mailer.NewOutbox()
+
+	require.NoError(t, newTestScheduler(store, outbox).RunDue(context.Background()))
+	assert.Equal(t, 1, store.exchanges)
+	assert.Equal(t, time.Date(2026, 10, 26, 9, 0, 0, 0, time.UTC), store.claimed[1])
+	require.Len(t, store.runs, 1)
+	assert.Equal(t, storages.RunSucceeded, store.runs[0].Status)
+	assert.True(t, store.runs[0].Rate.Equal(decimal.RequireFromString("0.0105")))
+	assert.Empty(t, outbox.Messages())
+}
+
+func TestRunDueDepositsAndTransfers(t *testing.T) {
+	deposit := weekly(1)
+	deposit.Kind = storages.ScheduleDepositExchange
+	recipient := 2
+	transfer := weekly(2)
+	transfer.Kind, transfer.To, transfer.RecipientID, transfer.Recipient = storages.ScheduleTransfer, "RUB", &recipient, "bob"
+	store := &fakeStore{due: []storages.Schedule{deposit, transfer}, claimed: map[int64]time.Time{}}
+
+	require.NoError(t, newTestScheduler(store, mailer.NewOutbox()).RunDue(context.Background()))
+	assert.Zero(t, store.exchanges)
+	assert.Equal(t, 1, store.deposits)
+	assert.Equal(t, []int{2}, store.transfers)
+	require.Len(t, store.runs, 2)
+	assert.Equal(t, storages.RunSucceeded, store.runs[0].Status)
+	assert.NotNil(t, store.runs[0].Rate)
+	assert.Equal(t, storages.RunSucceeded, store.runs[1].Status)
+	assert.Nil(t, store.runs[1].Rate, "transfers need no exchange rate")
+}
+
+func TestRunDueSkipsClaimedSchedules(t *testing.T) {
+	store := &fakeStore{due: []storages.Schedule{weekly(1)}, claimed: map[int64]time.Time{}, taken: map[int64]bool{1: true}}
+
+	require.NoError(t, newTestScheduler(store, mailer.NewOutbox()).RunDue(context.Background()))
+	assert.Zero(t, store.exchanges)
+	assert.Empty(t, store.runs)
+}
+
+func TestRunDueNotifiesFailure(t *testing.T) {
+	store := &fakeStore{due: []storages.Schedule{weekly(1)}, claimed: map[int64]time.Time{}, exchErr: storages.ErrExch, pause: true}
+	outbox := mailer.NewOutbox()
+
+	require.NoError(t, newTestScheduler(store, outbox).RunDue(context.Background()))
+	require.Len(t, store.runs, 1)
+	assert.Equal(t, storages.RunFailed, store.runs[0].Status)
+	assert.Equal(t, storages.ErrExch.Error(), store.runs[0].Error)
+
+	msgs := outbox.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "user@example.com", msgs[0].To)
+	assert.Contains(t, msgs[0].Body, "exchange of 100 RUB to EUR")
+	assert.Contains(t, msgs[0].Body, "paused after 3 failed runs")
+}
+
+type errLeader struct{ calls int }
+
+func (e *errLeader) TryLead(ctx context.Context) (bool, error) {
+	e.calls++
+	return false, errors.New("connection refused")
+}
+func (e *errLeader) Release() {}
+
+func TestRunWithoutLeadershipDoesNothing(t *testing.T) {
+	store := &fakeStore{due: []storages.Schedule{weekly(1)}, claimed: map[int64]time.Time{}}
+	leader := &errLeader{}
+	s := New(store, leader, fakeRater{}, mailer.NewOutbox(), nopLogger{}, config.Scheduler{})
+	s.interval = time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	s.Run(ctx)
+	assert.Positive(t, leader.calls)
+	assert.Zero(t, store.exchanges)
+}
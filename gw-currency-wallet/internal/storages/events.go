@@ -0,0 +1,159 @@
+package storages
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+)
+
+// Типы событий кошелька. Данные событий order.* — заявка в том виде, в
+// каком ее возвращает API, данные wallet.hold_captured — закрытый холд.
+const (
+	EventDeposited = "wallet.deposited"
+	EventWithdrawn = "wallet.withdrawn"
+	EventExchanged = "wallet.exchanged"
+
+	EventTransferSent     = "wallet.transfer_sent"
+	EventTransferReceived = "wallet.transfer_received"
+
+	EventHoldCaptured = "wallet.hold_captured"
+
+	EventOrderPlaced    = "order.placed"
+	EventOrderFilled    = "order.filled"
+	EventOrderCancelled = "order.cancelled"
+	EventOrderExpired   = "order.expired"
+
+	EventUserRegistered = "user.registered"
+)
+
+// EventTypes — все типы событий, на которые можно подписаться.
+var EventTypes = []string{
+	EventDeposited, EventWithdrawn, EventExchanged, EventTransferSent, EventTransferReceived, EventHoldCaptured,
+	EventOrderPlaced, EventOrderFilled, EventOrderCancelled, EventOrderExpired,
+}
+
+// internalEventTypes публикуются только для внутренних систем и не уходят в
+// вебхуки: в user.registered лежат персональные данные пользователя.
+var internalEventTypes = []string{EventUserRegistered}
+
+// Event — событие из outbox. ID растет в порядке записи событий, по нему
+// получатели отбрасывают повторы.
+type Event struct {
+	ID        int64           `json:"id"`
+	UserID    *int            `json:"user_id,omitempty"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// UserEvent — данные события user.registered.
+type UserEvent struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+// BalanceEvent — данные событий wallet.deposited, wallet.withdrawn и
+// wallet.transfer_*. Balance — остаток в валюте операции после нее.
+type BalanceEvent struct {
+	Currency string          `json:"currency"`
+	Amount   decimal.Decimal `json:"amount"`
+	Balance  decimal.Decimal `json:"balance"`
+}
+
+// ExchangeEvent — данные события wallet.exchanged.
+type ExchangeEvent struct {
+	From     string          `json:"from_currency"`
+	To       string          `json:"to_currency"`
+	Amount   decimal.Decimal `json:"amount"`
+	Rate     decimal.Decimal `json:"rate"`
+	Received decimal.Decimal `json:"received"`
+}
+
+// recordEvent пишет событие в outbox в транзакции tx. Событие появляется
+// только вместе с изменением баланса, а доставляется уже после коммита.
+func (r *Repository) recordEvent(ctx context.Context, tx querier, user_id int, kind string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO outbox_events (user_id, type, payload) VALUES ($1, $2, $3)", user_id, kind, payload)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func recordEvent sql query failed: %v", err))
+	}
+	return err
+}
+
+// ClaimEvents закрепляет за вызывающим до limit неопубликованных событий
+// на время lease и возвращает их по порядку id. Пока закрепление действует,
+// другие реплики событий не получают, поэтому порядок публикации
+// сохраняется. Транзакционная блокировка lockKey держится только на время
+// выбора событий, публикация идет уже вне транзакции.
+func (r *Repository) ClaimEvents(ctx context.Context, lockKey int64, limit int, lease time.Duration) ([]Event, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	var events []Event
+	err := r.WithTx(ctx, func(tx pgx.Tx) error {
+		events = nil
+		var locked bool
+		if err := tx.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock($1)", lockKey).Scan(&locked); err != nil {
+			r.lg.ErrorCtx(ctx, fmt.Sprintf("func claimEvents lock failed: %v", err))
+			return err
+		}
+		if !locked {
+			return nil
+		}
+		var busy bool
+		err := tx.QueryRow(ctx,
+			"SELECT EXISTS (SELECT 1 FROM outbox_events WHERE published_at IS NULL AND claimed_until > now())").Scan(&busy)
+		if err != nil {
+			r.lg.ErrorCtx(ctx, fmt.Sprintf("func claimEvents sql query failed: %v", err))
+			return err
+		}
+		if busy {
+			return nil
+		}
+		rows, err := tx.Query(ctx,
+			`UPDATE outbox_events SET claimed_until = now() + $2 * interval '1 millisecond'
+			WHERE id IN (SELECT id FROM outbox_events WHERE published_at IS NULL ORDER BY id LIMIT $1)
+			RETURNING id, user_id, type, created_at, payload`,
+			limit, lease.Milliseconds())
+		if err != nil {
+			r.lg.ErrorCtx(ctx, fmt.Sprintf("func claimEvents sql query failed: %v", err))
+			return err
+		}
+		events, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (Event, error) {
+			var ev Event
+			err := row.Scan(&ev.ID, &ev.UserID, &ev.Type, &ev.CreatedAt, &ev.Data)
+			return ev, err
+		})
+		if err != nil {
+			r.lg.ErrorCtx(ctx, fmt.Sprintf("func claimEvents scan errors: %v", err))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(events, func(a, b Event) int { return cmp.Compare(a.ID, b.ID) })
+	return events, nil
+}
+
+// FinishEvents снимает закрепление с событий claimed и отмечает
+// опубликованными published. Остальные события claimed снова ждут публикации.
+func (r *Repository) FinishEvents(ctx context.Context, claimed, published []int64) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	_, err := r.db.Exec(ctx,
+		"UPDATE outbox_events SET published_at = CASE WHEN id = ANY($2) THEN now() END, claimed_until = NULL WHERE id = ANY($1)",
+		claimed, published)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func finishEvents sql query failed: %v", err))
+	}
+	return err
+}
@@ -0,0 +1,98 @@
+package storages
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddUserRecordsRegistration(t *testing.T) {
+	rep, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO users \(username, email, pass\) VALUES \(\$1, \$2, \$3\) RETURNING id`).
+		WithArgs("alice", "alice@example.com", "hash").
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectExec(`INSERT INTO outbox_events`).
+		WithArgs(7, EventUserRegistered, []byte(`{"username":"alice","email":"alice@example.com"}`)).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+
+	err := rep.AddUser(RegisterRequest{Username: "alice", Email: "alice@example.com", Password: "hash"}, context.Background())
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func outboxRows(mock pgxmock.PgxPoolIface, ids ...int64) *pgxmock.Rows {
+	rows := mock.NewRows([]string{"id", "user_id", "type", "created_at", "payload"})
+	user_id := 1
+	for _, id := range ids {
+		rows.AddRow(id, &user_id, EventDeposited, time.Now(), []byte(`{"currency":"USD"}`))
+	}
+	return rows
+}
+
+func TestClaimEventsTakesBatchInOrder(t *testing.T) {
+	rep, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock\(\$1\)`).
+		WithArgs(int64(42)).
+		WillReturnRows(mock.NewRows([]string{"locked"}).AddRow(true))
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM outbox_events WHERE published_at IS NULL AND claimed_until > now\(\)\)`).
+		WillReturnRows(mock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery(`UPDATE outbox_events SET claimed_until = now\(\) \+ \$2 \* interval '1 millisecond'\s+WHERE id IN \(SELECT id FROM outbox_events WHERE published_at IS NULL ORDER BY id LIMIT \$1\)`).
+		WithArgs(10, int64(30000)).
+		WillReturnRows(outboxRows(mock, 3, 1, 2))
+	mock.ExpectCommit()
+
+	events, err := rep.ClaimEvents(context.Background(), 42, 10, 30*time.Second)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, []int64{1, 2, 3}, []int64{events[0].ID, events[1].ID, events[2].ID})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClaimEventsWaitsForOtherReplica(t *testing.T) {
+	tests := []struct {
+		name   string
+		locked bool
+		busy   bool
+	}{
+		{name: "lock taken", locked: false},
+		{name: "claim in progress", locked: true, busy: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rep, mock := newMockRepository(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock`).
+				WithArgs(int64(42)).
+				WillReturnRows(mock.NewRows([]string{"locked"}).AddRow(tt.locked))
+			if tt.locked {
+				mock.ExpectQuery(`SELECT EXISTS`).
+					WillReturnRows(mock.NewRows([]string{"exists"}).AddRow(tt.busy))
+			}
+			mock.ExpectCommit()
+
+			events, err := rep.ClaimEvents(context.Background(), 42, 10, time.Minute)
+			require.NoError(t, err)
+			assert.Empty(t, events)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestFinishEventsReleasesUnpublished(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	mock.ExpectExec(`UPDATE outbox_events SET published_at = CASE WHEN id = ANY\(\$2\) THEN now\(\) END, claimed_until = NULL WHERE id = ANY\(\$1\)`).
+		WithArgs([]int64{1, 2, 3}, []int64{1, 2}).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 3))
+
+	require.NoError(t, rep.FinishEvents(context.Background(), []int64{1, 2, 3}, []int64{1, 2}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,187 @@
+package storages
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+)
+
+// holdColumns возвращает холд со статусом expired, как только прошел его срок,
+// не дожидаясь, пока ExpireHolds обновит строку.
+const holdColumns = "id, user_id, currency, amount, captured, reference, " +
+	"CASE WHEN status = 'active' AND expires_at <= now() THEN 'expired' ELSE status END AS status, created_at, expires_at, closed_at"
+
+// heldSQL — сумма действующих холдов кошелька w в валюте, переданной
+// параметром запроса param (например, "$5"). Сама валюта в текст запроса
+// не попадает.
+func heldSQL(param string) string {
+	return fmt.Sprintf("(SELECT COALESCE(SUM(h.amount), 0) FROM holds h WHERE h.user_id = w.user_id AND h.currency = %s AND h.status = 'active' AND h.expires_at > now())", param)
+}
+
+// CreateHold резервирует h.Amount, если доступный остаток это позволяет.
+// Как и вывод, холд проверяется по лимитам вывода. Пока холд действует, он
+// занимает дневной лимит вывода; в операции попадает только списанная сумма.
+func (r *Repository) CreateHold(ctx context.Context, h Hold) (Hold, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	if !ValidCurrency(h.Currency) {
+		return Hold{}, ErrCurrency
+	}
+	query := fmt.Sprintf(
+		"INSERT INTO holds (user_id, currency, amount, reference, expires_at) SELECT w.user_id, $2, $3::decimal, $4, $5 FROM wallets w JOIN users u ON u.id = w.user_id WHERE w.user_id = $1 AND w.%s - %s >= $3::decimal AND w.status = $6 AND u.status = $6 RETURNING "+holdColumns,
+		h.Currency, heldSQL("$2"),
+	)
+	var res Hold
+	err := r.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := r.checkLimits(ctx, tx, h.UserID, opWithdraw, h.Currency, h.Amount); err != nil {
+			r.lg.InfoCtx(ctx, fmt.Sprintf("func createHold rejected: %v", err))
+			return err
+		}
+		var err error
+		res, err = scanHold(tx.QueryRow(ctx, query, h.UserID, h.Currency, h.Amount, h.Reference, h.ExpiresAt, StatusActive))
+		if err == pgx.ErrNoRows {
+			if err := r.checkStatus(ctx, tx, h.UserID, []string{StatusActive}); err == ErrInactive {
+				r.lg.InfoCtx(ctx, "func createHold account is frozen or closed")
+				return err
+			}
+			r.lg.InfoCtx(ctx, "func createHold insufficient funds or wallet with this username not found")
+			return ErrWithdraw
+		}
+		if err != nil {
+			r.lg.ErrorCtx(ctx, fmt.Sprintf("func createHold sql query failed: %v", err))
+		}
+		return err
+	})
+	if err != nil {
+		return Hold{}, err
+	}
+	r.lg.InfoCtx(ctx, fmt.Sprintf("func createHold hold %d created", res.ID))
+	return res, nil
+}
+
+// ListHolds возвращает холды пользователя, новые первыми. Пустой status — все холды.
+func (r *Repository) ListHolds(ctx context.Context, user_id int, status string) ([]Hold, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	rows, err := r.db.Query(ctx,
+		"SELECT * FROM (SELECT "+holdColumns+" FROM holds WHERE user_id = $1) h WHERE $2 = '' OR h.status = $2 ORDER BY created_at DESC, id DESC",
+		user_id, status)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func listHolds sql query failed: %v", err))
+		return nil, err
+	}
+	holds, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Hold, error) {
+		return scanHold(row)
+	})
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func listHolds scan errors: %v", err))
+		return nil, err
+	}
+	return holds, nil
+}
+
+// CaptureHold списывает amount из действующего холда и закрывает его; остаток
+// холда освобождается. Нулевой amount списывает весь холд. В дневной лимит
+// вывода засчитывается списанная сумма.
+func (r *Repository) CaptureHold(ctx context.Context, user_id int, id int64, amount decimal.Decimal) (Hold, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	var res Hold
+	err := r.WithTx(ctx, func(tx pgx.Tx) error {
+		var currency string
+		var held decimal.Decimal
+		err := tx.QueryRow(ctx,
+			"SELECT currency, amount FROM holds WHERE id = $1 AND user_id = $2 AND status = $3 AND expires_at > now() FOR UPDATE",
+			id, user_id, HoldActive).Scan(&currency, &held)
+		if err == pgx.ErrNoRows {
+			r.lg.InfoCtx(ctx, fmt.Sprintf("func captureHold hold %d not found or closed", id))
+			return ErrNoHold
+		}
+		if err != nil {
+			r.lg.ErrorCtx(ctx, fmt.Sprintf("func captureHold sql query failed: %v", err))
+			return err
+		}
+		if amount.IsZero() {
+			amount = held
+		}
+		if amount.GreaterThan(held) {
+			return ErrHoldAmount
+		}
+		if !ValidCurrency(currency) {
+			return ErrCurrency
+		}
+		result, err := tx.Exec(ctx, fmt.Sprintf(
+			"UPDATE wallets w SET %s = w.%s - $1::decimal FROM users u WHERE u.id = w.user_id AND w.user_id = $2 AND w.status = $3 AND u.status = $3",
+			currency, currency), amount, user_id, StatusActive)
+		if err != nil {
+			r.lg.ErrorCtx(ctx, fmt.Sprintf("func captureHold debit failed: %v", err))
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			if err := r.checkStatus(ctx, tx, user_id, []string{StatusActive}); err != nil {
+				r.lg.InfoCtx(ctx, fmt.Sprintf("func captureHold rejected: %v", err))
+				return err
+			}
+			return ErrWalletid
+		}
+		res, err = scanHold(tx.QueryRow(ctx,
+			"UPDATE holds SET status = $1, captured = $2, closed_at = now() WHERE id = $3 RETURNING "+holdColumns,
+			HoldCaptured, amount, id))
+		if err != nil {
+			r.lg.ErrorCtx(ctx, fmt.Sprintf("func captureHold sql query failed: %v", err))
+			return err
+		}
+		if err := r.recordOperation(ctx, tx, user_id, opWithdraw, currency, amount); err != nil {
+			return err
+		}
+		return r.recordEvent(ctx, tx, user_id, EventHoldCaptured, res)
+	})
+	if err != nil {
+		return Hold{}, err
+	}
+	r.lg.InfoCtx(ctx, fmt.Sprintf("func captureHold hold %d captured %s", id, amount))
+	return res, nil
+}
+
+// ReleaseHold закрывает действующий холд без списания.
+func (r *Repository) ReleaseHold(ctx context.Context, user_id int, id int64) (Hold, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	res, err := scanHold(r.db.QueryRow(ctx,
+		"UPDATE holds SET status = $1, closed_at = now() WHERE id = $2 AND user_id = $3 AND status = $4 AND expires_at > now() RETURNING "+holdColumns,
+		HoldReleased, id, user_id, HoldActive))
+	if err == pgx.ErrNoRows {
+		r.lg.InfoCtx(ctx, fmt.Sprintf("func releaseHold hold %d not found or closed", id))
+		return Hold{}, ErrNoHold
+	}
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func releaseHold sql query failed: %v", err))
+		return Hold{}, err
+	}
+	r.lg.InfoCtx(ctx, fmt.Sprintf("func releaseHold hold %d released", id))
+	return res, nil
+}
+
+// ExpireHolds переводит просроченные холды в expired и возвращает их число.
+// На доступный остаток это не влияет: просроченный холд перестает действовать сразу.
+func (r *Repository) ExpireHolds(ctx context.Context) (int64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result, err := r.db.Exec(ctx,
+		"UPDATE holds SET status = $1, closed_at = expires_at WHERE status = $2 AND expires_at <= now()",
+		HoldExpired, HoldActive)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func expireHolds sql query failed: %v", err))
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+func scanHold(row pgx.Row) (Hold, error) {
+	var h Hold
+	err := row.Scan(&h.ID, &h.UserID, &h.Currency, &h.Amount, &h.Captured, &h.Reference, &h.Status,
+		&h.CreatedAt, &h.ExpiresAt, &h.ClosedAt)
+	return h, err
+}
@@ -0,0 +1,213 @@
+package storages
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func holdRows(mock pgxmock.PgxPoolIface) *pgxmock.Rows {
+	return mock.NewRows([]string{"id", "user_id", "currency", "amount", "captured", "reference", "status",
+		"created_at", "expires_at", "closed_at"})
+}
+
+func TestGetBalanceSubtractsHolds(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	mock.ExpectQuery(`SELECT w.USD, w.RUB, w.EUR, \(SELECT COALESCE\(SUM\(h.amount\), 0\) FROM holds h .* h.currency = \$2 .*\) FROM wallets w`).
+		WithArgs(1, "USD", "RUB", "EUR").
+		WillReturnRows(heldBalanceRows(mock, 100, 0, 5, 40))
+
+	balance, err := rep.GetBalance(1, context.Background())
+	require.NoError(t, err)
+	assert.True(t, balance.USD.Equal(decimal.NewFromInt(100)))
+	assert.True(t, balance.Held["USD"].Equal(decimal.NewFromInt(40)))
+	assert.True(t, balance.Available["USD"].Equal(decimal.NewFromInt(60)))
+	assert.True(t, balance.Available["EUR"].Equal(decimal.NewFromInt(5)))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithdrawRespectsHolds(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	amount := decimal.NewFromInt(80)
+
+	mock.ExpectBegin()
+	expectTier(mock, 1)
+	mock.ExpectQuery(`UPDATE wallets w SET USD = w.USD - \$1::decimal .* AND w.USD - \(SELECT COALESCE\(SUM\(h.amount\), 0\) FROM holds h .*\) >= \$3::decimal`).
+		WithArgs(amount, 1, amount, StatusActive, "USD").
+		WillReturnRows(mock.NewRows([]string{"usd", "rub", "eur"}))
+	mock.ExpectQuery(`SELECT w.status, u.status FROM wallets w`).
+		WithArgs(1).
+		WillReturnRows(mock.NewRows([]string{"status", "status"}).AddRow(StatusActive, StatusActive))
+	mock.ExpectRollback()
+
+	_, err := rep.Withdraw(1, amount, "USD", context.Background())
+	assert.Equal(t, ErrWithdraw, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateHold(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	amount := decimal.NewFromInt(40)
+	expires := time.Now().Add(time.Hour)
+
+	mock.ExpectBegin()
+	expectTier(mock, 1)
+	mock.ExpectQuery(`INSERT INTO holds .* SELECT .* FROM wallets w JOIN users u .* AND w.USD - \(SELECT .*\) >= \$3::decimal`).
+		WithArgs(1, "USD", amount, "checkout-42", expires, StatusActive).
+		WillReturnRows(holdRows(mock).AddRow(int64(3), 1, "USD", amount, nil, "checkout-42", HoldActive, time.Now(), expires, nil))
+	mock.ExpectCommit()
+
+	h, err := rep.CreateHold(context.Background(), Hold{UserID: 1, Currency: "USD", Amount: amount, Reference: "checkout-42", ExpiresAt: expires})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), h.ID)
+	assert.Equal(t, HoldActive, h.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateHoldInsufficientFunds(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	amount := decimal.NewFromInt(40)
+	expires := time.Now().Add(time.Hour)
+
+	mock.ExpectBegin()
+	expectTier(mock, 1)
+	mock.ExpectQuery(`INSERT INTO holds`).
+		WithArgs(1, "USD", amount, "", expires, StatusActive).
+		WillReturnRows(holdRows(mock))
+	mock.ExpectQuery(`SELECT w.status, u.status FROM wallets w`).
+		WithArgs(1).
+		WillReturnRows(mock.NewRows([]string{"status", "status"}).AddRow(StatusActive, StatusActive))
+	mock.ExpectRollback()
+
+	_, err := rep.CreateHold(context.Background(), Hold{UserID: 1, Currency: "USD", Amount: amount, ExpiresAt: expires})
+	assert.Equal(t, ErrWithdraw, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCapturePartOfHold(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	captured := decimal.NewFromInt(25)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT currency, amount FROM holds WHERE id = \$1 AND user_id = \$2 AND status = \$3 AND expires_at > now\(\) FOR UPDATE`).
+		WithArgs(int64(3), 1, HoldActive).
+		WillReturnRows(mock.NewRows([]string{"currency", "amount"}).AddRow("USD", decimal.NewFromInt(40)))
+	mock.ExpectExec(`UPDATE wallets w SET USD = w.USD - \$1::decimal`).
+		WithArgs(captured, 1, StatusActive).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectQuery(`UPDATE holds SET status = \$1, captured = \$2`).
+		WithArgs(HoldCaptured, captured, int64(3)).
+		WillReturnRows(holdRows(mock).AddRow(int64(3), 1, "USD", decimal.NewFromInt(40), &captured, "", HoldCaptured, now, now.Add(time.Hour), &now))
+	mock.ExpectExec(`INSERT INTO wallet_operations`).
+		WithArgs(1, opWithdraw, "USD", captured).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	expectEvent(mock, 1, EventHoldCaptured)
+	mock.ExpectCommit()
+
+	h, err := rep.CaptureHold(context.Background(), 1, 3, captured)
+	require.NoError(t, err)
+	assert.Equal(t, HoldCaptured, h.Status)
+	assert.True(t, h.Captured.Equal(captured))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCaptureMoreThanHeld(t *testing.T) {
+	rep, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT currency, amount FROM holds`).
+		WithArgs(int64(3), 1, HoldActive).
+		WillReturnRows(mock.NewRows([]string{"currency", "amount"}).AddRow("USD", decimal.NewFromInt(40)))
+	mock.ExpectRollback()
+
+	_, err := rep.CaptureHold(context.Background(), 1, 3, decimal.NewFromInt(41))
+	assert.Equal(t, ErrHoldAmount, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReleaseExpiredHold(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	mock.ExpectQuery(`UPDATE holds SET status = \$1, closed_at = now\(\) WHERE id = \$2 AND user_id = \$3 AND status = \$4 AND expires_at > now\(\)`).
+		WithArgs(HoldReleased, int64(3), 1, HoldActive).
+		WillReturnRows(holdRows(mock))
+
+	_, err := rep.ReleaseHold(context.Background(), 1, 3)
+	assert.Equal(t, ErrNoHold, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpireHolds(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	mock.ExpectExec(`UPDATE holds SET status = \$1, closed_at = expires_at WHERE status = \$2 AND expires_at <= now\(\)`).
+		WithArgs(HoldExpired, HoldActive).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+
+	n, err := rep.ExpireHolds(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReleaseHoldDoesNotRecordOperation(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	now := time.Now()
+	mock.ExpectQuery(`UPDATE holds SET status = \$1, closed_at = now\(\)`).
+		WithArgs(HoldReleased, int64(3), 1, HoldActive).
+		WillReturnRows(holdRows(mock).AddRow(int64(3), 1, "USD", decimal.NewFromInt(40), nil, "", HoldReleased, now, now.Add(time.Hour), &now))
+
+	h, err := rep.ReleaseHold(context.Background(), 1, 3)
+	require.NoError(t, err)
+	assert.Equal(t, HoldReleased, h.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Холд занимает дневной лимит, только пока действует: после снятия или
+// истечения срока сумма из запроса пропадает и лимит освобождается.
+func TestWithdrawLimitCountsActiveHolds(t *testing.T) {
+	tests := []struct {
+		name    string
+		spent   int64
+		limited bool
+	}{
+		{name: "active hold", spent: 450, limited: true},
+		{name: "released or expired hold", spent: 300},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rep, mock := newMockRepository(t)
+			rep.limits = map[string]map[string]currencyLimits{
+				"standard": {"USD": {dailyWithdrawal: decimal.NewFromInt(500)}},
+			}
+			mock.ExpectBegin()
+			expectTier(mock, 1)
+			mock.ExpectQuery(`SELECT \(SELECT COALESCE\(SUM\(amount\), 0\) FROM wallet_operations .*\) \+ \(SELECT COALESCE\(SUM\(amount\), 0\) FROM holds WHERE user_id = \$1 AND currency = \$3 AND status = 'active' AND expires_at > now\(\)\)`).
+				WithArgs(1, opWithdraw, "USD").
+				WillReturnRows(mock.NewRows([]string{"spent"}).AddRow(decimal.NewFromInt(tt.spent)))
+			mock.ExpectRollback()
+
+			err := rep.WithTx(context.Background(), func(tx pgx.Tx) error {
+				if err := rep.checkLimits(context.Background(), tx, 1, opWithdraw, "USD", decimal.NewFromInt(100)); err != nil {
+					return err
+				}
+				return errors.New("rollback")
+			})
+			if tt.limited {
+				var limitErr *LimitError
+				require.ErrorAs(t, err, &limitErr)
+				assert.Equal(t, LimitDailyWithdrawal, limitErr.Limit)
+				assert.True(t, limitErr.Remaining.Equal(decimal.NewFromInt(50)))
+			} else {
+				assert.EqualError(t, err, "rollback")
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
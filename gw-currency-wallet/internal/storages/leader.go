@@ -0,0 +1,64 @@
+package storages
+
+import (
+	"context"
+	"fmt"
+
+	"gw-currency-wallet/internal/logger"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Leader выбирает одну реплику кошелька для фоновых задач с помощью
+// сессионной рекомендательной блокировки Postgres. Блокировка держится на
+// отдельном соединении: соединения пула возвращаются в пул между запросами
+// и унесли бы ее с собой. Обрыв соединения снимает блокировку, и ее
+// забирает другая реплика.
+type Leader struct {
+	dsn  string
+	key  int64
+	lg   logger.Logger
+	conn *pgx.Conn
+}
+
+func NewLeader(dsn string, key int64, lg logger.Logger) *Leader {
+	return &Leader{dsn: dsn, key: key, lg: lg}
+}
+
+// TryLead сообщает, держит ли реплика блокировку, и пытается ее взять, если нет.
+// Удерживаемая блокировка проверяется запросом: если соединение оборвалось,
+// лидерство потеряно.
+func (l *Leader) TryLead(ctx context.Context) (bool, error) {
+	if l.conn != nil {
+		err := l.conn.Ping(ctx)
+		if err == nil {
+			return true, nil
+		}
+		l.lg.WarnCtx(ctx, fmt.Sprintf("leader: lost lock connection: %v", err))
+		l.Release()
+	}
+	conn, err := pgx.Connect(ctx, l.dsn)
+	if err != nil {
+		return false, err
+	}
+	var locked bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", l.key).Scan(&locked); err != nil {
+		conn.Close(context.Background())
+		return false, err
+	}
+	if !locked {
+		conn.Close(context.Background())
+		return false, nil
+	}
+	l.conn = conn
+	l.lg.InfoCtx(ctx, fmt.Sprintf("leader: acquired advisory lock %d", l.key))
+	return true, nil
+}
+
+// Release закрывает соединение и тем самым снимает блокировку.
+func (l *Leader) Release() {
+	if l.conn != nil {
+		l.conn.Close(context.Background())
+		l.conn = nil
+	}
+}
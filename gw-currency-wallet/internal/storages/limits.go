@@ -0,0 +1,99 @@
+package storages
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	opWithdraw = "withdraw"
+	opExchange = "exchange"
+)
+
+// Виды лимитов, которые возвращаются в LimitError.
+const (
+	LimitMaxWithdrawal   = "max_withdrawal"
+	LimitDailyWithdrawal = "daily_withdrawal"
+	LimitDailyExchange   = "daily_exchange"
+)
+
+// LimitError сообщает о превышении лимита и о том, сколько еще можно провести.
+type LimitError struct {
+	Limit     string
+	Currency  string
+	Remaining decimal.Decimal
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("limit %s exceeded for %s, remaining %s", e.Limit, e.Currency, e.Remaining.StringFixed(2))
+}
+
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// checkLimits блокирует строку кошелька до конца транзакции и проверяет
+// лимиты тарифа пользователя для операции kind. Блокировка не дает
+// параллельным операциям вместе превысить дневной лимит.
+func (r *Repository) checkLimits(ctx context.Context, tx querier, user_id int, kind, currency string, amount decimal.Decimal) error {
+	if !amount.IsPositive() {
+		return ErrAmount
+	}
+	var tier string
+	err := tx.QueryRow(ctx, "SELECT u.tier FROM wallets w JOIN users u ON u.id = w.user_id WHERE w.user_id = $1 FOR UPDATE OF w", user_id).Scan(&tier)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil
+		}
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func checkLimits scan errors: %v", err))
+		return err
+	}
+	limits, ok := r.limits[tier][currency]
+	if !ok {
+		return nil
+	}
+
+	maxSingle, daily, dailyLimit := limitsFor(limits, kind)
+	if maxSingle.IsPositive() && amount.GreaterThan(maxSingle) {
+		return &LimitError{Limit: LimitMaxWithdrawal, Currency: currency, Remaining: maxSingle}
+	}
+	if !daily.IsPositive() {
+		return nil
+	}
+	spentSQL := "SELECT COALESCE(SUM(amount), 0) FROM wallet_operations WHERE user_id = $1 AND kind = $2 AND currency = $3 AND created_at >= date_trunc('day', now())"
+	if kind == opWithdraw {
+		// Действующие холды занимают лимит вывода, пока их не спишут, не снимут
+		// или не истечет их срок.
+		spentSQL = "SELECT (" + spentSQL + ") + (SELECT COALESCE(SUM(amount), 0) FROM holds WHERE user_id = $1 AND currency = $3 AND status = 'active' AND expires_at > now())"
+	}
+	var spent decimal.Decimal
+	err = tx.QueryRow(ctx, spentSQL, user_id, kind, currency).Scan(&spent)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func checkLimits sum errors: %v", err))
+		return err
+	}
+	if spent.Add(amount).GreaterThan(daily) {
+		return &LimitError{Limit: dailyLimit, Currency: currency, Remaining: decimal.Max(daily.Sub(spent), decimal.Zero)}
+	}
+	return nil
+}
+
+func (r *Repository) recordOperation(ctx context.Context, tx querier, user_id int, kind, currency string, amount decimal.Decimal) error {
+	_, err := tx.Exec(ctx, "INSERT INTO wallet_operations (user_id, kind, currency, amount) VALUES ($1, $2, $3, $4)", user_id, kind, currency, amount)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func recordOperation sql query failed: %v", err))
+	}
+	return err
+}
+
+func limitsFor(l currencyLimits, kind string) (maxSingle, daily decimal.Decimal, dailyLimit string) {
+	if kind == opWithdraw {
+		return l.maxWithdrawal, l.dailyWithdrawal, LimitDailyWithdrawal
+	}
+	return decimal.Zero, l.dailyExchange, LimitDailyExchange
+}
@@ -2,8 +2,11 @@ package storages
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"gw-currency-wallet/internal/logger"
+	"slices"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -11,49 +14,168 @@ import (
 )
 
 type RepositoryInterface interface {
-	Deposit(user_id int, amount decimal.Decimal, currency string, ctx context.Context) error
-	Withdraw(user_id int, amount decimal.Decimal, currency string, ctx context.Context) error
+	Deposit(user_id int, amount decimal.Decimal, currency string, ctx context.Context) (Balance, error)
+	Withdraw(user_id int, amount decimal.Decimal, currency string, ctx context.Context) (Balance, error)
 	GetBalance(user_id int, ctx context.Context) (Balance, error)
-	CheckUser(username string, email string, ctx context.Context) (bool, error)
 	AddUser(req RegisterRequest, ctx context.Context) error
 	GetUser(username string, ctx context.Context) (User, error)
 	ExchangeForCurrency(ctx context.Context, from, to string, amount decimal.Decimal, kurs float32, user_id int) (map[string]decimal.Decimal, error)
+	DepositAndExchange(ctx context.Context, from, to string, amount decimal.Decimal, kurs float32, user_id int) (map[string]decimal.Decimal, error)
+	Transfer(ctx context.Context, user_id, recipient_id int, currency string, amount decimal.Decimal) (Balance, error)
+	SetAccountStatus(user_id int, status string, ctx context.Context) error
+	GetTOTP(user_id int, ctx context.Context) (TOTP, error)
+	SetTOTPSecret(user_id int, secret string, ctx context.Context) error
+	EnableTOTP(user_id int, recoveryHashes []string, ctx context.Context) error
+	UseTOTPStep(user_id int, step int64, ctx context.Context) (bool, error)
+	UseRecoveryCode(user_id int, codeHash string, ctx context.Context) (bool, error)
+	CreateUserToken(email, kind, tokenHash string, expiresAt time.Time, ctx context.Context) (User, error)
+	VerifyEmail(tokenHash string, ctx context.Context) error
+	ResetPassword(tokenHash, passwordHash string, ctx context.Context) error
+	PlaceOrder(ctx context.Context, o Order) (Order, error)
+	ListOrders(ctx context.Context, user_id int, status string) ([]Order, error)
+	CancelOrder(ctx context.Context, user_id int, id int64) (Order, error)
+	OpenOrderPairs(ctx context.Context) ([]Pair, error)
+	FillOrders(ctx context.Context, pair Pair, rate decimal.Decimal) ([]Order, error)
+	ExpireOrders(ctx context.Context) ([]Order, error)
+	CreateHold(ctx context.Context, h Hold) (Hold, error)
+	ListHolds(ctx context.Context, user_id int, status string) ([]Hold, error)
+	CaptureHold(ctx context.Context, user_id int, id int64, amount decimal.Decimal) (Hold, error)
+	ReleaseHold(ctx context.Context, user_id int, id int64) (Hold, error)
+	ExpireHolds(ctx context.Context) (int64, error)
+	CreateSchedule(ctx context.Context, sch Schedule) (Schedule, error)
+	ListSchedules(ctx context.Context, user_id int) ([]Schedule, error)
+	GetSchedule(ctx context.Context, user_id int, id int64) (Schedule, error)
+	SetScheduleStatus(ctx context.Context, user_id int, id int64, status string, nextRun time.Time) (Schedule, error)
+	ListScheduleRuns(ctx context.Context, user_id int, id int64, limit int) ([]ScheduleRun, error)
+	DueSchedules(ctx context.Context, limit int) ([]Schedule, error)
+	ClaimSchedule(ctx context.Context, id int64, prevRun, nextRun time.Time) (bool, error)
+	RecordScheduleRun(ctx context.Context, run ScheduleRun, maxFailures int) (bool, error)
+	CreateWebhook(ctx context.Context, sub Webhook) (Webhook, error)
+	ListWebhooks(ctx context.Context, owner int) ([]Webhook, error)
+	DeleteWebhook(ctx context.Context, owner int, id int64) error
+	ListWebhookDeliveries(ctx context.Context, owner int, id int64, status string, limit int) ([]WebhookDelivery, error)
+	ReplayDelivery(ctx context.Context, owner int, id int64) (WebhookDelivery, error)
+	FanOutEvents(ctx context.Context, limit int) (int64, error)
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, id int64, status int) error
+	MarkDeliveryFailed(ctx context.Context, id int64, status int, reason string, retryAt *time.Time) error
+	ClaimEvents(ctx context.Context, lockKey int64, limit int, lease time.Duration) ([]Event, error)
+	FinishEvents(ctx context.Context, claimed, published []int64) error
+	Ready(ctx context.Context) error
 	Close()
 }
 
 type DBPool interface {
 	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
 	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+	Ping(ctx context.Context) error
 	Close()
 }
 
 type Repository struct {
-	db  DBPool
-	lg  logger.Logger
-	ctx context.Context
+	db            DBPool
+	lg            logger.Logger
+	depositPolicy string
+	limits        map[string]map[string]currencyLimits
+	txOptions     pgx.TxOptions
+	txRetries     int
+	queryTimeout  time.Duration
+}
+
+type currencyLimits struct {
+	maxWithdrawal   decimal.Decimal
+	dailyWithdrawal decimal.Decimal
+	dailyExchange   decimal.Decimal
 }
 
 const (
-	maxconns = 2000
+	uniqueViolation = "23505"
+)
+
+// Статусы аккаунта пользователя и его кошелька.
+const (
+	StatusActive = "active"
+	StatusFrozen = "frozen"
+	StatusClosed = "closed"
+)
+
+// Политики пополнения для кошельков, которые не находятся в статусе active.
+const (
+	DepositPolicyActiveOnly  = "active_only"
+	DepositPolicyAllowFrozen = "allow_frozen"
+	DepositPolicyAllowAll    = "allow_all"
 )
 
 var (
 	ErrWalletid = errors.New("wallet with this username not found")
 	ErrWithdraw = errors.New("insufficient funds or wallet with this username not found")
 	ErrExch     = errors.New("func exchangeForCurrency insufficient funds or wallet with this username not found")
+	ErrInactive = errors.New("account is frozen or closed")
+	ErrNoUser   = errors.New("user not found")
+	ErrStatus   = errors.New("unknown account status")
+	ErrCurrency = errors.New("unknown currency")
+	ErrAmount   = errors.New("amount must be positive")
+
+	ErrTOTPEnabled = errors.New("two-factor authentication is already enabled or not enrolled")
+	ErrToken       = errors.New("token is invalid, used or expired")
+	ErrUserExists  = errors.New("username or email already exists")
+	ErrNoOrder     = errors.New("order not found or already closed")
+	ErrNoHold      = errors.New("hold not found, closed or expired")
+	ErrHoldAmount  = errors.New("capture amount exceeds hold")
+	ErrNoSchedule  = errors.New("schedule not found")
+	ErrRecipient   = errors.New("recipient wallet not found or not accepting transfers")
+	ErrNoWebhook   = errors.New("webhook not found")
+	ErrNoDelivery  = errors.New("webhook delivery not found")
 )
 
 type User struct {
-	Id       int    `json:"id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Id            int    `json:"id"`
+	Username      string `json:"username"`
+	Email         string `json:"email"`
+	Password      string `json:"password"`
+	TOTPEnabled   bool   `json:"totp_enabled"`
+	EmailVerified bool   `json:"email_verified"`
 }
 
+type TOTP struct {
+	Secret  string
+	Enabled bool
+}
+
+// Balance — остатки кошелька. Held и Available заполняет только GetBalance:
+// сумма действующих холдов и остаток за их вычетом по валютам.
 type Balance struct {
-	USD decimal.Decimal `json:"USD"`
-	RUB decimal.Decimal `json:"RUB"`
-	EUR decimal.Decimal `json:"EUR"`
+	USD       decimal.Decimal            `json:"USD"`
+	RUB       decimal.Decimal            `json:"RUB"`
+	EUR       decimal.Decimal            `json:"EUR"`
+	Held      map[string]decimal.Decimal `json:"held,omitempty"`
+	Available map[string]decimal.Decimal `json:"available,omitempty"`
+}
+
+// Currencies — валюты кошелька в порядке полей Balance.
+var Currencies = []string{"USD", "RUB", "EUR"}
+
+// ValidCurrency сообщает, есть ли в кошельке колонка валюты c.
+func ValidCurrency(c string) bool {
+	return slices.Contains(Currencies, c)
+}
+
+// checkAmount не пускает в SQL неизвестную валюту и неположительную сумму.
+func checkAmount(currency string, amount decimal.Decimal) error {
+	if !ValidCurrency(currency) {
+		return ErrCurrency
+	}
+	if !amount.IsPositive() {
+		return ErrAmount
+	}
+	return nil
+}
+
+// Amounts возвращает баланс по валютам, ключ — код валюты.
+func (b Balance) Amounts() map[string]decimal.Decimal {
+	return map[string]decimal.Decimal{"USD": b.USD, "RUB": b.RUB, "EUR": b.EUR}
 }
 
 type RegisterRequest struct {
@@ -61,3 +183,142 @@ type RegisterRequest struct {
 	Password string `json:"password"`
 	Email    string `json:"email"`
 }
+
+// Состояния лимитной заявки.
+const (
+	OrderOpen      = "open"
+	OrderFilled    = "filled"
+	OrderCancelled = "cancelled"
+	OrderExpired   = "expired"
+)
+
+// Order — лимитная заявка на обмен Amount из From в To по курсу не хуже TargetRate.
+// FilledRate и Received заполняются при исполнении.
+type Order struct {
+	ID         int64            `json:"id"`
+	UserID     int              `json:"-"`
+	From       string           `json:"from_currency"`
+	To         string           `json:"to_currency"`
+	Amount     decimal.Decimal  `json:"amount"`
+	TargetRate decimal.Decimal  `json:"target_rate"`
+	Status     string           `json:"status"`
+	FilledRate *decimal.Decimal `json:"filled_rate,omitempty"`
+	Received   *decimal.Decimal `json:"received,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+	ExpiresAt  *time.Time       `json:"expires_at,omitempty"`
+	ClosedAt   *time.Time       `json:"closed_at,omitempty"`
+}
+
+// Pair — направление обмена.
+type Pair struct {
+	From string
+	To   string
+}
+
+// Состояния холда.
+const (
+	HoldActive   = "active"
+	HoldCaptured = "captured"
+	HoldReleased = "released"
+	HoldExpired  = "expired"
+)
+
+// Hold — резерв Amount в валюте Currency до ExpiresAt. Captured — списанная
+// часть, остаток при списании освобождается.
+type Hold struct {
+	ID        int64            `json:"id"`
+	UserID    int              `json:"-"`
+	Currency  string           `json:"currency"`
+	Amount    decimal.Decimal  `json:"amount"`
+	Captured  *decimal.Decimal `json:"captured,omitempty"`
+	Reference string           `json:"reference,omitempty"`
+	Status    string           `json:"status"`
+	CreatedAt time.Time        `json:"created_at"`
+	ExpiresAt time.Time        `json:"expires_at"`
+	ClosedAt  *time.Time       `json:"closed_at,omitempty"`
+}
+
+// Виды регулярных операций, состояния расписания и исходы запусков.
+const (
+	ScheduleExchange        = "exchange"
+	ScheduleDepositExchange = "deposit_exchange"
+	ScheduleTransfer        = "transfer"
+
+	ScheduleActive = "active"
+	SchedulePaused = "paused"
+
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+)
+
+// Schedule — регулярная операция пользователя по расписанию Cron (UTC).
+// Перевод идет в валюте From пользователю RecipientID, Recipient — его имя.
+// Failures — число неудачных запусков подряд.
+type Schedule struct {
+	ID          int64           `json:"id"`
+	UserID      int             `json:"-"`
+	Email       string          `json:"-"`
+	Kind        string          `json:"kind"`
+	From        string          `json:"from_currency"`
+	To          string          `json:"to_currency"`
+	RecipientID *int            `json:"-"`
+	Recipient   string          `json:"recipient,omitempty"`
+	Amount      decimal.Decimal `json:"amount"`
+	Cron        string          `json:"cron"`
+	Status      string          `json:"status"`
+	NextRunAt   time.Time       `json:"next_run_at"`
+	LastRunAt   *time.Time      `json:"last_run_at,omitempty"`
+	Failures    int             `json:"failures"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// ScheduleRun — один запуск регулярной операции.
+type ScheduleRun struct {
+	ID         int64            `json:"id"`
+	ScheduleID int64            `json:"schedule_id"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt time.Time        `json:"finished_at"`
+	Status     string           `json:"status"`
+	Rate       *decimal.Decimal `json:"rate,omitempty"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// Состояния доставки вебхука.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// Webhook — подписка на события кошелька. Подписка пользователя получает его
+// события, подписка партнера (UserID == nil) — события всех пользователей.
+// Пустой EventTypes — все события. Secret возвращается только при создании.
+type Webhook struct {
+	ID         int64     `json:"id"`
+	UserID     *int      `json:"user_id,omitempty"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WebhookDelivery — доставка события подписке. URL, Secret и Payload
+// заполняет только ClaimDeliveries.
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	SubscriptionID int64           `json:"subscription_id"`
+	EventID        int64           `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatus     *int            `json:"last_status,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	EventCreatedAt time.Time       `json:"-"`
+	URL            string          `json:"-"`
+	Secret         string          `json:"-"`
+	Payload        json.RawMessage `json:"-"`
+}
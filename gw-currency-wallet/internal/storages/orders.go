@@ -0,0 +1,236 @@
+package storages
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+)
+
+const orderColumns = "id, user_id, from_currency, to_currency, amount, target_rate, status, filled_rate, received, created_at, expires_at, closed_at"
+
+// PlaceOrder списывает o.Amount в валюте o.From и создает открытую заявку.
+// Резерв засчитывается в дневной лимит обмена сразу, как и обычный обмен.
+func (r *Repository) PlaceOrder(ctx context.Context, o Order) (Order, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	if !ValidCurrency(o.From) || !ValidCurrency(o.To) || o.From == o.To {
+		return Order{}, ErrCurrency
+	}
+	reserve := fmt.Sprintf(
+		"UPDATE wallets w SET %s = w.%s - $1::decimal FROM users u WHERE u.id = w.user_id AND w.user_id = $2 AND w.%s - %s >= $1 AND w.status = $3 AND u.status = $3",
+		o.From, o.From, o.From, heldSQL("$4"),
+	)
+	var res Order
+	err := r.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := r.checkLimits(ctx, tx, o.UserID, opExchange, o.From, o.Amount); err != nil {
+			r.lg.InfoCtx(ctx, fmt.Sprintf("func placeOrder rejected: %v", err))
+			return err
+		}
+		result, err := tx.Exec(ctx, reserve, o.Amount, o.UserID, StatusActive, o.From)
+		if err != nil {
+			r.lg.ErrorCtx(ctx, fmt.Sprintf("func placeOrder reserve failed: %v", err))
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			if err := r.checkStatus(ctx, tx, o.UserID, []string{StatusActive}); err == ErrInactive {
+				r.lg.InfoCtx(ctx, "func placeOrder account is frozen or closed")
+				return err
+			}
+			r.lg.InfoCtx(ctx, "func placeOrder insufficient funds or wallet with this username not found")
+			return ErrExch
+		}
+		row := tx.QueryRow(ctx,
+			"INSERT INTO exchange_orders (user_id, from_currency, to_currency, amount, target_rate, expires_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING "+orderColumns,
+			o.UserID, o.From, o.To, o.Amount, o.TargetRate, o.ExpiresAt)
+		if res, err = scanOrder(row); err != nil {
+			r.lg.ErrorCtx(ctx, fmt.Sprintf("func placeOrder insert failed: %v", err))
+			return err
+		}
+		if err := r.recordOperation(ctx, tx, o.UserID, opExchange, o.From, o.Amount); err != nil {
+			return err
+		}
+		return r.recordEvent(ctx, tx, o.UserID, EventOrderPlaced, res)
+	})
+	if err != nil {
+		return Order{}, err
+	}
+	r.lg.InfoCtx(ctx, fmt.Sprintf("func placeOrder order %d placed", res.ID))
+	return res, nil
+}
+
+// ListOrders возвращает заявки пользователя, новые первыми. Пустой status — все заявки.
+func (r *Repository) ListOrders(ctx context.Context, user_id int, status string) ([]Order, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	rows, err := r.db.Query(ctx,
+		"SELECT "+orderColumns+" FROM exchange_orders WHERE user_id = $1 AND ($2 = '' OR status = $2) ORDER BY created_at DESC, id DESC",
+		user_id, status)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func listOrders sql query failed: %v", err))
+		return nil, err
+	}
+	orders, err := collectOrders(rows)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func listOrders scan errors: %v", err))
+		return nil, err
+	}
+	return orders, nil
+}
+
+// CancelOrder закрывает открытую заявку пользователя и возвращает резерв на кошелек.
+func (r *Repository) CancelOrder(ctx context.Context, user_id int, id int64) (Order, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	var res Order
+	err := r.WithTx(ctx, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx,
+			"UPDATE exchange_orders SET status = $1, closed_at = now() WHERE id = $2 AND user_id = $3 AND status = $4 RETURNING "+orderColumns,
+			OrderCancelled, id, user_id, OrderOpen)
+		var err error
+		res, err = scanOrder(row)
+		if err == pgx.ErrNoRows {
+			r.lg.InfoCtx(ctx, fmt.Sprintf("func cancelOrder order %d not found or closed", id))
+			return ErrNoOrder
+		}
+		if err != nil {
+			r.lg.ErrorCtx(ctx, fmt.Sprintf("func cancelOrder sql query failed: %v", err))
+			return err
+		}
+		if err := r.credit(ctx, tx, res.UserID, res.From, res.Amount); err != nil {
+			return err
+		}
+		return r.recordEvent(ctx, tx, res.UserID, EventOrderCancelled, res)
+	})
+	if err != nil {
+		return Order{}, err
+	}
+	r.lg.InfoCtx(ctx, fmt.Sprintf("func cancelOrder order %d cancelled", id))
+	return res, nil
+}
+
+// OpenOrderPairs возвращает направления, по которым есть открытые заявки.
+func (r *Repository) OpenOrderPairs(ctx context.Context) ([]Pair, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	rows, err := r.db.Query(ctx, "SELECT DISTINCT from_currency, to_currency FROM exchange_orders WHERE status = $1", OrderOpen)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func openOrderPairs sql query failed: %v", err))
+		return nil, err
+	}
+	pairs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Pair, error) {
+		var p Pair
+		err := row.Scan(&p.From, &p.To)
+		return p, err
+	})
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func openOrderPairs scan errors: %v", err))
+		return nil, err
+	}
+	return pairs, nil
+}
+
+// FillOrders исполняет по курсу rate все открытые заявки направления pair,
+// чей целевой курс не выше rate, и зачисляет полученное на кошельки.
+// Заявки замороженных и закрытых аккаунтов ждут, пока аккаунт снова станет активным.
+func (r *Repository) FillOrders(ctx context.Context, pair Pair, rate decimal.Decimal) ([]Order, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	var filled []Order
+	err := r.WithTx(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx,
+			`UPDATE exchange_orders o SET status = $1, filled_rate = $2::decimal, received = ROUND(o.amount * $2::decimal, 2), closed_at = now()
+			FROM users u WHERE u.id = o.user_id AND u.status = $3
+			AND o.status = $4 AND o.from_currency = $5 AND o.to_currency = $6 AND o.target_rate <= $2::decimal
+			AND (o.expires_at IS NULL OR o.expires_at > now())
+			RETURNING o.id, o.user_id, o.from_currency, o.to_currency, o.amount, o.target_rate, o.status, o.filled_rate, o.received, o.created_at, o.expires_at, o.closed_at`,
+			OrderFilled, rate, StatusActive, OrderOpen, pair.From, pair.To)
+		if err != nil {
+			r.lg.ErrorCtx(ctx, fmt.Sprintf("func fillOrders sql query failed: %v", err))
+			return err
+		}
+		if filled, err = collectOrders(rows); err != nil {
+			r.lg.ErrorCtx(ctx, fmt.Sprintf("func fillOrders scan errors: %v", err))
+			return err
+		}
+		for _, o := range filled {
+			if err := r.credit(ctx, tx, o.UserID, o.To, *o.Received); err != nil {
+				return err
+			}
+			if err := r.recordEvent(ctx, tx, o.UserID, EventOrderFilled, o); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(filled) > 0 {
+		r.lg.InfoCtx(ctx, fmt.Sprintf("func fillOrders %d orders %s->%s filled at %s", len(filled), pair.From, pair.To, rate))
+	}
+	return filled, nil
+}
+
+// ExpireOrders закрывает открытые заявки с истекшим сроком и возвращает резерв.
+func (r *Repository) ExpireOrders(ctx context.Context) ([]Order, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	var expired []Order
+	err := r.WithTx(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx,
+			"UPDATE exchange_orders SET status = $1, closed_at = now() WHERE status = $2 AND expires_at <= now() RETURNING "+orderColumns,
+			OrderExpired, OrderOpen)
+		if err != nil {
+			r.lg.ErrorCtx(ctx, fmt.Sprintf("func expireOrders sql query failed: %v", err))
+			return err
+		}
+		if expired, err = collectOrders(rows); err != nil {
+			r.lg.ErrorCtx(ctx, fmt.Sprintf("func expireOrders scan errors: %v", err))
+			return err
+		}
+		for _, o := range expired {
+			if err := r.credit(ctx, tx, o.UserID, o.From, o.Amount); err != nil {
+				return err
+			}
+			if err := r.recordEvent(ctx, tx, o.UserID, EventOrderExpired, o); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(expired) > 0 {
+		r.lg.InfoCtx(ctx, fmt.Sprintf("func expireOrders %d orders expired", len(expired)))
+	}
+	return expired, nil
+}
+
+// credit зачисляет amount на кошелек без проверки статуса: так возвращается
+// резерв и зачисляется результат уже принятой заявки.
+func (r *Repository) credit(ctx context.Context, tx querier, user_id int, currency string, amount decimal.Decimal) error {
+	if !ValidCurrency(currency) {
+		return ErrCurrency
+	}
+	_, err := tx.Exec(ctx, fmt.Sprintf("UPDATE wallets SET %s = %s + $1::decimal WHERE user_id = $2", currency, currency), amount, user_id)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func credit sql query failed: %v", err))
+	}
+	return err
+}
+
+func scanOrder(row pgx.Row) (Order, error) {
+	var o Order
+	err := row.Scan(&o.ID, &o.UserID, &o.From, &o.To, &o.Amount, &o.TargetRate, &o.Status,
+		&o.FilledRate, &o.Received, &o.CreatedAt, &o.ExpiresAt, &o.ClosedAt)
+	return o, err
+}
+
+func collectOrders(rows pgx.Rows) ([]Order, error) {
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Order, error) {
+		return scanOrder(row)
+	})
+}
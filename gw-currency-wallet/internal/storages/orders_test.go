@@ -0,0 +1,182 @@
+package storages
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func orderRows(mock pgxmock.PgxPoolIface) *pgxmock.Rows {
+	return mock.NewRows([]string{"id", "user_id", "from_currency", "to_currency", "amount", "target_rate", "status",
+		"filled_rate", "received", "created_at", "expires_at", "closed_at"})
+}
+
+func TestPlaceOrderReservesFunds(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	amount := decimal.NewFromInt(100)
+	target := decimal.RequireFromString("0.95")
+	now := time.Now()
+
+	mock.ExpectBegin()
+	expectTier(mock, 1)
+	mock.ExpectExec(`UPDATE wallets w SET USD = w.USD - \$1::decimal .* AND w.USD - \(SELECT COALESCE\(SUM\(h.amount\), 0\) FROM holds h .*\) >= \$1`).
+		WithArgs(amount, 1, StatusActive, "USD").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectQuery(`INSERT INTO exchange_orders`).
+		WithArgs(1, "USD", "EUR", amount, target, (*time.Time)(nil)).
+		WillReturnRows(orderRows(mock).AddRow(int64(7), 1, "USD", "EUR", amount, target, OrderOpen, nil, nil, now, nil, nil))
+	mock.ExpectExec(`INSERT INTO wallet_operations`).
+		WithArgs(1, opExchange, "USD", amount).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	expectEvent(mock, 1, EventOrderPlaced)
+	mock.ExpectCommit()
+
+	o, err := rep.PlaceOrder(context.Background(), Order{UserID: 1, From: "USD", To: "EUR", Amount: amount, TargetRate: target})
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), o.ID)
+	assert.Equal(t, OrderOpen, o.Status)
+	assert.Nil(t, o.FilledRate)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPlaceOrderInsufficientFunds(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	amount := decimal.NewFromInt(100)
+
+	mock.ExpectBegin()
+	expectTier(mock, 1)
+	mock.ExpectExec(`UPDATE wallets w SET USD`).
+		WithArgs(amount, 1, StatusActive, "USD").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	mock.ExpectQuery(`SELECT w.status, u.status FROM wallets w`).
+		WithArgs(1).
+		WillReturnRows(mock.NewRows([]string{"status", "status"}).AddRow(StatusActive, StatusActive))
+	mock.ExpectRollback()
+
+	_, err := rep.PlaceOrder(context.Background(), Order{UserID: 1, From: "USD", To: "EUR", Amount: amount, TargetRate: decimal.NewFromInt(1)})
+	assert.Equal(t, ErrExch, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPlaceOrderRejectsUnknownCurrency(t *testing.T) {
+	rep, mock := newMockRepository(t)
+
+	_, err := rep.PlaceOrder(context.Background(), Order{UserID: 1, From: "USD; DROP TABLE wallets", To: "EUR", Amount: decimal.NewFromInt(1)})
+	assert.Equal(t, ErrCurrency, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCancelOrderRefundsReserve(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	amount := decimal.NewFromInt(100)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE exchange_orders SET status = \$1, closed_at = now\(\) WHERE id = \$2 AND user_id = \$3 AND status = \$4`).
+		WithArgs(OrderCancelled, int64(7), 1, OrderOpen).
+		WillReturnRows(orderRows(mock).AddRow(int64(7), 1, "USD", "EUR", amount, decimal.NewFromInt(1), OrderCancelled, nil, nil, now, nil, &now))
+	mock.ExpectExec(`UPDATE wallets SET USD = USD \+ \$1::decimal WHERE user_id = \$2`).
+		WithArgs(amount, 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectEvent(mock, 1, EventOrderCancelled)
+	mock.ExpectCommit()
+
+	o, err := rep.CancelOrder(context.Background(), 1, 7)
+	require.NoError(t, err)
+	assert.Equal(t, OrderCancelled, o.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCancelClosedOrder(t *testing.T) {
+	rep, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE exchange_orders SET status`).
+		WithArgs(OrderCancelled, int64(7), 1, OrderOpen).
+		WillReturnRows(orderRows(mock))
+	mock.ExpectRollback()
+
+	_, err := rep.CancelOrder(context.Background(), 1, 7)
+	assert.Equal(t, ErrNoOrder, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFillOrdersCreditsTargetCurrency(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	rate := decimal.RequireFromString("0.96")
+	received := decimal.NewFromInt(96)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE exchange_orders o SET status = \$1, filled_rate = \$2::decimal, received = ROUND\(o.amount \* \$2::decimal, 2\)`).
+		WithArgs(OrderFilled, rate, StatusActive, OrderOpen, "USD", "EUR").
+		WillReturnRows(orderRows(mock).
+			AddRow(int64(7), 1, "USD", "EUR", decimal.NewFromInt(100), decimal.RequireFromString("0.95"), OrderFilled, &rate, &received, now, nil, &now))
+	mock.ExpectExec(`UPDATE wallets SET EUR = EUR \+ \$1::decimal`).
+		WithArgs(received, 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectEvent(mock, 1, EventOrderFilled)
+	mock.ExpectCommit()
+
+	filled, err := rep.FillOrders(context.Background(), Pair{From: "USD", To: "EUR"}, rate)
+	require.NoError(t, err)
+	require.Len(t, filled, 1)
+	assert.True(t, filled[0].Received.Equal(received))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFillOrdersRollsBackWhenEventNotRecorded(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	rate := decimal.RequireFromString("0.96")
+	received := decimal.NewFromInt(96)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE exchange_orders o SET status`).
+		WithArgs(OrderFilled, rate, StatusActive, OrderOpen, "USD", "EUR").
+		WillReturnRows(orderRows(mock).
+			AddRow(int64(7), 1, "USD", "EUR", decimal.NewFromInt(100), decimal.RequireFromString("0.95"), OrderFilled, &rate, &received, now, nil, &now))
+	mock.ExpectExec(`UPDATE wallets SET EUR = EUR \+ \$1::decimal`).
+		WithArgs(received, 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`INSERT INTO outbox_events`).
+		WithArgs(1, EventOrderFilled, pgxmock.AnyArg()).
+		WillReturnError(errors.New("disk full"))
+	mock.ExpectRollback()
+
+	_, err := rep.FillOrders(context.Background(), Pair{From: "USD", To: "EUR"}, rate)
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpireOrdersRefundsReserve(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE exchange_orders SET status = \$1, closed_at = now\(\) WHERE status = \$2 AND expires_at <= now\(\)`).
+		WithArgs(OrderExpired, OrderOpen).
+		WillReturnRows(orderRows(mock).
+			AddRow(int64(7), 1, "RUB", "USD", decimal.NewFromInt(500), decimal.RequireFromString("0.012"), OrderExpired, nil, nil, now, &now, &now).
+			AddRow(int64(8), 2, "EUR", "USD", decimal.NewFromInt(10), decimal.RequireFromString("1.2"), OrderExpired, nil, nil, now, &now, &now))
+	mock.ExpectExec(`UPDATE wallets SET RUB = RUB \+ \$1::decimal`).
+		WithArgs(decimal.NewFromInt(500), 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectEvent(mock, 1, EventOrderExpired)
+	mock.ExpectExec(`UPDATE wallets SET EUR = EUR \+ \$1::decimal`).
+		WithArgs(decimal.NewFromInt(10), 2).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectEvent(mock, 2, EventOrderExpired)
+	mock.ExpectCommit()
+
+	expired, err := rep.ExpireOrders(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, expired, 2)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,87 @@
+package storages
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gw-currency-wallet/internal/config"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	defaultConnectBackoff    = 500 * time.Millisecond
+	defaultConnectBackoffMax = 30 * time.Second
+	pingTimeout              = 5 * time.Second
+)
+
+// poolConfig собирает настройки пула из database_url и секции database.
+// Нулевые значения оставляют настройки pgx по умолчанию.
+func poolConfig(cfg *config.ConfigAdr) (*pgxpool.Config, error) {
+	conf, err := pgxpool.ParseConfig(cfg.Database_url)
+	if err != nil {
+		return nil, err
+	}
+	db := cfg.Database
+	if db.Max_conns > 0 {
+		conf.MaxConns = db.Max_conns
+	}
+	if db.Min_conns > 0 {
+		conf.MinConns = db.Min_conns
+	}
+	if conf.MinConns > conf.MaxConns {
+		return nil, fmt.Errorf("database.min_conns %d is greater than max_conns %d", conf.MinConns, conf.MaxConns)
+	}
+	if db.Max_conn_lifetime_sec > 0 {
+		conf.MaxConnLifetime = time.Duration(db.Max_conn_lifetime_sec) * time.Second
+	}
+	if db.Max_conn_idle_time_sec > 0 {
+		conf.MaxConnIdleTime = time.Duration(db.Max_conn_idle_time_sec) * time.Second
+	}
+	if db.Health_check_period_sec > 0 {
+		conf.HealthCheckPeriod = time.Duration(db.Health_check_period_sec) * time.Second
+	}
+	return conf, nil
+}
+
+// connect проверяет соединение с базой, повторяя попытки с экспоненциальной
+// паузой, пока база не ответит, не кончатся попытки или не отменится ctx.
+// Процесс при этом не падает: до успешного подключения сервис просто не готов.
+func (r *Repository) connect(ctx context.Context, cfg config.Database) {
+	backoff := time.Duration(cfg.Connect_backoff_ms) * time.Millisecond
+	if backoff <= 0 {
+		backoff = defaultConnectBackoff
+	}
+	maxBackoff := time.Duration(cfg.Connect_backoff_max_ms) * time.Millisecond
+	if maxBackoff <= 0 {
+		maxBackoff = defaultConnectBackoffMax
+	}
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+		err := r.db.Ping(pingCtx)
+		cancel()
+		if err == nil {
+			r.lg.InfoCtx(ctx, fmt.Sprintf("database connection established after %d attempt(s)", attempt))
+			return
+		}
+		if cfg.Connect_attempts > 0 && attempt >= cfg.Connect_attempts {
+			r.lg.ErrorCtx(ctx, fmt.Sprintf("database is unavailable after %d attempts: %v", attempt, err))
+			return
+		}
+		r.lg.WarnCtx(ctx, fmt.Sprintf("database is unavailable, retrying in %s: %v", backoff, err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// Ready сообщает, может ли репозиторий обслуживать запросы: база отвечает на ping.
+func (r *Repository) Ready(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	return r.db.Ping(ctx)
+}
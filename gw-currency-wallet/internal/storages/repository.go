@@ -2,23 +2,26 @@ package storages
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"time"
 
 	"gw-currency-wallet/internal/config"
 	"gw-currency-wallet/internal/logger"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/lib/pq"
 	"github.com/shopspring/decimal"
 )
 
 func NewRepository(lg logger.Logger, ctx context.Context, cfg *config.ConfigAdr) RepositoryInterface {
-	conf, err := pgxpool.ParseConfig(cfg.Database_url)
+	conf, err := poolConfig(cfg)
 	if err != nil {
-		lg.FatalCtx(ctx, "Could not parse database URL: ", err)
+		lg.FatalCtx(ctx, "Could not parse database settings: ", err)
 	}
-	conf.MaxConns = maxconns
 
 	pg, err := pgxpool.NewWithConfig(ctx, conf)
 	if err != nil {
@@ -27,34 +30,67 @@ func NewRepository(lg logger.Logger, ctx context.Context, cfg *config.ConfigAdr)
 	rep := new(Repository)
 	rep.db = pg
 	rep.lg = lg
-	rep.ctx = ctx
+	rep.depositPolicy = cfg.Deposit_policy
+	rep.limits = newLimits(cfg.Limits)
+	rep.txOptions, err = txOptions(cfg.Transactions.Isolation)
+	if err != nil {
+		lg.FatalCtx(ctx, "Could not parse transaction settings: ", err)
+	}
+	rep.queryTimeout = time.Duration(cfg.Database.Query_timeout_ms) * time.Millisecond
+	rep.txRetries = cfg.Transactions.Max_retries
+	if rep.txRetries <= 0 {
+		rep.txRetries = defaultTxRetries
+	}
+	go rep.connect(ctx, cfg.Database)
 	return rep
 }
 
+func newLimits(cfg map[string]config.TierLimits) map[string]map[string]currencyLimits {
+	limits := make(map[string]map[string]currencyLimits)
+	for tier, currencies := range cfg {
+		limits[tier] = make(map[string]currencyLimits)
+		for currency, l := range currencies {
+			limits[tier][currency] = currencyLimits{
+				maxWithdrawal:   decimal.NewFromFloat(l.Max_withdrawal),
+				dailyWithdrawal: decimal.NewFromFloat(l.Daily_withdrawal),
+				dailyExchange:   decimal.NewFromFloat(l.Daily_exchange),
+			}
+		}
+	}
+	return limits
+}
+
 func (r *Repository) Close() {
 	r.db.Close()
 }
 
-func (r *Repository) CheckUser(username string, email string, ctx context.Context) (bool, error) {
-	var userUsername string
-	var userEmail string
-	err := r.db.QueryRow(r.ctx, "SELECT username, email FROM users WHERE username = $1 or email = $2 LIMIT 1", username, email).Scan(&userUsername, &userEmail)
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			r.lg.InfoCtx(ctx, "CheckUser no users found")
-			return false, nil
-		}
-		r.lg.ErrorCtx(ctx, "Could not scan user")
-		return false, err
+// withTimeout ограничивает ctx настроенным временем на вызов репозитория.
+// Отмена запроса клиента по-прежнему прерывает SQL раньше таймаута.
+func (r *Repository) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
 	}
-	r.lg.InfoCtx(ctx, "username or email exists")
-	return true, nil
+	return context.WithTimeout(ctx, r.queryTimeout)
 }
 
 func (r *Repository) AddUser(user RegisterRequest, ctx context.Context) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
-	_, err := r.db.Exec(r.ctx, "INSERT INTO users (username, email, pass) VALUES ($1, $2, $3)", user.Username, user.Email, user.Password)
+	err := r.WithTx(ctx, func(tx pgx.Tx) error {
+		var user_id int
+		err := tx.QueryRow(ctx, "INSERT INTO users (username, email, pass) VALUES ($1, $2, $3) RETURNING id", user.Username, user.Email, user.Password).Scan(&user_id)
+		if err != nil {
+			return err
+		}
+		return r.recordEvent(ctx, tx, user_id, EventUserRegistered, UserEvent{Username: user.Username, Email: user.Email})
+	})
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			r.lg.InfoCtx(ctx, fmt.Sprintf("func adduser %s violates %s", pgErr.TableName, pgErr.ConstraintName))
+			return ErrUserExists
+		}
 		r.lg.ErrorCtx(ctx, "func adduser sql query failed")
 		return err
 	}
@@ -63,12 +99,14 @@ func (r *Repository) AddUser(user RegisterRequest, ctx context.Context) error {
 }
 
 func (r *Repository) GetUser(username string, ctx context.Context) (User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 	user := new(User)
-	err := r.db.QueryRow(r.ctx, "SELECT username, pass, id FROM users WHERE username = $1 ", username).Scan(&user.Username, &user.Password, &user.Id)
+	err := r.db.QueryRow(ctx, "SELECT username, pass, id, totp_enabled, email_verified FROM users WHERE username = $1 ", username).Scan(&user.Username, &user.Password, &user.Id, &user.TOTPEnabled, &user.EmailVerified)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			r.lg.InfoCtx(ctx, "GetUser no users found")
-			return User{}, err
+			return User{}, ErrNoUser
 		}
 		r.lg.ErrorCtx(ctx, "Could not scan user")
 		return User{}, err
@@ -78,9 +116,14 @@ func (r *Repository) GetUser(username string, ctx context.Context) (User, error)
 }
 
 func (r *Repository) GetBalance(user_id int, ctx context.Context) (Balance, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 	balance := new(Balance)
+	var heldUSD, heldRUB, heldEUR decimal.Decimal
 	r.lg.DebugCtx(ctx, fmt.Sprintf("user_id: %v", user_id))
-	err := r.db.QueryRow(r.ctx, "SELECT USD, RUB, EUR FROM wallets WHERE user_id = $1 ", user_id).Scan(&balance.USD, &balance.RUB, &balance.EUR)
+	err := r.db.QueryRow(ctx,
+		"SELECT w.USD, w.RUB, w.EUR, "+heldSQL("$2")+", "+heldSQL("$3")+", "+heldSQL("$4")+" FROM wallets w WHERE w.user_id = $1",
+		user_id, "USD", "RUB", "EUR").Scan(&balance.USD, &balance.RUB, &balance.EUR, &heldUSD, &heldRUB, &heldEUR)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			r.lg.InfoCtx(ctx, "GetBalance no wallet found")
@@ -89,69 +132,318 @@ func (r *Repository) GetBalance(user_id int, ctx context.Context) (Balance, erro
 		r.lg.ErrorCtx(ctx, fmt.Sprintf("Could not scan balance errors: %v", err))
 		return Balance{}, err
 	}
+	balance.Held = map[string]decimal.Decimal{"USD": heldUSD, "RUB": heldRUB, "EUR": heldEUR}
+	balance.Available = make(map[string]decimal.Decimal, len(balance.Held))
+	for currency, total := range balance.Amounts() {
+		balance.Available[currency] = total.Sub(balance.Held[currency])
+	}
 	r.lg.InfoCtx(ctx, "GetBalance sql complete")
 	return *balance, nil
 }
 
-func (r *Repository) Deposit(user_id int, amount decimal.Decimal, currency string, ctx context.Context) error {
-	queryString := fmt.Sprintf("UPDATE wallets SET %s = %s + $1::decimal WHERE user_id = $2", currency, currency)
-	result, err := r.db.Exec(r.ctx, queryString, amount, user_id)
-	if err != nil {
-		r.lg.ErrorCtx(ctx, "func deposit sql query failed")
-		return err
+func (r *Repository) Deposit(user_id int, amount decimal.Decimal, currency string, ctx context.Context) (Balance, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	if err := checkAmount(currency, amount); err != nil {
+		return Balance{}, err
 	}
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		r.lg.InfoCtx(ctx, "func deposit wallet with this username not found")
-		return ErrWalletid
+	var balance Balance
+	err := r.WithTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		balance, err = r.deposit(ctx, tx, user_id, amount, currency)
+		return err
+	})
+	if err != nil {
+		return Balance{}, err
 	}
 	r.lg.InfoCtx(ctx, "func deposit sql complete")
-	return err
+	return balance, nil
 }
 
-func (r *Repository) Withdraw(user_id int, amount decimal.Decimal, currency string, ctx context.Context) error {
-	queryString := fmt.Sprintf("UPDATE wallets SET %s = %s - $1::decimal WHERE user_id = $2 AND %s >= $3::decimal", currency, currency, currency)
-	result, err := r.db.Exec(ctx, queryString, amount, user_id, amount)
-	rowsAffected := result.RowsAffected()
+func (r *Repository) deposit(ctx context.Context, tx querier, user_id int, amount decimal.Decimal, currency string) (Balance, error) {
+	allowed := depositStatuses(r.depositPolicy)
+	queryString := fmt.Sprintf(
+		"UPDATE wallets w SET %s = w.%s + $1::decimal FROM users u WHERE u.id = w.user_id AND w.user_id = $2 AND w.status = ANY($3) AND u.status = ANY($3) RETURNING w.USD, w.RUB, w.EUR",
+		currency, currency,
+	)
+	var balance Balance
+	err := tx.QueryRow(ctx, queryString, amount, user_id, allowed).Scan(&balance.USD, &balance.RUB, &balance.EUR)
+	if err == pgx.ErrNoRows {
+		if err := r.checkStatus(ctx, tx, user_id, allowed); err != nil {
+			r.lg.InfoCtx(ctx, fmt.Sprintf("func deposit rejected: %v", err))
+			return Balance{}, err
+		}
+		r.lg.InfoCtx(ctx, "func deposit wallet with this username not found")
+		return Balance{}, ErrWalletid
+	}
 	if err != nil {
-		r.lg.ErrorCtx(ctx, "func withdraw sql query failed")
-		return err
+		r.lg.ErrorCtx(ctx, "func deposit sql query failed")
+		return Balance{}, err
 	}
-	if rowsAffected == 0 {
-		r.lg.InfoCtx(ctx, "func withdraw insufficient funds or wallet with this username not found")
-		return ErrWithdraw
+	return balance, r.recordEvent(ctx, tx, user_id, EventDeposited, BalanceEvent{Currency: currency, Amount: amount, Balance: balance.Amounts()[currency]})
+}
+
+func (r *Repository) Withdraw(user_id int, amount decimal.Decimal, currency string, ctx context.Context) (Balance, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	if err := checkAmount(currency, amount); err != nil {
+		return Balance{}, err
+	}
+	queryString := fmt.Sprintf(
+		"UPDATE wallets w SET %s = w.%s - $1::decimal FROM users u WHERE u.id = w.user_id AND w.user_id = $2 AND w.%s - %s >= $3::decimal AND w.status = $4 AND u.status = $4 RETURNING w.USD, w.RUB, w.EUR",
+		currency, currency, currency, heldSQL("$5"),
+	)
+	var balance Balance
+	err := r.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := r.checkLimits(ctx, tx, user_id, opWithdraw, currency, amount); err != nil {
+			r.lg.InfoCtx(ctx, fmt.Sprintf("func withdraw rejected: %v", err))
+			return err
+		}
+		err := tx.QueryRow(ctx, queryString, amount, user_id, amount, StatusActive, currency).Scan(&balance.USD, &balance.RUB, &balance.EUR)
+		if err == pgx.ErrNoRows {
+			if err := r.checkStatus(ctx, tx, user_id, []string{StatusActive}); err == ErrInactive {
+				r.lg.InfoCtx(ctx, "func withdraw account is frozen or closed")
+				return err
+			}
+			r.lg.InfoCtx(ctx, "func withdraw insufficient funds or wallet with this username not found")
+			return ErrWithdraw
+		}
+		if err != nil {
+			r.lg.ErrorCtx(ctx, "func withdraw sql query failed")
+			return err
+		}
+		if err := r.recordOperation(ctx, tx, user_id, opWithdraw, currency, amount); err != nil {
+			return err
+		}
+		return r.recordEvent(ctx, tx, user_id, EventWithdrawn, BalanceEvent{Currency: currency, Amount: amount, Balance: balance.Amounts()[currency]})
+	})
+	if err != nil {
+		return Balance{}, err
 	}
 	r.lg.InfoCtx(ctx, "func withdraw sql complete")
-	return nil
+	return balance, nil
 }
 
 func (r *Repository) ExchangeForCurrency(ctx context.Context, from, to string, amount decimal.Decimal, kurs float32, user_id int) (map[string]decimal.Decimal, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	if err := checkAmount(from, amount); err != nil {
+		return nil, err
+	}
+	if !ValidCurrency(to) || to == from {
+		return nil, ErrCurrency
+	}
+	var res map[string]decimal.Decimal
+	err := r.WithTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		res, err = r.exchange(ctx, tx, from, to, amount, kurs, user_id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.lg.InfoCtx(ctx, "func exchangeForCurrency sql complete")
+	return res, nil
+}
+
+// DepositAndExchange пополняет кошелек на amount в from и обменивает
+// пополнение в to в одной транзакции: если обмен не прошел, пополнения нет.
+func (r *Repository) DepositAndExchange(ctx context.Context, from, to string, amount decimal.Decimal, kurs float32, user_id int) (map[string]decimal.Decimal, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	if err := checkAmount(from, amount); err != nil {
+		return nil, err
+	}
+	if !ValidCurrency(to) || to == from {
+		return nil, ErrCurrency
+	}
+	var res map[string]decimal.Decimal
+	err := r.WithTx(ctx, func(tx pgx.Tx) error {
+		if _, err := r.deposit(ctx, tx, user_id, amount, from); err != nil {
+			return err
+		}
+		var err error
+		res, err = r.exchange(ctx, tx, from, to, amount, kurs, user_id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.lg.InfoCtx(ctx, "func depositAndExchange sql complete")
+	return res, nil
+}
+
+func (r *Repository) exchange(ctx context.Context, tx querier, from, to string, amount decimal.Decimal, kurs float32, user_id int) (map[string]decimal.Decimal, error) {
 	kursDecimal := decimal.NewFromFloat32(kurs)
 	queryString := fmt.Sprintf(
-		"UPDATE wallets SET %s = %s - $1::decimal, %s = %s + ($2::decimal * $3::decimal) WHERE user_id = $4 AND %s >= $5",
-		from, from, to, to, from,
+		"UPDATE wallets w SET %s = w.%s - $1::decimal, %s = w.%s + ($2::decimal * $3::decimal) FROM users u WHERE u.id = w.user_id AND w.user_id = $4 AND w.%s - %s >= $5 AND w.status = $6 AND u.status = $6 RETURNING w.%s, w.%s",
+		from, from, to, to, from, heldSQL("$7"), from, to,
 	)
-	result, err := r.db.Exec(ctx, queryString, amount, amount, kursDecimal, user_id, amount)
-	if err != nil {
-		r.lg.ErrorCtx(ctx, "func exchangeForCurrency sql query failed")
+	if err := r.checkLimits(ctx, tx, user_id, opExchange, from, amount); err != nil {
+		r.lg.InfoCtx(ctx, fmt.Sprintf("func exchangeForCurrency rejected: %v", err))
 		return nil, err
 	}
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
+	var fromvalue, tovalue decimal.Decimal
+	err := tx.QueryRow(ctx, queryString, amount, amount, kursDecimal, user_id, amount, StatusActive, from).Scan(&fromvalue, &tovalue)
+	if err == pgx.ErrNoRows {
+		if err := r.checkStatus(ctx, tx, user_id, []string{StatusActive}); err == ErrInactive {
+			r.lg.InfoCtx(ctx, "func exchangeForCurrency account is frozen or closed")
+			return nil, err
+		}
 		r.lg.InfoCtx(ctx, "func exchangeForCurrency insufficient funds or wallet with this username not found")
 		return nil, ErrExch
 	}
-	res := make(map[string]decimal.Decimal)
-	var fromvalue, tovalue decimal.Decimal
-	queryString2 := fmt.Sprintf("SELECT %s, %s FROM wallets WHERE user_id = $1", from, to)
-	err = r.db.QueryRow(ctx, queryString2, user_id).Scan(&fromvalue, &tovalue)
 	if err != nil {
-		r.lg.ErrorCtx(ctx, fmt.Sprintf("func exchangeForCurrency scan errors: %v", err))
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func exchangeForCurrency sql query failed: %v", err))
+		return nil, err
+	}
+	if err := r.recordOperation(ctx, tx, user_id, opExchange, from, amount); err != nil {
+		return nil, err
+	}
+	err = r.recordEvent(ctx, tx, user_id, EventExchanged, ExchangeEvent{
+		From: from, To: to, Amount: amount, Rate: kursDecimal, Received: amount.Mul(kursDecimal),
+	})
+	if err != nil {
 		return nil, err
 	}
+	res := make(map[string]decimal.Decimal)
 	res[from] = fromvalue
 	res[to] = tovalue
-
-	r.lg.InfoCtx(ctx, "func exchangeForCurrency sql complete")
 	return res, nil
 }
+
+// Transfer переводит amount в currency с кошелька user_id на кошелек
+// recipient_id. Для отправителя перевод — тот же вывод: с проверкой статуса,
+// лимитов вывода и доступного остатка; получатель принимает его как
+// пополнение. Возвращает остатки отправителя.
+func (r *Repository) Transfer(ctx context.Context, user_id, recipient_id int, currency string, amount decimal.Decimal) (Balance, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	if err := checkAmount(currency, amount); err != nil {
+		return Balance{}, err
+	}
+	if recipient_id == user_id {
+		return Balance{}, ErrRecipient
+	}
+	allowed := depositStatuses(r.depositPolicy)
+	debit := fmt.Sprintf(
+		"UPDATE wallets w SET %s = w.%s - $1::decimal FROM users u WHERE u.id = w.user_id AND w.user_id = $2 AND w.%s - %s >= $3::decimal AND w.status = $4 AND u.status = $4 RETURNING w.USD, w.RUB, w.EUR",
+		currency, currency, currency, heldSQL("$5"),
+	)
+	credit := fmt.Sprintf(
+		"UPDATE wallets w SET %s = w.%s + $1::decimal FROM users u WHERE u.id = w.user_id AND w.user_id = $2 AND w.status = ANY($3) AND u.status = ANY($3) RETURNING w.%s",
+		currency, currency, currency,
+	)
+	var balance Balance
+	err := r.WithTx(ctx, func(tx pgx.Tx) error {
+		// Кошельки блокируются по порядку user_id, чтобы встречные переводы
+		// не ждали друг друга.
+		_, err := tx.Exec(ctx, "SELECT 1 FROM wallets WHERE user_id IN ($1, $2) ORDER BY user_id FOR UPDATE", user_id, recipient_id)
+		if err != nil {
+			r.lg.ErrorCtx(ctx, fmt.Sprintf("func transfer lock failed: %v", err))
+			return err
+		}
+		if err := r.checkLimits(ctx, tx, user_id, opWithdraw, currency, amount); err != nil {
+			r.lg.InfoCtx(ctx, fmt.Sprintf("func transfer rejected: %v", err))
+			return err
+		}
+		err = tx.QueryRow(ctx, debit, amount, user_id, amount, StatusActive, currency).Scan(&balance.USD, &balance.RUB, &balance.EUR)
+		if err == pgx.ErrNoRows {
+			if err := r.checkStatus(ctx, tx, user_id, []string{StatusActive}); err == ErrInactive {
+				r.lg.InfoCtx(ctx, "func transfer account is frozen or closed")
+				return err
+			}
+			r.lg.InfoCtx(ctx, "func transfer insufficient funds or wallet with this username not found")
+			return ErrWithdraw
+		}
+		if err != nil {
+			r.lg.ErrorCtx(ctx, fmt.Sprintf("func transfer sql query failed: %v", err))
+			return err
+		}
+		var received decimal.Decimal
+		err = tx.QueryRow(ctx, credit, amount, recipient_id, allowed).Scan(&received)
+		if err == pgx.ErrNoRows {
+			r.lg.InfoCtx(ctx, fmt.Sprintf("func transfer recipient %d not found or inactive", recipient_id))
+			return ErrRecipient
+		}
+		if err != nil {
+			r.lg.ErrorCtx(ctx, fmt.Sprintf("func transfer sql query failed: %v", err))
+			return err
+		}
+		if err := r.recordOperation(ctx, tx, user_id, opWithdraw, currency, amount); err != nil {
+			return err
+		}
+		err = r.recordEvent(ctx, tx, user_id, EventTransferSent, BalanceEvent{Currency: currency, Amount: amount, Balance: balance.Amounts()[currency]})
+		if err != nil {
+			return err
+		}
+		return r.recordEvent(ctx, tx, recipient_id, EventTransferReceived, BalanceEvent{Currency: currency, Amount: amount, Balance: received})
+	})
+	if err != nil {
+		return Balance{}, err
+	}
+	r.lg.InfoCtx(ctx, "func transfer sql complete")
+	return balance, nil
+}
+
+func (r *Repository) SetAccountStatus(user_id int, status string, ctx context.Context) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	if !validStatus(status) {
+		r.lg.InfoCtx(ctx, fmt.Sprintf("func setAccountStatus unknown status %q", status))
+		return ErrStatus
+	}
+	err := r.WithTx(ctx, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx,
+			"WITH u AS (UPDATE users SET status = $1 WHERE id = $2 RETURNING id) UPDATE wallets SET status = $1 WHERE user_id IN (SELECT id FROM u)",
+			status, user_id)
+		if err != nil {
+			r.lg.ErrorCtx(ctx, "func setAccountStatus sql query failed")
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			r.lg.InfoCtx(ctx, "func setAccountStatus wallet with this username not found")
+			return ErrWalletid
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	r.lg.InfoCtx(ctx, fmt.Sprintf("func setAccountStatus user %d is now %s", user_id, status))
+	return nil
+}
+
+// checkStatus объясняет, почему UPDATE не затронул ни одной строки: возвращает
+// ErrInactive, если статус пользователя или кошелька не входит в allowed,
+// ErrWalletid, если кошелька нет, и nil, если дело не в статусе.
+func (r *Repository) checkStatus(ctx context.Context, q querier, user_id int, allowed []string) error {
+	var walletStatus, userStatus string
+	err := q.QueryRow(ctx, "SELECT w.status, u.status FROM wallets w JOIN users u ON u.id = w.user_id WHERE w.user_id = $1", user_id).Scan(&walletStatus, &userStatus)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrWalletid
+		}
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func checkStatus scan errors: %v", err))
+		return err
+	}
+	if !slices.Contains(allowed, walletStatus) || !slices.Contains(allowed, userStatus) {
+		return ErrInactive
+	}
+	return nil
+}
+
+func depositStatuses(policy string) []string {
+	switch policy {
+	case DepositPolicyActiveOnly:
+		return []string{StatusActive}
+	case DepositPolicyAllowAll:
+		return []string{StatusActive, StatusFrozen, StatusClosed}
+	default:
+		return []string{StatusActive, StatusFrozen}
+	}
+}
+
+func validStatus(status string) bool {
+	return status == StatusActive || status == StatusFrozen || status == StatusClosed
+}
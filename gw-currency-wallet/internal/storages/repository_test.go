@@ -0,0 +1,388 @@
+package storages
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) DebugCtx(ctx context.Context, msg string)            {}
+func (nopLogger) InfoCtx(ctx context.Context, msg string)             {}
+func (nopLogger) WarnCtx(ctx context.Context, msg string)             {}
+func (nopLogger) ErrorCtx(ctx context.Context, msg string)            {}
+func (nopLogger) FatalCtx(ctx context.Context, msg string, err error) {}
+
+func newMockRepository(t *testing.T) (*Repository, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(mock.Close)
+	rep := &Repository{db: mock, lg: nopLogger{}, depositPolicy: DepositPolicyActiveOnly}
+	return rep, mock
+}
+
+func balanceRows(mock pgxmock.PgxPoolIface, usd, rub, eur int64) *pgxmock.Rows {
+	return mock.NewRows([]string{"usd", "rub", "eur"}).
+		AddRow(decimal.NewFromInt(usd), decimal.NewFromInt(rub), decimal.NewFromInt(eur))
+}
+
+// heldBalanceRows — ответ GetBalance с холдом usdHeld в долларах.
+func heldBalanceRows(mock pgxmock.PgxPoolIface, usd, rub, eur, usdHeld int64) *pgxmock.Rows {
+	return mock.NewRows([]string{"usd", "rub", "eur", "held_usd", "held_rub", "held_eur"}).
+		AddRow(decimal.NewFromInt(usd), decimal.NewFromInt(rub), decimal.NewFromInt(eur),
+			decimal.NewFromInt(usdHeld), decimal.Zero, decimal.Zero)
+}
+
+func expectTier(mock pgxmock.PgxPoolIface, user_id int) {
+	mock.ExpectQuery(`SELECT u.tier FROM wallets w JOIN users u .* FOR UPDATE OF w`).
+		WithArgs(user_id).
+		WillReturnRows(mock.NewRows([]string{"tier"}).AddRow("standard"))
+}
+
+func expectEvent(mock pgxmock.PgxPoolIface, user_id int, kind string) {
+	mock.ExpectExec(`INSERT INTO outbox_events`).
+		WithArgs(user_id, kind, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+}
+
+func TestDepositReturnsBalance(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	amount := decimal.NewFromInt(50)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE wallets w SET USD = w.USD \+ \$1::decimal .* RETURNING w.USD, w.RUB, w.EUR`).
+		WithArgs(amount, 1, []string{StatusActive}).
+		WillReturnRows(balanceRows(mock, 150, 10, 0))
+	expectEvent(mock, 1, EventDeposited)
+	mock.ExpectCommit()
+
+	balance, err := rep.Deposit(1, amount, "USD", context.Background())
+	require.NoError(t, err)
+	assert.True(t, balance.USD.Equal(decimal.NewFromInt(150)))
+	assert.True(t, balance.RUB.Equal(decimal.NewFromInt(10)))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDepositInactiveAccount(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	amount := decimal.NewFromInt(50)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE wallets w SET USD .* RETURNING`).
+		WithArgs(amount, 1, []string{StatusActive}).
+		WillReturnRows(mock.NewRows([]string{"usd", "rub", "eur"}))
+	mock.ExpectQuery(`SELECT w.status, u.status FROM wallets w`).
+		WithArgs(1).
+		WillReturnRows(mock.NewRows([]string{"status", "status"}).AddRow(StatusFrozen, StatusActive))
+	mock.ExpectRollback()
+
+	_, err := rep.Deposit(1, amount, "USD", context.Background())
+	assert.Equal(t, ErrInactive, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDepositCommitFailure(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	amount := decimal.NewFromInt(50)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE wallets w SET USD .* RETURNING`).
+		WithArgs(amount, 1, []string{StatusActive}).
+		WillReturnRows(balanceRows(mock, 150, 0, 0))
+	expectEvent(mock, 1, EventDeposited)
+	mock.ExpectCommit().WillReturnError(errors.New("connection reset"))
+
+	balance, err := rep.Deposit(1, amount, "USD", context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, Balance{}, balance)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDepositRollsBackWhenEventNotRecorded(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	amount := decimal.NewFromInt(50)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE wallets w SET USD .* RETURNING`).
+		WithArgs(amount, 1, []string{StatusActive}).
+		WillReturnRows(balanceRows(mock, 150, 0, 0))
+	mock.ExpectExec(`INSERT INTO outbox_events`).
+		WithArgs(1, EventDeposited, []byte(`{"currency":"USD","amount":"50","balance":"150"}`)).
+		WillReturnError(errors.New("disk full"))
+	mock.ExpectRollback()
+
+	balance, err := rep.Deposit(1, amount, "USD", context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, Balance{}, balance)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithdrawReturnsBalance(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	amount := decimal.NewFromInt(30)
+
+	mock.ExpectBegin()
+	expectTier(mock, 1)
+	mock.ExpectQuery(`UPDATE wallets w SET EUR = w.EUR - \$1::decimal .* h.currency = \$5 .* RETURNING w.USD, w.RUB, w.EUR`).
+		WithArgs(amount, 1, amount, StatusActive, "EUR").
+		WillReturnRows(balanceRows(mock, 0, 0, 70))
+	mock.ExpectExec(`INSERT INTO wallet_operations`).
+		WithArgs(1, opWithdraw, "EUR", amount).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	expectEvent(mock, 1, EventWithdrawn)
+	mock.ExpectCommit()
+
+	balance, err := rep.Withdraw(1, amount, "EUR", context.Background())
+	require.NoError(t, err)
+	assert.True(t, balance.EUR.Equal(decimal.NewFromInt(70)))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithdrawInsufficientFunds(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	amount := decimal.NewFromInt(300)
+
+	mock.ExpectBegin()
+	expectTier(mock, 1)
+	mock.ExpectQuery(`UPDATE wallets w SET USD .* RETURNING`).
+		WithArgs(amount, 1, amount, StatusActive, "USD").
+		WillReturnRows(mock.NewRows([]string{"usd", "rub", "eur"}))
+	mock.ExpectQuery(`SELECT w.status, u.status FROM wallets w`).
+		WithArgs(1).
+		WillReturnRows(mock.NewRows([]string{"status", "status"}).AddRow(StatusActive, StatusActive))
+	mock.ExpectRollback()
+
+	_, err := rep.Withdraw(1, amount, "USD", context.Background())
+	assert.Equal(t, ErrWithdraw, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInvalidAmountNeverReachesSQL(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	negative := decimal.NewFromInt(-100)
+
+	_, err := rep.Deposit(1, negative, "USD", context.Background())
+	assert.Equal(t, ErrAmount, err)
+	_, err = rep.Withdraw(1, decimal.Zero, "USD", context.Background())
+	assert.Equal(t, ErrAmount, err)
+	_, err = rep.Withdraw(1, decimal.NewFromInt(10), "USD = 0, RUB", context.Background())
+	assert.Equal(t, ErrCurrency, err)
+	_, err = rep.ExchangeForCurrency(context.Background(), "USD", "EUR", negative, 0.9, 1)
+	assert.Equal(t, ErrAmount, err)
+	_, err = rep.ExchangeForCurrency(context.Background(), "USD", "USD", decimal.NewFromInt(10), 1, 1)
+	assert.Equal(t, ErrCurrency, err)
+	assert.Equal(t, ErrAmount, rep.checkLimits(context.Background(), mock, 1, opWithdraw, "USD", negative))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithdrawRollsBackWhenOperationNotRecorded(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	amount := decimal.NewFromInt(30)
+
+	mock.ExpectBegin()
+	expectTier(mock, 1)
+	mock.ExpectQuery(`UPDATE wallets w SET USD .* RETURNING`).
+		WithArgs(amount, 1, amount, StatusActive, "USD").
+		WillReturnRows(balanceRows(mock, 70, 0, 0))
+	mock.ExpectExec(`INSERT INTO wallet_operations`).
+		WithArgs(1, opWithdraw, "USD", amount).
+		WillReturnError(errors.New("disk full"))
+	mock.ExpectRollback()
+
+	balance, err := rep.Withdraw(1, amount, "USD", context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, Balance{}, balance)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExchangeReturnsBalances(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	amount := decimal.NewFromInt(100)
+
+	mock.ExpectBegin()
+	expectTier(mock, 1)
+	mock.ExpectQuery(`UPDATE wallets w SET USD = w.USD - \$1::decimal, EUR = w.EUR \+ .* RETURNING w.USD, w.EUR`).
+		WithArgs(amount, amount, pgxmock.AnyArg(), 1, amount, StatusActive, "USD").
+		WillReturnRows(mock.NewRows([]string{"usd", "eur"}).AddRow(decimal.NewFromInt(0), decimal.NewFromInt(92)))
+	mock.ExpectExec(`INSERT INTO wallet_operations`).
+		WithArgs(1, opExchange, "USD", amount).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	expectEvent(mock, 1, EventExchanged)
+	mock.ExpectCommit()
+
+	res, err := rep.ExchangeForCurrency(context.Background(), "USD", "EUR", amount, 0.92, 1)
+	require.NoError(t, err)
+	assert.True(t, res["USD"].IsZero())
+	assert.True(t, res["EUR"].Equal(decimal.NewFromInt(92)))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExchangeInactiveAccount(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	amount := decimal.NewFromInt(100)
+
+	mock.ExpectBegin()
+	expectTier(mock, 1)
+	mock.ExpectQuery(`UPDATE wallets w SET USD .* RETURNING`).
+		WithArgs(amount, amount, pgxmock.AnyArg(), 1, amount, StatusActive, "USD").
+		WillReturnRows(mock.NewRows([]string{"usd", "eur"}))
+	mock.ExpectQuery(`SELECT w.status, u.status FROM wallets w`).
+		WithArgs(1).
+		WillReturnRows(mock.NewRows([]string{"status", "status"}).AddRow(StatusClosed, StatusClosed))
+	mock.ExpectRollback()
+
+	res, err := rep.ExchangeForCurrency(context.Background(), "USD", "EUR", amount, 0.92, 1)
+	assert.Equal(t, ErrInactive, err)
+	assert.Nil(t, res)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDepositAndExchangeRollsBackDeposit(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	amount := decimal.NewFromInt(100)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE wallets w SET RUB = w.RUB \+ \$1::decimal`).
+		WithArgs(amount, 1, []string{StatusActive}).
+		WillReturnRows(balanceRows(mock, 0, 100, 0))
+	expectEvent(mock, 1, EventDeposited)
+	expectTier(mock, 1)
+	mock.ExpectQuery(`UPDATE wallets w SET RUB = w.RUB - \$1::decimal, EUR = w.EUR \+ .* RETURNING w.RUB, w.EUR`).
+		WithArgs(amount, amount, pgxmock.AnyArg(), 1, amount, StatusActive, "RUB").
+		WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+
+	res, err := rep.DepositAndExchange(context.Background(), "RUB", "EUR", amount, 0.0105, 1)
+	assert.Error(t, err)
+	assert.Nil(t, res)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTransferMovesFunds(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	amount := decimal.NewFromInt(30)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT 1 FROM wallets WHERE user_id IN \(\$1, \$2\) ORDER BY user_id FOR UPDATE`).
+		WithArgs(1, 2).
+		WillReturnResult(pgxmock.NewResult("SELECT", 2))
+	expectTier(mock, 1)
+	mock.ExpectQuery(`UPDATE wallets w SET EUR = w.EUR - \$1::decimal .* h.currency = \$5 .* RETURNING w.USD, w.RUB, w.EUR`).
+		WithArgs(amount, 1, amount, StatusActive, "EUR").
+		WillReturnRows(balanceRows(mock, 0, 0, 70))
+	mock.ExpectQuery(`UPDATE wallets w SET EUR = w.EUR \+ \$1::decimal .* RETURNING w.EUR`).
+		WithArgs(amount, 2, []string{StatusActive}).
+		WillReturnRows(mock.NewRows([]string{"eur"}).AddRow(decimal.NewFromInt(30)))
+	mock.ExpectExec(`INSERT INTO wallet_operations`).
+		WithArgs(1, opWithdraw, "EUR", amount).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	expectEvent(mock, 1, EventTransferSent)
+	expectEvent(mock, 2, EventTransferReceived)
+	mock.ExpectCommit()
+
+	balance, err := rep.Transfer(context.Background(), 1, 2, "EUR", amount)
+	require.NoError(t, err)
+	assert.True(t, balance.EUR.Equal(decimal.NewFromInt(70)))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTransferToInactiveRecipient(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	amount := decimal.NewFromInt(30)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT 1 FROM wallets WHERE user_id IN`).
+		WithArgs(1, 2).
+		WillReturnResult(pgxmock.NewResult("SELECT", 2))
+	expectTier(mock, 1)
+	mock.ExpectQuery(`UPDATE wallets w SET EUR = w.EUR - \$1::decimal`).
+		WithArgs(amount, 1, amount, StatusActive, "EUR").
+		WillReturnRows(balanceRows(mock, 0, 0, 70))
+	mock.ExpectQuery(`UPDATE wallets w SET EUR = w.EUR \+ \$1::decimal`).
+		WithArgs(amount, 2, []string{StatusActive}).
+		WillReturnRows(mock.NewRows([]string{"eur"}))
+	mock.ExpectRollback()
+
+	_, err := rep.Transfer(context.Background(), 1, 2, "EUR", amount)
+	assert.Equal(t, ErrRecipient, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTransferToSelf(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	_, err := rep.Transfer(context.Background(), 1, 1, "EUR", decimal.NewFromInt(30))
+	assert.Equal(t, ErrRecipient, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCancelledRequestAbortsQuery(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	mock.ExpectQuery(`SELECT w.USD, w.RUB, w.EUR, .* FROM wallets w`).
+		WithArgs(1, "USD", "RUB", "EUR").
+		WillReturnRows(heldBalanceRows(mock, 1, 2, 3, 0)).
+		WillDelayFor(5 * time.Second)
+
+	queryErr := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := rep.GetBalance(1, r.Context())
+		queryErr <- err
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	_, err = http.DefaultClient.Do(req)
+	require.Error(t, err)
+
+	select {
+	case err := <-queryErr:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("query was not aborted after the client went away")
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQueryTimeout(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	rep.queryTimeout = 20 * time.Millisecond
+	mock.ExpectQuery(`SELECT username, pass, id`).
+		WithArgs("alice").
+		WillReturnRows(mock.NewRows([]string{"username", "pass", "id", "totp_enabled", "email_verified"})).
+		WillDelayFor(5 * time.Second)
+
+	start := time.Now()
+	_, err := rep.GetUser("alice", context.Background())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQueryTimeoutAbortsTransaction(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	rep.queryTimeout = 20 * time.Millisecond
+	amount := decimal.NewFromInt(10)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE wallets w SET USD .* RETURNING`).
+		WithArgs(amount, 1, []string{StatusActive}).
+		WillReturnRows(balanceRows(mock, 10, 0, 0)).
+		WillDelayFor(5 * time.Second)
+	mock.ExpectRollback()
+
+	_, err := rep.Deposit(1, amount, "USD", context.Background())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,201 @@
+package storages
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const scheduleColumns = "s.id, s.user_id, s.kind, s.from_currency, s.to_currency, s.recipient_id, " +
+	"COALESCE((SELECT username FROM users WHERE id = s.recipient_id), ''), s.amount, s.cron, s.status, s.next_run_at, s.last_run_at, s.failures, s.created_at"
+
+func (r *Repository) CreateSchedule(ctx context.Context, sch Schedule) (Schedule, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	if !ValidCurrency(sch.From) || !ValidCurrency(sch.To) || (sch.From == sch.To) != (sch.Kind == ScheduleTransfer) {
+		return Schedule{}, ErrCurrency
+	}
+	if (sch.RecipientID != nil) != (sch.Kind == ScheduleTransfer) {
+		return Schedule{}, ErrRecipient
+	}
+	res, err := scanSchedule(r.db.QueryRow(ctx,
+		"INSERT INTO scheduled_operations AS s (user_id, kind, from_currency, to_currency, recipient_id, amount, cron, next_run_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING "+scheduleColumns,
+		sch.UserID, sch.Kind, sch.From, sch.To, sch.RecipientID, sch.Amount, sch.Cron, sch.NextRunAt))
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func createSchedule sql query failed: %v", err))
+		return Schedule{}, err
+	}
+	r.lg.InfoCtx(ctx, fmt.Sprintf("func createSchedule schedule %d created", res.ID))
+	return res, nil
+}
+
+func (r *Repository) ListSchedules(ctx context.Context, user_id int) ([]Schedule, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	rows, err := r.db.Query(ctx, "SELECT "+scheduleColumns+" FROM scheduled_operations s WHERE s.user_id = $1 ORDER BY s.id", user_id)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func listSchedules sql query failed: %v", err))
+		return nil, err
+	}
+	res, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Schedule, error) {
+		return scanSchedule(row)
+	})
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func listSchedules scan errors: %v", err))
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *Repository) GetSchedule(ctx context.Context, user_id int, id int64) (Schedule, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	res, err := scanSchedule(r.db.QueryRow(ctx, "SELECT "+scheduleColumns+" FROM scheduled_operations s WHERE s.id = $1 AND s.user_id = $2", id, user_id))
+	if err == pgx.ErrNoRows {
+		return Schedule{}, ErrNoSchedule
+	}
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func getSchedule sql query failed: %v", err))
+		return Schedule{}, err
+	}
+	return res, nil
+}
+
+// SetScheduleStatus приостанавливает или возобновляет расписание. Нулевой
+// nextRun оставляет время следующего запуска прежним. Возобновление
+// сбрасывает счетчик неудач.
+func (r *Repository) SetScheduleStatus(ctx context.Context, user_id int, id int64, status string, nextRun time.Time) (Schedule, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	var next *time.Time
+	if !nextRun.IsZero() {
+		next = &nextRun
+	}
+	res, err := scanSchedule(r.db.QueryRow(ctx,
+		"UPDATE scheduled_operations s SET status = $1, next_run_at = COALESCE($2, s.next_run_at), failures = CASE WHEN $1 = $3 THEN 0 ELSE s.failures END WHERE s.id = $4 AND s.user_id = $5 RETURNING "+scheduleColumns,
+		status, next, ScheduleActive, id, user_id))
+	if err == pgx.ErrNoRows {
+		return Schedule{}, ErrNoSchedule
+	}
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func setScheduleStatus sql query failed: %v", err))
+		return Schedule{}, err
+	}
+	r.lg.InfoCtx(ctx, fmt.Sprintf("func setScheduleStatus schedule %d is now %s", id, status))
+	return res, nil
+}
+
+// ListScheduleRuns возвращает последние limit запусков расписания пользователя.
+func (r *Repository) ListScheduleRuns(ctx context.Context, user_id int, id int64, limit int) ([]ScheduleRun, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	var exists bool
+	err := r.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM scheduled_operations WHERE id = $1 AND user_id = $2)", id, user_id).Scan(&exists)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func listScheduleRuns sql query failed: %v", err))
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNoSchedule
+	}
+	rows, err := r.db.Query(ctx,
+		"SELECT id, schedule_id, started_at, finished_at, status, rate, error FROM scheduled_runs WHERE schedule_id = $1 ORDER BY started_at DESC LIMIT $2",
+		id, limit)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func listScheduleRuns sql query failed: %v", err))
+		return nil, err
+	}
+	runs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (ScheduleRun, error) {
+		var run ScheduleRun
+		err := row.Scan(&run.ID, &run.ScheduleID, &run.StartedAt, &run.FinishedAt, &run.Status, &run.Rate, &run.Error)
+		return run, err
+	})
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func listScheduleRuns scan errors: %v", err))
+		return nil, err
+	}
+	return runs, nil
+}
+
+// DueSchedules возвращает до limit активных расписаний, время запуска которых
+// наступило, вместе с почтой владельца для уведомлений.
+func (r *Repository) DueSchedules(ctx context.Context, limit int) ([]Schedule, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	rows, err := r.db.Query(ctx,
+		"SELECT "+scheduleColumns+", u.email FROM scheduled_operations s JOIN users u ON u.id = s.user_id WHERE s.status = $1 AND s.next_run_at <= now() ORDER BY s.next_run_at LIMIT $2",
+		ScheduleActive, limit)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func dueSchedules sql query failed: %v", err))
+		return nil, err
+	}
+	res, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Schedule, error) {
+		var s Schedule
+		err := row.Scan(&s.ID, &s.UserID, &s.Kind, &s.From, &s.To, &s.RecipientID, &s.Recipient, &s.Amount, &s.Cron, &s.Status,
+			&s.NextRunAt, &s.LastRunAt, &s.Failures, &s.CreatedAt, &s.Email)
+		return s, err
+	})
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func dueSchedules scan errors: %v", err))
+		return nil, err
+	}
+	return res, nil
+}
+
+// ClaimSchedule переносит запуск с prevRun на nextRun, если его еще никто не
+// перенес. Запуск выполняет только тот, кто его перенес, поэтому операция не
+// повторится, даже если два планировщика увидели ее одновременно.
+func (r *Repository) ClaimSchedule(ctx context.Context, id int64, prevRun, nextRun time.Time) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result, err := r.db.Exec(ctx,
+		"UPDATE scheduled_operations SET next_run_at = $1 WHERE id = $2 AND next_run_at = $3 AND status = $4",
+		nextRun, id, prevRun, ScheduleActive)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func claimSchedule sql query failed: %v", err))
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// RecordScheduleRun сохраняет запуск в историю и обновляет счетчик неудач.
+// После maxFailures неудач подряд расписание приостанавливается; ноль
+// отключает остановку. Возвращает true, если расписание приостановлено сейчас.
+func (r *Repository) RecordScheduleRun(ctx context.Context, run ScheduleRun, maxFailures int) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	failed := run.Status == RunFailed
+	var status string
+	err := r.WithTx(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx,
+			"INSERT INTO scheduled_runs (schedule_id, started_at, status, rate, error) VALUES ($1, $2, $3, $4, $5)",
+			run.ScheduleID, run.StartedAt, run.Status, run.Rate, run.Error)
+		if err != nil {
+			r.lg.ErrorCtx(ctx, fmt.Sprintf("func recordScheduleRun insert failed: %v", err))
+			return err
+		}
+		err = tx.QueryRow(ctx,
+			`UPDATE scheduled_operations SET last_run_at = $1,
+			failures = CASE WHEN $2::boolean THEN failures + 1 ELSE 0 END,
+			status = CASE WHEN $2::boolean AND $3::int > 0 AND failures + 1 >= $3::int THEN $4 ELSE status END
+			WHERE id = $5 RETURNING status`,
+			run.StartedAt, failed, maxFailures, SchedulePaused, run.ScheduleID).Scan(&status)
+		if err != nil {
+			r.lg.ErrorCtx(ctx, fmt.Sprintf("func recordScheduleRun update failed: %v", err))
+		}
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return failed && status == SchedulePaused, nil
+}
+
+func scanSchedule(row pgx.Row) (Schedule, error) {
+	var s Schedule
+	err := row.Scan(&s.ID, &s.UserID, &s.Kind, &s.From, &s.To, &s.RecipientID, &s.Recipient, &s.Amount, &s.Cron, &s.Status,
+		&s.NextRunAt, &s.LastRunAt, &s.Failures, &s.CreatedAt)
+	return s, err
+}
@@ -0,0 +1,87 @@
+package storages
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimScheduleOnlyOnce(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	prev := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	next := prev.AddDate(0, 0, 7)
+
+	mock.ExpectExec(`UPDATE scheduled_operations SET next_run_at = \$1 WHERE id = \$2 AND next_run_at = \$3 AND status = \$4`).
+		WithArgs(next, int64(5), prev, ScheduleActive).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`UPDATE scheduled_operations SET next_run_at`).
+		WithArgs(next, int64(5), prev, ScheduleActive).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	ok, err := rep.ClaimSchedule(context.Background(), 5, prev, next)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = rep.ClaimSchedule(context.Background(), 5, prev, next)
+	require.NoError(t, err)
+	assert.False(t, ok, "another scheduler already moved the run")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecordFailedRunPausesSchedule(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	started := time.Now()
+	rate := decimal.RequireFromString("0.0105")
+	run := ScheduleRun{ScheduleID: 5, StartedAt: started, Status: RunFailed, Rate: &rate, Error: ErrExch.Error()}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO scheduled_runs`).
+		WithArgs(int64(5), started, RunFailed, &rate, ErrExch.Error()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery(`UPDATE scheduled_operations SET last_run_at = \$1`).
+		WithArgs(started, true, 3, SchedulePaused, int64(5)).
+		WillReturnRows(mock.NewRows([]string{"status"}).AddRow(SchedulePaused))
+	mock.ExpectCommit()
+
+	paused, err := rep.RecordScheduleRun(context.Background(), run, 3)
+	require.NoError(t, err)
+	assert.True(t, paused)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListRunsOfForeignSchedule(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM scheduled_operations WHERE id = \$1 AND user_id = \$2\)`).
+		WithArgs(int64(5), 2).
+		WillReturnRows(mock.NewRows([]string{"exists"}).AddRow(false))
+
+	_, err := rep.ListScheduleRuns(context.Background(), 2, 5, 20)
+	assert.Equal(t, ErrNoSchedule, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateScheduleChecksKind(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	recipient := 2
+	tests := []struct {
+		name string
+		sch  Schedule
+		err  error
+	}{
+		{name: "transfer without recipient", sch: Schedule{Kind: ScheduleTransfer, From: "RUB", To: "RUB"}, err: ErrRecipient},
+		{name: "transfer between currencies", sch: Schedule{Kind: ScheduleTransfer, From: "RUB", To: "EUR", RecipientID: &recipient}, err: ErrCurrency},
+		{name: "exchange with recipient", sch: Schedule{Kind: ScheduleExchange, From: "RUB", To: "EUR", RecipientID: &recipient}, err: ErrRecipient},
+		{name: "exchange into the same currency", sch: Schedule{Kind: ScheduleDepositExchange, From: "RUB", To: "RUB"}, err: ErrCurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := rep.CreateSchedule(context.Background(), tt.sch)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
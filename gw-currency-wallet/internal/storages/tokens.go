@@ -0,0 +1,114 @@
+package storages
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Назначения одноразовых токенов из таблицы user_tokens.
+const (
+	TokenVerifyEmail   = "verify_email"
+	TokenResetPassword = "reset_password"
+)
+
+// CreateUserToken сохраняет хеш одноразового токена для пользователя с почтой
+// email и возвращает этого пользователя. Возвращает ErrNoUser, если почта не найдена.
+func (r *Repository) CreateUserToken(email, kind, tokenHash string, expiresAt time.Time, ctx context.Context) (User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	var user User
+	err := r.WithTx(ctx, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `
+			WITH u AS (SELECT id, username FROM users WHERE email = $1),
+			ins AS (
+				INSERT INTO user_tokens (user_id, kind, token_hash, expires_at)
+				SELECT id, $2, $3, $4 FROM u
+				RETURNING user_id
+			)
+			SELECT u.id, u.username FROM u JOIN ins ON ins.user_id = u.id`,
+			email, kind, tokenHash, expiresAt).Scan(&user.Id, &user.Username)
+		if err == pgx.ErrNoRows {
+			r.lg.InfoCtx(ctx, "CreateUserToken no users found")
+			return ErrNoUser
+		}
+		if err != nil {
+			r.lg.ErrorCtx(ctx, fmt.Sprintf("func createUserToken sql query failed: %v", err))
+		}
+		return err
+	})
+	if err != nil {
+		return User{}, err
+	}
+	user.Email = email
+	r.lg.InfoCtx(ctx, "func createUserToken sql complete")
+	return user, nil
+}
+
+// VerifyEmail гасит токен подтверждения и отмечает почту подтвержденной одним запросом.
+func (r *Repository) VerifyEmail(tokenHash string, ctx context.Context) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	err := r.WithTx(ctx, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			WITH t AS (
+				UPDATE user_tokens SET used_at = now()
+				WHERE kind = $1 AND token_hash = $2 AND used_at IS NULL AND expires_at > now()
+				RETURNING user_id
+			)
+			UPDATE users SET email_verified = TRUE WHERE id IN (SELECT user_id FROM t)`,
+			TokenVerifyEmail, tokenHash)
+		if err != nil {
+			r.lg.ErrorCtx(ctx, "func verifyEmail sql query failed")
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			r.lg.InfoCtx(ctx, "func verifyEmail token is invalid, used or expired")
+			return ErrToken
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	r.lg.InfoCtx(ctx, "func verifyEmail sql complete")
+	return nil
+}
+
+// ResetPassword гасит токен сброса, меняет пароль и отзывает остальные
+// неиспользованные токены сброса этого пользователя.
+func (r *Repository) ResetPassword(tokenHash, passwordHash string, ctx context.Context) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	var user_id int
+	err := r.WithTx(ctx, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `
+			UPDATE user_tokens SET used_at = now()
+			WHERE kind = $1 AND token_hash = $2 AND used_at IS NULL AND expires_at > now()
+			RETURNING user_id`, TokenResetPassword, tokenHash).Scan(&user_id)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				r.lg.InfoCtx(ctx, "func resetPassword token is invalid, used or expired")
+				return ErrToken
+			}
+			r.lg.ErrorCtx(ctx, "func resetPassword sql query failed")
+			return err
+		}
+		if _, err := tx.Exec(ctx, "UPDATE users SET pass = $1 WHERE id = $2", passwordHash, user_id); err != nil {
+			r.lg.ErrorCtx(ctx, "func resetPassword update password failed")
+			return err
+		}
+		if _, err := tx.Exec(ctx, "UPDATE user_tokens SET used_at = now() WHERE user_id = $1 AND kind = $2 AND used_at IS NULL", user_id, TokenResetPassword); err != nil {
+			r.lg.ErrorCtx(ctx, "func resetPassword revoke tokens failed")
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	r.lg.InfoCtx(ctx, fmt.Sprintf("func resetPassword user %d changed password", user_id))
+	return nil
+}
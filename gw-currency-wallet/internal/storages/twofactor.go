@@ -0,0 +1,119 @@
+package storages
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *Repository) GetTOTP(user_id int, ctx context.Context) (TOTP, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	var secret *string
+	var res TOTP
+	err := r.db.QueryRow(ctx, "SELECT totp_secret, totp_enabled FROM users WHERE id = $1", user_id).Scan(&secret, &res.Enabled)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			r.lg.InfoCtx(ctx, "GetTOTP no users found")
+			return TOTP{}, ErrNoUser
+		}
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func getTOTP scan errors: %v", err))
+		return TOTP{}, err
+	}
+	if secret != nil {
+		res.Secret = *secret
+	}
+	return res, nil
+}
+
+// SetTOTPSecret сохраняет новый секрет для подтверждения. Пока 2FA не
+// подтверждена кодом, секрет можно перезаписывать.
+func (r *Repository) SetTOTPSecret(user_id int, secret string, ctx context.Context) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	err := r.WithTx(ctx, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, "UPDATE users SET totp_secret = $1, totp_last_step = NULL WHERE id = $2 AND NOT totp_enabled", secret, user_id)
+		if err != nil {
+			r.lg.ErrorCtx(ctx, "func setTOTPSecret sql query failed")
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			r.lg.InfoCtx(ctx, "func setTOTPSecret 2fa already enabled or user not found")
+			return ErrTOTPEnabled
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	r.lg.InfoCtx(ctx, "func setTOTPSecret sql complete")
+	return nil
+}
+
+// EnableTOTP включает 2FA и заменяет коды восстановления одной транзакцией.
+func (r *Repository) EnableTOTP(user_id int, recoveryHashes []string, ctx context.Context) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	err := r.WithTx(ctx, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, "UPDATE users SET totp_enabled = TRUE WHERE id = $1 AND totp_secret IS NOT NULL AND NOT totp_enabled", user_id)
+		if err != nil {
+			r.lg.ErrorCtx(ctx, "func enableTOTP sql query failed")
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			r.lg.InfoCtx(ctx, "func enableTOTP 2fa already enabled or not enrolled")
+			return ErrTOTPEnabled
+		}
+		if _, err := tx.Exec(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", user_id); err != nil {
+			r.lg.ErrorCtx(ctx, "func enableTOTP delete recovery codes failed")
+			return err
+		}
+		for _, hash := range recoveryHashes {
+			if _, err := tx.Exec(ctx, "INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)", user_id, hash); err != nil {
+				r.lg.ErrorCtx(ctx, "func enableTOTP insert recovery code failed")
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	r.lg.InfoCtx(ctx, "func enableTOTP sql complete")
+	return nil
+}
+
+// UseTOTPStep запоминает использованный шаг TOTP и возвращает false, если
+// этот или более поздний шаг уже был использован: один код нельзя предъявить дважды.
+func (r *Repository) UseTOTPStep(user_id int, step int64, ctx context.Context) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	var used bool
+	err := r.WithTx(ctx, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, "UPDATE users SET totp_last_step = $1 WHERE id = $2 AND (totp_last_step IS NULL OR totp_last_step < $1)", step, user_id)
+		if err != nil {
+			r.lg.ErrorCtx(ctx, "func useTOTPStep sql query failed")
+			return err
+		}
+		used = result.RowsAffected() == 1
+		return nil
+	})
+	return used, err
+}
+
+func (r *Repository) UseRecoveryCode(user_id int, codeHash string, ctx context.Context) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	var used bool
+	err := r.WithTx(ctx, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, "UPDATE recovery_codes SET used_at = now() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL", user_id, codeHash)
+		if err != nil {
+			r.lg.ErrorCtx(ctx, "func useRecoveryCode sql query failed")
+			return err
+		}
+		used = result.RowsAffected() > 0
+		return nil
+	})
+	return used, err
+}
@@ -0,0 +1,87 @@
+package storages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Коды SQLSTATE, после которых транзакцию можно безопасно повторить целиком.
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+const (
+	defaultTxRetries = 3
+	txRetryBackoff   = 20 * time.Millisecond
+)
+
+// TxFunc — тело транзакции. При повторе оно вызывается заново, поэтому
+// вне tx может только присваивать результаты.
+type TxFunc func(tx pgx.Tx) error
+
+// WithTx выполняет fn в одной транзакции с настроенным уровнем изоляции.
+// Если fn вернул ошибку, транзакция откатывается и ошибка возвращается как есть.
+// При ошибке сериализации или взаимоблокировке транзакция повторяется
+// не более txRetries раз с нарастающей паузой.
+func (r *Repository) WithTx(ctx context.Context, fn TxFunc) error {
+	for attempt := 1; ; attempt++ {
+		err := r.runTx(ctx, fn)
+		if err == nil || !retryableTx(err) || attempt > r.txRetries {
+			return err
+		}
+		r.lg.WarnCtx(ctx, fmt.Sprintf("transaction attempt %d failed, retrying: %v", attempt, err))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * txRetryBackoff):
+		}
+	}
+}
+
+func (r *Repository) runTx(ctx context.Context, fn TxFunc) error {
+	tx, err := r.db.BeginTx(ctx, r.txOptions)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("begin transaction failed: %v", err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("commit transaction failed: %v", err))
+		return err
+	}
+	return nil
+}
+
+func retryableTx(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == serializationFailure || pgErr.Code == deadlockDetected
+}
+
+// txOptions переводит уровень изоляции из конфига в параметры pgx.
+// Пустое значение оставляет уровень по умолчанию для базы.
+func txOptions(isolation string) (pgx.TxOptions, error) {
+	switch isolation {
+	case "":
+		return pgx.TxOptions{}, nil
+	case "read_committed":
+		return pgx.TxOptions{IsoLevel: pgx.ReadCommitted}, nil
+	case "repeatable_read":
+		return pgx.TxOptions{IsoLevel: pgx.RepeatableRead}, nil
+	case "serializable":
+		return pgx.TxOptions{IsoLevel: pgx.Serializable}, nil
+	}
+	return pgx.TxOptions{}, fmt.Errorf("unknown transaction isolation level %q", isolation)
+}
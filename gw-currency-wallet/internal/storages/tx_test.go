@@ -0,0 +1,100 @@
+package storages
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTxRetriesSerializationFailure(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	rep.txOptions = pgx.TxOptions{IsoLevel: pgx.Serializable}
+	rep.txRetries = 2
+
+	for _, code := range []string{serializationFailure, deadlockDetected} {
+		mock.ExpectBeginTx(rep.txOptions)
+		mock.ExpectExec(`UPDATE users`).WillReturnError(&pgconn.PgError{Code: code})
+		mock.ExpectRollback()
+	}
+	mock.ExpectBeginTx(rep.txOptions)
+	mock.ExpectExec(`UPDATE users`).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	calls := 0
+	err := rep.WithTx(context.Background(), func(tx pgx.Tx) error {
+		calls++
+		_, err := tx.Exec(context.Background(), "UPDATE users SET status = 'active'")
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithTxGivesUpAfterMaxRetries(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	rep.txRetries = 1
+
+	for i := 0; i < 2; i++ {
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE users`).WillReturnError(&pgconn.PgError{Code: serializationFailure})
+		mock.ExpectRollback()
+	}
+
+	err := rep.WithTx(context.Background(), func(tx pgx.Tx) error {
+		_, err := tx.Exec(context.Background(), "UPDATE users SET status = 'active'")
+		return err
+	})
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	assert.Equal(t, serializationFailure, pgErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithTxDoesNotRetryOtherErrors(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	rep.txRetries = 3
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	calls := 0
+	err := rep.WithTx(context.Background(), func(tx pgx.Tx) error {
+		calls++
+		return ErrWithdraw
+	})
+	assert.Equal(t, ErrWithdraw, err)
+	assert.Equal(t, 1, calls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithTxBeginFailure(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	mock.ExpectBegin().WillReturnError(errors.New("pool closed"))
+
+	err := rep.WithTx(context.Background(), func(tx pgx.Tx) error {
+		t.Fatal("fn must not run without a transaction")
+		return nil
+	})
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTxOptions(t *testing.T) {
+	opts, err := txOptions("serializable")
+	require.NoError(t, err)
+	assert.Equal(t, pgx.Serializable, opts.IsoLevel)
+
+	opts, err = txOptions("")
+	require.NoError(t, err)
+	assert.Equal(t, pgx.TxOptions{}, opts)
+
+	_, err = txOptions("snapshot")
+	assert.Error(t, err)
+}
@@ -0,0 +1,230 @@
+package storages
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Владелец подписки owner — id пользователя или 0 для подписок партнеров,
+// которые в базе хранятся с user_id IS NULL.
+
+const (
+	webhookColumns  = "s.id, s.user_id, s.url, s.event_types, s.active, s.created_at"
+	deliveryColumns = "d.id, d.subscription_id, d.event_id, e.type, d.status, d.attempts, d.next_attempt_at, d.last_status, d.last_error, d.delivered_at, d.created_at"
+)
+
+func (r *Repository) CreateWebhook(ctx context.Context, sub Webhook) (Webhook, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	owner := 0
+	if sub.UserID != nil {
+		owner = *sub.UserID
+	}
+	res, err := scanWebhook(r.db.QueryRow(ctx,
+		"INSERT INTO webhook_subscriptions AS s (user_id, url, secret, event_types) VALUES (NULLIF($1, 0), $2, $3, $4) RETURNING "+webhookColumns,
+		owner, sub.URL, sub.Secret, sub.EventTypes))
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func createWebhook sql query failed: %v", err))
+		return Webhook{}, err
+	}
+	res.Secret = sub.Secret
+	r.lg.InfoCtx(ctx, fmt.Sprintf("func createWebhook webhook %d created", res.ID))
+	return res, nil
+}
+
+func (r *Repository) ListWebhooks(ctx context.Context, owner int) ([]Webhook, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	rows, err := r.db.Query(ctx,
+		"SELECT "+webhookColumns+" FROM webhook_subscriptions s WHERE s.user_id IS NOT DISTINCT FROM NULLIF($1, 0) ORDER BY s.id", owner)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func listWebhooks sql query failed: %v", err))
+		return nil, err
+	}
+	res, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Webhook, error) {
+		return scanWebhook(row)
+	})
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func listWebhooks scan errors: %v", err))
+		return nil, err
+	}
+	return res, nil
+}
+
+// DeleteWebhook удаляет подписку вместе с историей ее доставок.
+func (r *Repository) DeleteWebhook(ctx context.Context, owner int, id int64) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result, err := r.db.Exec(ctx,
+		"DELETE FROM webhook_subscriptions WHERE id = $1 AND user_id IS NOT DISTINCT FROM NULLIF($2, 0)", id, owner)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func deleteWebhook sql query failed: %v", err))
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNoWebhook
+	}
+	r.lg.InfoCtx(ctx, fmt.Sprintf("func deleteWebhook webhook %d deleted", id))
+	return nil
+}
+
+// ListWebhookDeliveries возвращает последние limit доставок подписки,
+// пустой status — в любом состоянии.
+func (r *Repository) ListWebhookDeliveries(ctx context.Context, owner int, id int64, status string, limit int) ([]WebhookDelivery, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	var exists bool
+	err := r.db.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM webhook_subscriptions WHERE id = $1 AND user_id IS NOT DISTINCT FROM NULLIF($2, 0))",
+		id, owner).Scan(&exists)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func listWebhookDeliveries sql query failed: %v", err))
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNoWebhook
+	}
+	rows, err := r.db.Query(ctx,
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries d JOIN outbox_events e ON e.id = d.event_id WHERE d.subscription_id = $1 AND ($2 = '' OR d.status = $2) ORDER BY d.id DESC LIMIT $3",
+		id, status, limit)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func listWebhookDeliveries sql query failed: %v", err))
+		return nil, err
+	}
+	res, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (WebhookDelivery, error) {
+		return scanDelivery(row)
+	})
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func listWebhookDeliveries scan errors: %v", err))
+		return nil, err
+	}
+	return res, nil
+}
+
+// ReplayDelivery ставит доставку в очередь заново с обнулением попыток, в том
+// числе уже доставленную или отправленную в dead.
+func (r *Repository) ReplayDelivery(ctx context.Context, owner int, id int64) (WebhookDelivery, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	res, err := scanDelivery(r.db.QueryRow(ctx,
+		`UPDATE webhook_deliveries d SET status = $1, attempts = 0, next_attempt_at = now(), delivered_at = NULL
+		FROM webhook_subscriptions s, outbox_events e
+		WHERE d.id = $2 AND s.id = d.subscription_id AND s.user_id IS NOT DISTINCT FROM NULLIF($3, 0) AND e.id = d.event_id
+		RETURNING `+deliveryColumns,
+		DeliveryPending, id, owner))
+	if err == pgx.ErrNoRows {
+		return WebhookDelivery{}, ErrNoDelivery
+	}
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func replayDelivery sql query failed: %v", err))
+		return WebhookDelivery{}, err
+	}
+	r.lg.InfoCtx(ctx, fmt.Sprintf("func replayDelivery delivery %d queued", id))
+	return res, nil
+}
+
+// FanOutEvents создает доставки для до limit еще не разосланных событий
+// outbox по всем подходящим активным подпискам и отмечает события
+// разосланными. Внутренние события никому не рассылаются. Возвращает число обработанных событий. События, взятые
+// другой репликой, пропускаются.
+func (r *Repository) FanOutEvents(ctx context.Context, limit int) (int64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result, err := r.db.Exec(ctx,
+		`WITH ev AS (
+			SELECT id, user_id, type FROM outbox_events WHERE dispatched_at IS NULL ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED
+		), ins AS (
+			INSERT INTO webhook_deliveries (subscription_id, event_id)
+			SELECT s.id, ev.id FROM ev JOIN webhook_subscriptions s ON s.active
+				AND (s.user_id IS NULL OR s.user_id = ev.user_id)
+				AND (cardinality(s.event_types) = 0 OR ev.type = ANY(s.event_types))
+			WHERE ev.type <> ALL($2)
+			ON CONFLICT DO NOTHING
+		)
+		UPDATE outbox_events o SET dispatched_at = now() FROM ev WHERE o.id = ev.id`,
+		limit, internalEventTypes)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func fanOutEvents sql query failed: %v", err))
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// ClaimDeliveries берет до limit доставок, время попытки которых наступило, и
+// откладывает их следующую попытку на lease. Пока попытка идет, доставку не
+// возьмет другая реплика, а если процесс упадет, доставка повторится после lease.
+func (r *Repository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	rows, err := r.db.Query(ctx,
+		`UPDATE webhook_deliveries d SET next_attempt_at = now() + $1 * interval '1 millisecond'
+		FROM webhook_subscriptions s, outbox_events e
+		WHERE d.id IN (
+			SELECT id FROM webhook_deliveries WHERE status = $2 AND next_attempt_at <= now()
+			ORDER BY next_attempt_at LIMIT $3 FOR UPDATE SKIP LOCKED
+		) AND s.id = d.subscription_id AND e.id = d.event_id
+		RETURNING `+deliveryColumns+`, e.created_at, s.url, s.secret, e.payload`,
+		lease.Milliseconds(), DeliveryPending, limit)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func claimDeliveries sql query failed: %v", err))
+		return nil, err
+	}
+	res, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (WebhookDelivery, error) {
+		var d WebhookDelivery
+		err := row.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastStatus, &d.LastError, &d.DeliveredAt, &d.CreatedAt, &d.EventCreatedAt, &d.URL, &d.Secret, &d.Payload)
+		return d, err
+	})
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func claimDeliveries scan errors: %v", err))
+		return nil, err
+	}
+	return res, nil
+}
+
+// MarkDelivered отмечает доставку успешной; status — код ответа получателя.
+func (r *Repository) MarkDelivered(ctx context.Context, id int64, status int) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	_, err := r.db.Exec(ctx,
+		"UPDATE webhook_deliveries SET status = $1, attempts = attempts + 1, last_status = $2, last_error = '', delivered_at = now() WHERE id = $3",
+		DeliveryDelivered, status, id)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func markDelivered sql query failed: %v", err))
+	}
+	return err
+}
+
+// MarkDeliveryFailed записывает неудачную попытку: status — код ответа
+// получателя или 0, если ответа не было. Следующая попытка будет в retryAt,
+// nil отправляет доставку в dead.
+func (r *Repository) MarkDeliveryFailed(ctx context.Context, id int64, status int, reason string, retryAt *time.Time) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	_, err := r.db.Exec(ctx,
+		`UPDATE webhook_deliveries SET attempts = attempts + 1, last_status = NULLIF($1, 0), last_error = $2,
+		status = CASE WHEN $3::timestamptz IS NULL THEN $4 ELSE status END,
+		next_attempt_at = COALESCE($3, next_attempt_at)
+		WHERE id = $5`,
+		status, reason, retryAt, DeliveryDead, id)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("func markDeliveryFailed sql query failed: %v", err))
+	}
+	return err
+}
+
+func scanWebhook(row pgx.Row) (Webhook, error) {
+	var s Webhook
+	err := row.Scan(&s.ID, &s.UserID, &s.URL, &s.EventTypes, &s.Active, &s.CreatedAt)
+	return s, err
+}
+
+func scanDelivery(row pgx.Row) (WebhookDelivery, error) {
+	var d WebhookDelivery
+	err := row.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastStatus, &d.LastError, &d.DeliveredAt, &d.CreatedAt)
+	return d, err
+}
@@ -0,0 +1,46 @@
+package storages
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFanOutEventsMarksDispatched(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	mock.ExpectExec(`WITH ev AS \(\s*SELECT id, user_id, type FROM outbox_events WHERE dispatched_at IS NULL .* FOR UPDATE SKIP LOCKED.* WHERE ev.type <> ALL\(\$2\)`).
+		WithArgs(100, []string{EventUserRegistered}).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 3))
+
+	n, err := rep.FanOutEvents(context.Background(), 100)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMarkDeliveryFailedDeadLetters(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	var retryAt *time.Time
+	mock.ExpectExec(`UPDATE webhook_deliveries SET attempts = attempts \+ 1`).
+		WithArgs(502, "receiver responded 502", retryAt, DeliveryDead, int64(7)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	err := rep.MarkDeliveryFailed(context.Background(), 7, 502, "receiver responded 502", nil)
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReplayForeignDelivery(t *testing.T) {
+	rep, mock := newMockRepository(t)
+	mock.ExpectQuery(`UPDATE webhook_deliveries d SET status = \$1, attempts = 0, .* s.user_id IS NOT DISTINCT FROM NULLIF\(\$3, 0\)`).
+		WithArgs(DeliveryPending, int64(7), 2).
+		WillReturnRows(mock.NewRows([]string{"id"}))
+
+	_, err := rep.ReplayDelivery(context.Background(), 2, 7)
+	assert.Equal(t, ErrNoDelivery, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
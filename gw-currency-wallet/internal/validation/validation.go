@@ -0,0 +1,115 @@
+package validation
+
+import (
+	"bufio"
+	"net/mail"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gw-currency-wallet/internal/config"
+)
+
+const (
+	defaultMinPassword = 8
+	// maxPassword — bcrypt учитывает только первые 72 байта пароля.
+	maxPassword = 72
+	minUsername = 3
+	maxUsername = 32
+	maxEmail    = 255
+)
+
+var usernameRe = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicy проверяет пароли по длине и по локальному списку утекших паролей.
+type PasswordPolicy struct {
+	minLength int
+	breached  map[string]struct{}
+}
+
+// NewPasswordPolicy загружает список утекших паролей из cfg.Breached_list
+// (по одному паролю в строке). Пустой путь отключает эту проверку.
+func NewPasswordPolicy(cfg config.PasswordPolicy) (*PasswordPolicy, error) {
+	p := new(PasswordPolicy)
+	p.minLength = cfg.Min_length
+	if p.minLength <= 0 {
+		p.minLength = defaultMinPassword
+	}
+	p.breached = make(map[string]struct{})
+	if cfg.Breached_list == "" {
+		return p, nil
+	}
+	file, err := os.Open(cfg.Breached_list)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			p.breached[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Password возвращает нарушения политики или nil. Нулевая политика проверяет только длину.
+func (p *PasswordPolicy) Password(password, username string) []FieldError {
+	minLength := defaultMinPassword
+	if p != nil {
+		minLength = p.minLength
+	}
+	switch {
+	case utf8.RuneCountInString(password) < minLength:
+		return []FieldError{{Field: "password", Message: "must be at least " + strconv.Itoa(minLength) + " characters"}}
+	case len(password) > maxPassword:
+		return []FieldError{{Field: "password", Message: "must be at most " + strconv.Itoa(maxPassword) + " bytes"}}
+	case username != "" && strings.EqualFold(password, username):
+		return []FieldError{{Field: "password", Message: "must not match the username"}}
+	}
+	if p != nil {
+		if _, ok := p.breached[strings.ToLower(password)]; ok {
+			return []FieldError{{Field: "password", Message: "is in a list of breached passwords"}}
+		}
+	}
+	return nil
+}
+
+// NormalizeEmail обрезает пробелы и приводит адрес к нижнему регистру.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Email проверяет, что адрес — это ровно один адрес без имени и угловых скобок.
+func Email(email string) []FieldError {
+	if email == "" {
+		return []FieldError{{Field: "email", Message: "is required"}}
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || len(email) > maxEmail || !strings.Contains(email[strings.LastIndex(email, "@"):], ".") {
+		return []FieldError{{Field: "email", Message: "is not a valid email address"}}
+	}
+	return nil
+}
+
+func Username(username string) []FieldError {
+	switch {
+	case username == "":
+		return []FieldError{{Field: "username", Message: "is required"}}
+	case len(username) < minUsername || len(username) > maxUsername:
+		return []FieldError{{Field: "username", Message: "must be " + strconv.Itoa(minUsername) + "-" + strconv.Itoa(maxUsername) + " characters"}}
+	case !usernameRe.MatchString(username):
+		return []FieldError{{Field: "username", Message: "may contain only letters, digits, '_', '.' and '-'"}}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package validation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gw-currency-wallet/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "breached.txt")
+	assert.NoError(t, os.WriteFile(list, []byte("Password123\nletmein\n"), 0644))
+	p, err := NewPasswordPolicy(config.PasswordPolicy{Min_length: 10, Breached_list: list})
+	assert.NoError(t, err)
+
+	assert.Empty(t, p.Password("correct horse battery", "alice"))
+	assert.Equal(t, "must be at least 10 characters", p.Password("short", "alice")[0].Message)
+	assert.Equal(t, "is in a list of breached passwords", p.Password("PASSWORD123", "alice")[0].Message)
+	assert.Equal(t, "must not match the username", p.Password("alice_long_name", "Alice_Long_Name")[0].Message)
+
+	_, err = NewPasswordPolicy(config.PasswordPolicy{Breached_list: filepath.Join(t.TempDir(), "missing.txt")})
+	assert.Error(t, err)
+}
+
+func TestEmail(t *testing.T) {
+	assert.Equal(t, "user@example.com", NormalizeEmail("  User@Example.COM "))
+	assert.Empty(t, Email("user@example.com"))
+	for _, email := range []string{"", "user", "user@localhost", "User <user@example.com>", "a@b.c d"} {
+		assert.NotEmpty(t, Email(email), email)
+	}
+}
+
+func TestUsername(t *testing.T) {
+	assert.Empty(t, Username("new.user_1"))
+	for _, username := range []string{"", "ab", "user name", "юзер"} {
+		assert.NotEmpty(t, Username(username), username)
+	}
+}
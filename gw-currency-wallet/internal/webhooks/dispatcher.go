@@ -0,0 +1,187 @@
+// Package webhooks доставляет события кошелька из outbox подписчикам
+// HTTP-запросами с подписью HMAC, повторами и отправкой в dead.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gw-currency-wallet/internal/config"
+	"gw-currency-wallet/internal/logger"
+	"gw-currency-wallet/internal/storages"
+)
+
+const (
+	defaultPollInterval = 5 * time.Second
+	defaultBatchSize    = 100
+	defaultTimeout      = 5 * time.Second
+	defaultMaxAttempts  = 8
+	defaultBackoff      = 30 * time.Second
+	defaultBackoffMax   = time.Hour
+)
+
+// Store — операции репозитория, которые нужны рассылке.
+type Store interface {
+	FanOutEvents(ctx context.Context, limit int) (int64, error)
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]storages.WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, id int64, status int) error
+	MarkDeliveryFailed(ctx context.Context, id int64, status int, reason string, retryAt *time.Time) error
+}
+
+// Envelope — тело запроса с вебхуком. ID — id события: при повторах и ручной
+// переотправке он не меняется, по нему получатель отбрасывает дубли.
+type Envelope struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type Dispatcher struct {
+	store       Store
+	client      *http.Client
+	lg          logger.Logger
+	interval    time.Duration
+	batch       int
+	maxAttempts int
+	backoff     time.Duration
+	backoffMax  time.Duration
+	now         func() time.Time
+}
+
+func New(store Store, lg logger.Logger, cfg config.Webhooks) *Dispatcher {
+	timeout := time.Duration(cfg.Timeout_ms) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	d := &Dispatcher{
+		store:       store,
+		client:      newClient(timeout, PublicIP),
+		lg:          lg,
+		interval:    time.Duration(cfg.Poll_interval_sec) * time.Second,
+		batch:       cfg.Batch_size,
+		maxAttempts: cfg.Max_attempts,
+		backoff:     time.Duration(cfg.Backoff_base_sec) * time.Second,
+		backoffMax:  time.Duration(cfg.Backoff_max_sec) * time.Second,
+		now:         time.Now,
+	}
+	if d.interval <= 0 {
+		d.interval = defaultPollInterval
+	}
+	if d.batch <= 0 {
+		d.batch = defaultBatchSize
+	}
+	if d.maxAttempts <= 0 {
+		d.maxAttempts = defaultMaxAttempts
+	}
+	if d.backoff <= 0 {
+		d.backoff = defaultBackoff
+	}
+	if d.backoffMax < d.backoff {
+		d.backoffMax = max(defaultBackoffMax, d.backoff)
+	}
+	return d
+}
+
+// Run раз в interval рассылает новые события и отправляет наступившие доставки.
+// Несколько реплик могут работать одновременно: события и доставки
+// разбираются с SKIP LOCKED.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := d.Dispatch(ctx); err != nil && ctx.Err() == nil {
+			d.lg.WarnCtx(ctx, fmt.Sprintf("webhooks: %v", err))
+		}
+	}
+}
+
+// Dispatch раскладывает события outbox по подпискам и отправляет одну пачку доставок.
+func (d *Dispatcher) Dispatch(ctx context.Context) error {
+	if _, err := d.store.FanOutEvents(ctx, d.batch); err != nil {
+		return err
+	}
+	// Аренда больше таймаута запроса, чтобы доставку не взяли повторно, пока она идет.
+	lease := d.client.Timeout*time.Duration(d.batch) + d.interval
+	due, err := d.store.ClaimDeliveries(ctx, d.batch, lease)
+	if err != nil {
+		return err
+	}
+	for _, dl := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		d.deliver(ctx, dl)
+	}
+	return nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, dl storages.WebhookDelivery) {
+	status, err := d.send(ctx, dl)
+	if err == nil {
+		if err := d.store.MarkDelivered(ctx, dl.ID, status); err != nil {
+			d.lg.ErrorCtx(ctx, fmt.Sprintf("webhooks: delivery %d: %v", dl.ID, err))
+		}
+		return
+	}
+
+	attempt := dl.Attempts + 1
+	var retryAt *time.Time
+	if attempt < d.maxAttempts {
+		at := d.now().Add(d.retryDelay(attempt))
+		retryAt = &at
+		d.lg.WarnCtx(ctx, fmt.Sprintf("webhooks: delivery %d attempt %d failed, retrying at %s: %v", dl.ID, attempt, at.Format(time.RFC3339), err))
+	} else {
+		d.lg.ErrorCtx(ctx, fmt.Sprintf("webhooks: delivery %d is dead after %d attempts: %v", dl.ID, attempt, err))
+	}
+	if err := d.store.MarkDeliveryFailed(ctx, dl.ID, status, err.Error(), retryAt); err != nil {
+		d.lg.ErrorCtx(ctx, fmt.Sprintf("webhooks: delivery %d: %v", dl.ID, err))
+	}
+}
+
+// send отправляет доставку и возвращает код ответа, 0 — если ответа не было.
+// Успех — любой ответ 2xx. Тело ответа не читается: last_error видит
+// владелец подписки, и в нем не должно оказаться ничего, кроме кода.
+func (d *Dispatcher) send(ctx context.Context, dl storages.WebhookDelivery) (int, error) {
+	body, err := json.Marshal(Envelope{ID: dl.EventID, Type: dl.EventType, CreatedAt: dl.EventCreatedAt, Data: dl.Payload})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dl.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, dl.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(dl.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(dl.Secret, d.now(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, nil
+	}
+	return resp.StatusCode, fmt.Errorf("receiver responded %d", resp.StatusCode)
+}
+
+// retryDelay удваивает паузу с каждой неудачной попыткой до backoffMax.
+func (d *Dispatcher) retryDelay(attempt int) time.Duration {
+	delay := d.backoff << (attempt - 1)
+	if delay > d.backoffMax || delay <= 0 {
+		return d.backoffMax
+	}
+	return delay
+}
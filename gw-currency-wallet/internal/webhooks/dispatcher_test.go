@@ -0,0 +1,186 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"gw-currency-wallet/internal/config"
+	"gw-currency-wallet/internal/storages"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) DebugCtx(ctx context.Context, msg string)            {}
+func (nopLogger) InfoCtx(ctx context.Context, msg string)             {}
+func (nopLogger) WarnCtx(ctx context.Context, msg string)             {}
+func (nopLogger) ErrorCtx(ctx context.Context, msg string)            {}
+func (nopLogger) FatalCtx(ctx context.Context, msg string, err error) {}
+
+type failure struct {
+	status  int
+	reason  string
+	retryAt *time.Time
+}
+
+type fakeStore struct {
+	due       []storages.WebhookDelivery
+	fanned    int
+	delivered map[int64]int
+	failed    map[int64]failure
+}
+
+func newFakeStore(due ...storages.WebhookDelivery) *fakeStore {
+	return &fakeStore{due: due, delivered: map[int64]int{}, failed: map[int64]failure{}}
+}
+
+func (f *fakeStore) FanOutEvents(ctx context.Context, limit int) (int64, error) {
+	f.fanned++
+	return 0, nil
+}
+
+func (f *fakeStore) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]storages.WebhookDelivery, error) {
+	due := f.due
+	f.due = nil
+	return due, nil
+}
+
+func (f *fakeStore) MarkDelivered(ctx context.Context, id int64, status int) error {
+	f.delivered[id] = status
+	return nil
+}
+
+func (f *fakeStore) MarkDeliveryFailed(ctx context.Context, id int64, status int, reason string, retryAt *time.Time) error {
+	f.failed[id] = failure{status: status, reason: reason, retryAt: retryAt}
+	return nil
+}
+
+// receiver — получатель вебхуков, проверяющий подпись.
+type receiver struct {
+	mu       sync.Mutex
+	secret   string
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+	verified []error
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	rc.verified = append(rc.verified, Verify(rc.secret, r.Header.Get(SignatureHeader), body, time.Now(), 5*time.Minute))
+	w.WriteHeader(rc.status)
+	io.WriteString(w, "receiver says no\n")
+}
+
+var now = time.Date(2026, 10, 19, 15, 0, 0, 0, time.UTC)
+
+// newTestDispatcher разрешает подключения к loopback, где слушает httptest.
+func newTestDispatcher(store Store) *Dispatcher {
+	d := New(store, nopLogger{}, config.Webhooks{Max_attempts: 3, Backoff_base_sec: 10, Backoff_max_sec: 15})
+	d.client = newClient(d.client.Timeout, func(netip.Addr) bool { return true })
+	d.now = time.Now
+	return d
+}
+
+func delivery(id int64, url string, attempts int) storages.WebhookDelivery {
+	return storages.WebhookDelivery{
+		ID:             id,
+		EventID:        100 + id,
+		EventType:      storages.EventDeposited,
+		Attempts:       attempts,
+		EventCreatedAt: now,
+		URL:            url,
+		Secret:         "whsec_test",
+		Payload:        json.RawMessage(`{"currency":"USD","amount":"50","balance":"150"}`),
+	}
+}
+
+func TestDispatchDeliversSignedEvent(t *testing.T) {
+	rc := &receiver{secret: "whsec_test", status: http.StatusNoContent}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+	store := newFakeStore(delivery(1, srv.URL, 0))
+
+	require.NoError(t, newTestDispatcher(store).Dispatch(context.Background()))
+
+	assert.Equal(t, 1, store.fanned)
+	assert.Equal(t, http.StatusNoContent, store.delivered[1])
+	require.Len(t, rc.requests, 1)
+	assert.NoError(t, rc.verified[0])
+	assert.Equal(t, storages.EventDeposited, rc.requests[0].Header.Get(EventHeader))
+	assert.Equal(t, "1", rc.requests[0].Header.Get(DeliveryHeader))
+
+	var env Envelope
+	require.NoError(t, json.Unmarshal(rc.bodies[0], &env))
+	assert.Equal(t, int64(101), env.ID)
+	assert.Equal(t, storages.EventDeposited, env.Type)
+	assert.JSONEq(t, `{"currency":"USD","amount":"50","balance":"150"}`, string(env.Data))
+}
+
+func TestDispatchRetriesWithBackoff(t *testing.T) {
+	rc := &receiver{secret: "whsec_test", status: http.StatusInternalServerError}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+	store := newFakeStore(delivery(1, srv.URL, 0), delivery(2, srv.URL, 1))
+	d := newTestDispatcher(store)
+	d.now = func() time.Time { return now }
+
+	require.NoError(t, d.Dispatch(context.Background()))
+
+	require.Len(t, store.failed, 2)
+	first := store.failed[1]
+	assert.Equal(t, http.StatusInternalServerError, first.status)
+	assert.Equal(t, "receiver responded 500", first.reason, "response body is not stored")
+	require.NotNil(t, first.retryAt)
+	assert.Equal(t, now.Add(10*time.Second), *first.retryAt)
+	require.NotNil(t, store.failed[2].retryAt)
+	assert.Equal(t, now.Add(15*time.Second), *store.failed[2].retryAt, "backoff is capped")
+}
+
+func TestDispatchDeadLettersAfterMaxAttempts(t *testing.T) {
+	rc := &receiver{secret: "whsec_test", status: http.StatusBadGateway}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+	store := newFakeStore(delivery(1, srv.URL, 2))
+
+	require.NoError(t, newTestDispatcher(store).Dispatch(context.Background()))
+
+	assert.Empty(t, store.delivered)
+	assert.Nil(t, store.failed[1].retryAt)
+}
+
+func TestDispatchUnreachableReceiver(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+	store := newFakeStore(delivery(1, url, 0))
+
+	require.NoError(t, newTestDispatcher(store).Dispatch(context.Background()))
+
+	assert.Equal(t, 0, store.failed[1].status)
+	assert.NotNil(t, store.failed[1].retryAt)
+}
+
+func TestVerifyRejectsTamperedOrStale(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	header := Sign("whsec_test", now, body)
+
+	assert.NoError(t, Verify("whsec_test", header, body, now.Add(time.Minute), 5*time.Minute))
+	assert.Equal(t, ErrSignature, Verify("whsec_other", header, body, now, 5*time.Minute))
+	assert.Equal(t, ErrSignature, Verify("whsec_test", header, []byte(`{"id":2}`), now, 5*time.Minute))
+	assert.Equal(t, ErrSignature, Verify("whsec_test", header, body, now.Add(time.Hour), 5*time.Minute))
+	assert.Equal(t, ErrSignature, Verify("whsec_test", "v1=abc", body, now, 5*time.Minute))
+}
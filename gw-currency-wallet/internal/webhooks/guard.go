@@ -0,0 +1,103 @@
+package webhooks
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrInvalidURL = errors.New("webhook url must be an absolute http or https URL")
+	// ErrForbiddenAddress — адрес получателя ведет во внутреннюю сеть сервиса.
+	ErrForbiddenAddress = errors.New("webhook address is not allowed")
+)
+
+// Сети, куда вебхуки не отправляются, помимо loopback, частных, link-local
+// и multicast адресов, которые проверяет PublicIP.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// PublicIP сообщает, можно ли отправлять вебхук на адрес ip.
+func PublicIP(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL проверяет адрес подписки без обращения к DNS: схема http или
+// https, хост — публичный IP или полное доменное имя. Имена без точки
+// (postgres, app2) и локальные зоны — это хосты внутренней сети. Имя,
+// которое указывает на внутренний адрес, отсекается при подключении.
+func CheckURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return ErrInvalidURL
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if !PublicIP(ip) {
+			return ErrForbiddenAddress
+		}
+		return nil
+	}
+	if !strings.Contains(host, ".") || host == "localhost" {
+		return ErrForbiddenAddress
+	}
+	for _, zone := range []string{".localhost", ".local", ".internal", ".localdomain", ".home.arpa"} {
+		if strings.HasSuffix(host, zone) {
+			return ErrForbiddenAddress
+		}
+	}
+	return nil
+}
+
+// newClient возвращает клиента, который подключается только к адресам, для
+// которых allow вернул true. Проверка идет при каждом подключении уже после
+// разрешения имени, поэтому DNS не подменит адрес после CheckURL. Прокси и
+// переадресации отключены: ответ 3xx считается неудачной доставкой.
+func newClient(timeout time.Duration, allow func(netip.Addr) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addr, err := netip.ParseAddrPort(address)
+			if err != nil || !allow(addr.Addr()) {
+				return ErrForbiddenAddress
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
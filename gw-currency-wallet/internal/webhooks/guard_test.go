@@ -0,0 +1,86 @@
+package webhooks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"gw-currency-wallet/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url string
+		err error
+	}{
+		{url: "https://example.com/hooks"},
+		{url: "http://93.184.216.34:8080/hooks"},
+		{url: "https://[2606:4700::1111]/hooks"},
+		{url: "/hooks", err: ErrInvalidURL},
+		{url: "ftp://example.com/hooks", err: ErrInvalidURL},
+		{url: "http://169.254.169.254/latest/meta-data", err: ErrForbiddenAddress},
+		{url: "http://postgres:5432", err: ErrForbiddenAddress},
+		{url: "http://app2:50052", err: ErrForbiddenAddress},
+		{url: "http://localhost:8080/hooks", err: ErrForbiddenAddress},
+		{url: "http://api.localhost/hooks", err: ErrForbiddenAddress},
+		{url: "http://127.0.0.1/hooks", err: ErrForbiddenAddress},
+		{url: "http://10.0.0.5/hooks", err: ErrForbiddenAddress},
+		{url: "http://172.18.0.3/hooks", err: ErrForbiddenAddress},
+		{url: "http://192.168.1.1/hooks", err: ErrForbiddenAddress},
+		{url: "http://100.64.0.1/hooks", err: ErrForbiddenAddress},
+		{url: "http://0.0.0.0/hooks", err: ErrForbiddenAddress},
+		{url: "http://[::1]/hooks", err: ErrForbiddenAddress},
+		{url: "http://[fe80::1]/hooks", err: ErrForbiddenAddress},
+		{url: "http://[fd00::1]/hooks", err: ErrForbiddenAddress},
+		{url: "http://[::ffff:127.0.0.1]/hooks", err: ErrForbiddenAddress},
+		{url: "http://metadata.google.internal/", err: ErrForbiddenAddress},
+		{url: "http://printer.local/", err: ErrForbiddenAddress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			assert.Equal(t, tt.err, CheckURL(tt.url))
+		})
+	}
+}
+
+func TestPublicIP(t *testing.T) {
+	assert.True(t, PublicIP(netip.MustParseAddr("8.8.8.8")))
+	assert.False(t, PublicIP(netip.MustParseAddr("169.254.169.254")))
+	assert.False(t, PublicIP(netip.MustParseAddr("::ffff:10.1.2.3")))
+	assert.False(t, PublicIP(netip.Addr{}))
+}
+
+// Имя может указывать на внутренний адрес, поэтому адрес проверяется еще раз
+// при подключении, и запрос до получателя не доходит.
+func TestDispatchRefusesInternalAddress(t *testing.T) {
+	rc := &receiver{secret: "whsec_test", status: http.StatusNoContent}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+	store := newFakeStore(delivery(1, srv.URL, 0))
+	d := New(store, nopLogger{}, config.Webhooks{})
+
+	require.NoError(t, d.Dispatch(context.Background()))
+
+	assert.Empty(t, rc.requests)
+	assert.Empty(t, store.delivered)
+	assert.Contains(t, store.failed[1].reason, ErrForbiddenAddress.Error())
+}
+
+func TestDispatchDoesNotFollowRedirects(t *testing.T) {
+	internal := &receiver{secret: "whsec_test", status: http.StatusNoContent}
+	target := httptest.NewServer(internal)
+	defer target.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer redirect.Close()
+	store := newFakeStore(delivery(1, redirect.URL, 0))
+
+	require.NoError(t, newTestDispatcher(store).Dispatch(context.Background()))
+
+	assert.Empty(t, internal.requests)
+	assert.Equal(t, http.StatusTemporaryRedirect, store.failed[1].status)
+}
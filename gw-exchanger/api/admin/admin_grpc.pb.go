@@ -0,0 +1,291 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: api/admin/admin.proto
+
+package admin
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	AdminService_AddCurrency_FullMethodName        = "/exchange.admin.AdminService/AddCurrency"
+	AdminService_DisableCurrency_FullMethodName    = "/exchange.admin.AdminService/DisableCurrency"
+	AdminService_SetRateOverride_FullMethodName    = "/exchange.admin.AdminService/SetRateOverride"
+	AdminService_RemoveRateOverride_FullMethodName = "/exchange.admin.AdminService/RemoveRateOverride"
+	AdminService_ListRateOverrides_FullMethodName  = "/exchange.admin.AdminService/ListRateOverrides"
+)
+
+// AdminServiceClient is the client API for AdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// AdminService управляет справочником валют и ручными курсами.
+// Курсы задаются относительно USD, как и данные поставщика.
+type AdminServiceClient interface {
+	// AddCurrency добавляет валюту или включает ранее отключенную с новым курсом.
+	AddCurrency(ctx context.Context, in *AddCurrencyRequest, opts ...grpc.CallOption) (*Currency, error)
+	// DisableCurrency убирает валюту из ответов ExchangeService.
+	DisableCurrency(ctx context.Context, in *CurrencyCode, opts ...grpc.CallOption) (*Currency, error)
+	// SetRateOverride закрепляет курс валюты до expires_at. Пока ручной курс
+	// действует, он важнее данных поставщика.
+	SetRateOverride(ctx context.Context, in *SetRateOverrideRequest, opts ...grpc.CallOption) (*RateOverride, error)
+	// RemoveRateOverride досрочно снимает ручной курс.
+	RemoveRateOverride(ctx context.Context, in *RateOverrideId, opts ...grpc.CallOption) (*RateOverride, error)
+	// ListRateOverrides возвращает действующие ручные курсы.
+	ListRateOverrides(ctx context.Context, in *ListRateOverridesRequest, opts ...grpc.CallOption) (*ListRateOverridesResponse, error)
+}
+
+type adminServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminServiceClient(cc grpc.ClientConnInterface) AdminServiceClient {
+	return &adminServiceClient{cc}
+}
+
+func (c *adminServiceClient) AddCurrency(ctx context.Context, in *AddCurrencyRequest, opts ...grpc.CallOption) (*Currency, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Currency)
+	err := c.cc.Invoke(ctx, AdminService_AddCurrency_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) DisableCurrency(ctx context.Context, in *CurrencyCode, opts ...grpc.CallOption) (*Currency, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Currency)
+	err := c.cc.Invoke(ctx, AdminService_DisableCurrency_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) SetRateOverride(ctx context.Context, in *SetRateOverrideRequest, opts ...grpc.CallOption) (*RateOverride, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RateOverride)
+	err := c.cc.Invoke(ctx, AdminService_SetRateOverride_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) RemoveRateOverride(ctx context.Context, in *RateOverrideId, opts ...grpc.CallOption) (*RateOverride, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RateOverride)
+	err := c.cc.Invoke(ctx, AdminService_RemoveRateOverride_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) ListRateOverrides(ctx context.Context, in *ListRateOverridesRequest, opts ...grpc.CallOption) (*ListRateOverridesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListRateOverridesResponse)
+	err := c.cc.Invoke(ctx, AdminService_ListRateOverrides_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServiceServer is the server API for AdminService service.
+// All implementations must embed UnimplementedAdminServiceServer
+// for forward compatibility.
+//
+// AdminService управляет справочником валют и ручными курсами.
+// Курсы задаются относительно USD, как и данные поставщика.
+type AdminServiceServer interface {
+	// AddCurrency добавляет валюту или включает ранее отключенную с новым курсом.
+	AddCurrency(context.Context, *AddCurrencyRequest) (*Currency, error)
+	// DisableCurrency убирает валюту из ответов ExchangeService.
+	DisableCurrency(context.Context, *CurrencyCode) (*Currency, error)
+	// SetRateOverride закрепляет курс валюты до expires_at. Пока ручной курс
+	// действует, он важнее данных поставщика.
+	SetRateOverride(context.Context, *SetRateOverrideRequest) (*RateOverride, error)
+	// RemoveRateOverride досрочно снимает ручной курс.
+	RemoveRateOverride(context.Context, *RateOverrideId) (*RateOverride, error)
+	// ListRateOverrides возвращает действующие ручные курсы.
+	ListRateOverrides(context.Context, *ListRateOverridesRequest) (*ListRateOverridesResponse, error)
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+// UnimplementedAdminServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAdminServiceServer struct{}
+
+func (UnimplementedAdminServiceServer) AddCurrency(context.Context, *AddCurrencyRequest) (*Currency, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddCurrency not implemented")
+}
+func (UnimplementedAdminServiceServer) DisableCurrency(context.Context, *CurrencyCode) (*Currency, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisableCurrency not implemented")
+}
+func (UnimplementedAdminServiceServer) SetRateOverride(context.Context, *SetRateOverrideRequest) (*RateOverride, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetRateOverride not implemented")
+}
+func (UnimplementedAdminServiceServer) RemoveRateOverride(context.Context, *RateOverrideId) (*RateOverride, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveRateOverride not implemented")
+}
+func (UnimplementedAdminServiceServer) ListRateOverrides(context.Context, *ListRateOverridesRequest) (*ListRateOverridesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRateOverrides not implemented")
+}
+func (UnimplementedAdminServiceServer) mustEmbedUnimplementedAdminServiceServer() {}
+func (UnimplementedAdminServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeAdminServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServiceServer will
+// result in compilation errors.
+type UnsafeAdminServiceServer interface {
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+func RegisterAdminServiceServer(s grpc.ServiceRegistrar, srv AdminServiceServer) {
+	// If the following call pancis, it indicates UnimplementedAdminServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&AdminService_ServiceDesc, srv)
+}
+
+func _AdminService_AddCurrency_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddCurrencyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).AddCurrency(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_AddCurrency_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).AddCurrency(ctx, req.(*AddCurrencyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_DisableCurrency_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CurrencyCode)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).DisableCurrency(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_DisableCurrency_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).DisableCurrency(ctx, req.(*CurrencyCode))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_SetRateOverride_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRateOverrideRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).SetRateOverride(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_SetRateOverride_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).SetRateOverride(ctx, req.(*SetRateOverrideRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_RemoveRateOverride_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RateOverrideId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).RemoveRateOverride(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_RemoveRateOverride_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).RemoveRateOverride(ctx, req.(*RateOverrideId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_ListRateOverrides_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRateOverridesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).ListRateOverrides(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_ListRateOverrides_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).ListRateOverrides(ctx, req.(*ListRateOverridesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AdminService_ServiceDesc is the grpc.ServiceDesc for AdminService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AdminService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "exchange.admin.AdminService",
+	HandlerType: (*AdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddCurrency",
+			Handler:    _AdminService_AddCurrency_Handler,
+		},
+		{
+			MethodName: "DisableCurrency",
+			Handler:    _AdminService_DisableCurrency_Handler,
+		},
+		{
+			MethodName: "SetRateOverride",
+			Handler:    _AdminService_SetRateOverride_Handler,
+		},
+		{
+			MethodName: "RemoveRateOverride",
+			Handler:    _AdminService_RemoveRateOverride_Handler,
+		},
+		{
+			MethodName: "ListRateOverrides",
+			Handler:    _AdminService_ListRateOverrides_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "api/admin/admin.proto",
+}
@@ -0,0 +1,56 @@
+// healthcheck опрашивает grpc.health.v1 локального gw-exchanger и завершается
+// с кодом 0, только если сервис отвечает SERVING. Используется в HEALTHCHECK контейнера.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"gw-exchanger/internal/tlsconfig"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:50052", "адрес gRPC-сервера")
+	service := flag.String("service", "", "имя сервиса, пустое — весь сервер")
+	timeout := flag.Duration("timeout", 3*time.Second, "таймаут проверки")
+	caFile := flag.String("ca", "", "корневой сертификат сервера, включает TLS")
+	certFile := flag.String("cert", "", "сертификат клиента для mTLS")
+	keyFile := flag.String("key", "", "ключ клиента для mTLS")
+	serverName := flag.String("server-name", "", "ожидаемое имя в сертификате сервера")
+	flag.Parse()
+
+	creds := insecure.NewCredentials()
+	if *caFile != "" {
+		certs, err := tlsconfig.New(*certFile, *keyFile, *caFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		creds = certs.ClientCredentials(*serverName)
+	}
+	conn, err := grpc.NewClient(*addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	res, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: *service})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if res.Status != healthpb.HealthCheckResponse_SERVING {
+		fmt.Fprintln(os.Stderr, res.Status)
+		os.Exit(1)
+	}
+}
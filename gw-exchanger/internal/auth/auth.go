@@ -0,0 +1,159 @@
+// Package auth проверяет сервисные токены, которыми клиенты gRPC API
+// представляются в метаданных, и разрешает методы по спискам сервисов.
+package auth
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gw-exchanger/internal/config"
+	"gw-exchanger/internal/logger"
+
+	"github.com/dgrijalva/jwt-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// Audience — получатель, на которого должен быть выписан токен.
+	Audience = "gw-exchanger"
+	// Anyone в списке метода разрешает вызов без токена.
+	Anyone = "*"
+
+	healthService = "/grpc.health.v1.Health/"
+)
+
+type serviceKey struct{}
+
+// ServiceFromContext возвращает имя проверенного вызывающего сервиса.
+func ServiceFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(serviceKey{}).(string)
+	return name, ok
+}
+
+// Authorizer проверяет токен в заголовке authorization: Bearer <JWT HS256>.
+// Subject токена — имя сервиса, подпись проверяется его секретом.
+type Authorizer struct {
+	secrets map[string][]byte
+	allow   map[string][]string
+	lg      logger.Logger
+	now     func() time.Time
+}
+
+func New(cfg config.ServiceAuth, lg logger.Logger) *Authorizer {
+	a := &Authorizer{secrets: make(map[string][]byte), allow: cfg.Allow, lg: lg, now: time.Now}
+	for name, secret := range cfg.Services {
+		// Пустым ключом токен подпишет кто угодно.
+		if secret != "" {
+			a.secrets[name] = []byte(secret)
+		}
+	}
+	return a
+}
+
+func (a *Authorizer) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := a.authorize(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (a *Authorizer) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authorize(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// authorize пропускает проверки здоровья без токена, остальные методы —
+// только если вызывающий есть в списке метода или его сервиса.
+func (a *Authorizer) authorize(ctx context.Context, method string) (context.Context, error) {
+	if strings.HasPrefix(method, healthService) {
+		return ctx, nil
+	}
+	allowed, ok := a.allowed(method)
+	if !ok {
+		a.lg.WarnCtx(ctx, fmt.Sprintf("auth: %s is not in the allow-list", method))
+		return nil, status.Error(codes.PermissionDenied, "method is not allowed")
+	}
+	if slices.Contains(allowed, Anyone) {
+		return ctx, nil
+	}
+	service, err := a.verify(ctx)
+	if err != nil {
+		a.lg.WarnCtx(ctx, fmt.Sprintf("auth: %s: %v", method, err))
+		return nil, status.Error(codes.Unauthenticated, "invalid service token")
+	}
+	if !slices.Contains(allowed, service) {
+		a.lg.WarnCtx(ctx, fmt.Sprintf("auth: %s is not allowed to call %s", service, method))
+		return nil, status.Error(codes.PermissionDenied, "method is not allowed")
+	}
+	return context.WithValue(ctx, serviceKey{}, service), nil
+}
+
+// allowed ищет список для полного имени метода, затем для "/пакет.Сервис/*".
+func (a *Authorizer) allowed(method string) ([]string, bool) {
+	if list, ok := a.allow[method]; ok {
+		return list, true
+	}
+	if i := strings.LastIndex(method, "/"); i > 0 {
+		list, ok := a.allow[method[:i+1]+"*"]
+		return list, ok
+	}
+	return nil, false
+}
+
+func (a *Authorizer) verify(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return "", fmt.Errorf("no token")
+	}
+	tokenStr, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		return "", fmt.Errorf("not a bearer token")
+	}
+
+	claims := new(jwt.StandardClaims)
+	_, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		secret, ok := a.secrets[claims.Subject]
+		if !ok {
+			return nil, fmt.Errorf("unknown service %q", claims.Subject)
+		}
+		return secret, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	now := a.now().Unix()
+	if !claims.VerifyExpiresAt(now, true) {
+		return "", fmt.Errorf("token without expiry or expired")
+	}
+	if !claims.VerifyAudience(Audience, true) {
+		return "", fmt.Errorf("token is not issued for %s", Audience)
+	}
+	return claims.Subject, nil
+}
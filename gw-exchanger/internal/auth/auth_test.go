@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gw-exchanger/internal/config"
+
+	"github.com/dgrijalva/jwt-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) DebugCtx(ctx context.Context, msg string)            {}
+func (nopLogger) InfoCtx(ctx context.Context, msg string)             {}
+func (nopLogger) WarnCtx(ctx context.Context, msg string)             {}
+func (nopLogger) ErrorCtx(ctx context.Context, msg string)            {}
+func (nopLogger) FatalCtx(ctx context.Context, msg string, err error) {}
+
+const (
+	getRates    = "/exchange.ExchangeService/GetExchangeRates"
+	setOverride = "/exchange.admin.AdminService/SetRateOverride"
+	streamRates = "/exchange.rates.RateService/StreamRates"
+	publicInfo  = "/exchange.ExchangeService/GetCurrencies"
+	unlisted    = "/exchange.ExchangeService/DeleteEverything"
+	healthCheck = "/grpc.health.v1.Health/Check"
+)
+
+func newTestAuthorizer() *Authorizer {
+	return New(config.ServiceAuth{
+		Enabled: true,
+		Services: map[string]string{
+			"gw-currency-wallet": "wallet-secret",
+			"gw-treasury":        "treasury-secret",
+			"gw-legacy":          "",
+		},
+		Allow: map[string][]string{
+			getRates:                          {"gw-currency-wallet"},
+			publicInfo:                        {Anyone},
+			"/exchange.admin.AdminService/*":  {"gw-treasury"},
+			"/exchange.rates.RateService/*":   {"gw-currency-wallet", "gw-legacy"},
+			"/exchange.ExchangeService/Other": {"gw-treasury"},
+		},
+	}, nopLogger{})
+}
+
+type tokenOpts struct {
+	method   jwt.SigningMethod
+	subject  string
+	audience string
+	expires  time.Duration
+	secret   interface{}
+}
+
+func token(t *testing.T, o tokenOpts) string {
+	t.Helper()
+	if o.method == nil {
+		o.method = jwt.SigningMethodHS256
+	}
+	if o.audience == "" {
+		o.audience = Audience
+	}
+	claims := jwt.StandardClaims{Subject: o.subject, Audience: o.audience}
+	if o.expires != 0 {
+		claims.ExpiresAt = time.Now().Add(o.expires).Unix()
+	}
+	s, err := jwt.NewWithClaims(o.method, claims).SignedString(o.secret)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return s
+}
+
+func walletToken(t *testing.T) string {
+	return token(t, tokenOpts{subject: "gw-currency-wallet", expires: time.Minute, secret: []byte("wallet-secret")})
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		header  string
+		code    codes.Code
+		service string
+	}{
+		{name: "valid token", method: getRates, header: "Bearer " + walletToken(t), service: "gw-currency-wallet"},
+		{name: "missing token", method: getRates, code: codes.Unauthenticated},
+		{name: "no bearer prefix", method: getRates, header: walletToken(t), code: codes.Unauthenticated},
+		{name: "basic scheme", method: getRates, header: "Basic Z3c6c2VjcmV0", code: codes.Unauthenticated},
+		{name: "malformed token", method: getRates, header: "Bearer not.a.jwt", code: codes.Unauthenticated},
+		{
+			name:   "wrong signature",
+			method: getRates,
+			header: "Bearer " + token(t, tokenOpts{subject: "gw-currency-wallet", expires: time.Minute, secret: []byte("guessed")}),
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "signed with another service secret",
+			method: getRates,
+			header: "Bearer " + token(t, tokenOpts{subject: "gw-currency-wallet", expires: time.Minute, secret: []byte("treasury-secret")}),
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "HS512 instead of HS256",
+			method: getRates,
+			header: "Bearer " + token(t, tokenOpts{method: jwt.SigningMethodHS512, subject: "gw-currency-wallet", expires: time.Minute, secret: []byte("wallet-secret")}),
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "alg none",
+			method: getRates,
+			header: "Bearer " + token(t, tokenOpts{method: jwt.SigningMethodNone, subject: "gw-currency-wallet", expires: time.Minute, secret: jwt.UnsafeAllowNoneSignatureType}),
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "unknown service",
+			method: getRates,
+			header: "Bearer " + token(t, tokenOpts{subject: "gw-intruder", expires: time.Minute, secret: []byte("wallet-secret")}),
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "service with empty secret",
+			method: streamRates,
+			header: "Bearer " + token(t, tokenOpts{subject: "gw-legacy", expires: time.Minute, secret: []byte("")}),
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "expired token",
+			method: getRates,
+			header: "Bearer " + token(t, tokenOpts{subject: "gw-currency-wallet", expires: -time.Minute, secret: []byte("wallet-secret")}),
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "token without expiry",
+			method: getRates,
+			header: "Bearer " + token(t, tokenOpts{subject: "gw-currency-wallet", secret: []byte("wallet-secret")}),
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "wrong audience",
+			method: getRates,
+			header: "Bearer " + token(t, tokenOpts{subject: "gw-currency-wallet", audience: "gw-currency-wallet", expires: time.Minute, secret: []byte("wallet-secret")}),
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "service not on method allow-list",
+			method: getRates,
+			header: "Bearer " + token(t, tokenOpts{subject: "gw-treasury", expires: time.Minute, secret: []byte("treasury-secret")}),
+			code:   codes.PermissionDenied,
+		},
+		{name: "method without entry", method: unlisted, header: "Bearer " + walletToken(t), code: codes.PermissionDenied},
+		{
+			name:    "service wildcard",
+			method:  setOverride,
+			header:  "Bearer " + token(t, tokenOpts{subject: "gw-treasury", expires: time.Minute, secret: []byte("treasury-secret")}),
+			service: "gw-treasury",
+		},
+		{name: "service wildcard, other caller", method: setOverride, header: "Bearer " + walletToken(t), code: codes.PermissionDenied},
+		{name: "service wildcard, stream", method: streamRates, header: "Bearer " + walletToken(t), service: "gw-currency-wallet"},
+		{name: "anyone without token", method: publicInfo},
+		{name: "health check without token", method: healthCheck},
+	}
+
+	a := newTestAuthorizer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.header != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tt.header))
+			}
+			got, err := a.authorize(ctx, tt.method)
+			if code := status.Code(err); code != tt.code {
+				t.Fatalf("code = %v (%v), want %v", code, err, tt.code)
+			}
+			if err != nil {
+				return
+			}
+			service, ok := ServiceFromContext(got)
+			if tt.service == "" {
+				if ok {
+					t.Fatalf("service = %q, want none", service)
+				}
+				return
+			}
+			if service != tt.service {
+				t.Fatalf("service = %q, want %q", service, tt.service)
+			}
+		})
+	}
+}
+
+func TestAllowedPrefersExactMethod(t *testing.T) {
+	a := New(config.ServiceAuth{Allow: map[string][]string{
+		"/exchange.admin.AdminService/*":               {"gw-treasury"},
+		"/exchange.admin.AdminService/ListOverrides":   {"gw-currency-wallet"},
+		"/exchange.admin.AdminService/AddCurrency/*":   {"gw-currency-wallet"},
+		"/exchange.admin.AdminService.Extra/RemoveAll": {"gw-treasury"},
+	}}, nopLogger{})
+
+	if list, ok := a.allowed("/exchange.admin.AdminService/ListOverrides"); !ok || len(list) != 1 || list[0] != "gw-currency-wallet" {
+		t.Fatalf("exact entry: got %v, %v", list, ok)
+	}
+	if list, ok := a.allowed("/exchange.admin.AdminService/AddCurrency"); !ok || list[0] != "gw-treasury" {
+		t.Fatalf("wildcard entry: got %v, %v", list, ok)
+	}
+	if _, ok := a.allowed("/exchange.other.Service/AddCurrency"); ok {
+		t.Fatal("wildcard must not match another service")
+	}
+	if _, ok := a.allowed("malformed"); ok {
+		t.Fatal("method without service must not match")
+	}
+}
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"strings"
 	"sync"
 	"time"
 )
@@ -9,6 +10,7 @@ type Cache struct {
 	mu           sync.RWMutex
 	data         map[string]float32
 	specialRates map[string]float32
+	updatedAt    time.Time // когда data прочитаны из базы
 	ttl          time.Duration
 }
 
@@ -31,6 +33,18 @@ func (c *Cache) GetAll() map[string]float32 {
 	return copyData
 }
 
+// Snapshot возвращает копию всех курсов и время их загрузки.
+func (c *Cache) Snapshot() (map[string]float32, time.Time) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	copyData := make(map[string]float32, len(c.data))
+	for k, v := range c.data {
+		copyData[k] = v
+	}
+	return copyData, c.updatedAt
+}
+
 func (c *Cache) Set(data map[string]float32) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -38,6 +52,7 @@ func (c *Cache) Set(data map[string]float32) {
 	for key, value := range data {
 		c.data[key] = value
 	}
+	c.updatedAt = time.Now()
 
 	time.AfterFunc(c.ttl, func() {
 		c.mu.Lock()
@@ -66,3 +81,26 @@ func (c *Cache) SetSpecificRate(key string, value float32) {
 		delete(c.specialRates, key)
 	})
 }
+
+// Invalidate удаляет закешированные курсы, зависящие от валюты code:
+// снимок всех курсов целиком и пары, в которых она участвует.
+func (c *Cache) Invalidate(code string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.data = make(map[string]float32)
+	for key := range c.specialRates {
+		if strings.HasPrefix(key, code) || strings.HasSuffix(key, code) {
+			delete(c.specialRates, key)
+		}
+	}
+}
+
+// Clear удаляет все закешированные курсы.
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.data = make(map[string]float32)
+	c.specialRates = make(map[string]float32)
+}
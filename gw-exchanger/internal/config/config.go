@@ -2,6 +2,8 @@ package config
 
 import (
 	"io/ioutil"
+	"os"
+	"strings"
 
 	"gw-exchanger/internal/logger"
 
@@ -9,9 +11,50 @@ import (
 )
 
 type ConfigAdr struct {
-	Database_url string `yaml:"database_url"`
-	APP_ADR      string `yaml:"app_adr"`
-	Cache_ttl    int    `yaml:"cache_ttl"`
+	Database_url string      `yaml:"database_url"`
+	APP_ADR      string      `yaml:"app_adr"`
+	Cache_ttl    int         `yaml:"cache_ttl"`
+	Database     Database    `yaml:"database"`
+	Grpc_tls     TLS         `yaml:"grpc_tls"`
+	Service_auth ServiceAuth `yaml:"service_auth"`
+}
+
+// ServiceAuth включает проверку сервисных токенов. Services — секреты подписи
+// по имени сервиса. Allow — кому разрешен метод: ключ — полное имя метода или
+// "/пакет.Сервис/*", значение — имена сервисов, "*" — любой вызывающий, в том
+// числе без токена. Методы без записи запрещены, grpc.health.v1 открыт всегда.
+// Секреты не хранятся в файле: значение из переменной окружения
+// SERVICE_SECRET_<ИМЯ> (например, SERVICE_SECRET_GW_CURRENCY_WALLET) заменяет
+// значение из конфига. Сервис с пустым секретом не пройдет проверку.
+type ServiceAuth struct {
+	Enabled  bool                `yaml:"enabled"`
+	Services map[string]string   `yaml:"services"`
+	Allow    map[string][]string `yaml:"allow"`
+}
+
+// TLS настраивает gRPC-сервер. Если задан Ca_file, клиенты обязаны предъявить
+// подписанный им сертификат. Файлы перечитываются каждые Reload_sec секунд,
+// если изменились.
+type TLS struct {
+	Enabled    bool   `yaml:"enabled"`
+	Cert_file  string `yaml:"cert_file"`
+	Key_file   string `yaml:"key_file"`
+	Ca_file    string `yaml:"ca_file"`
+	Reload_sec int    `yaml:"reload_sec"`
+}
+
+// Database настраивает пул соединений с базой. Нулевые значения оставляют
+// настройки pgx по умолчанию. Connect_attempts — число попыток первого
+// подключения, ноль — без ограничения.
+type Database struct {
+	Max_conns               int32 `yaml:"max_conns"`
+	Min_conns               int32 `yaml:"min_conns"`
+	Max_conn_lifetime_sec   int   `yaml:"max_conn_lifetime_sec"`
+	Max_conn_idle_time_sec  int   `yaml:"max_conn_idle_time_sec"`
+	Health_check_period_sec int   `yaml:"health_check_period_sec"`
+	Connect_attempts        int   `yaml:"connect_attempts"`
+	Connect_backoff_ms      int   `yaml:"connect_backoff_ms"`
+	Connect_backoff_max_ms  int   `yaml:"connect_backoff_max_ms"`
 }
 
 func LoadConfig(filePath string) (*logger.Config, *ConfigAdr, error) {
@@ -27,5 +70,19 @@ func LoadConfig(filePath string) (*logger.Config, *ConfigAdr, error) {
 	if err := yaml.Unmarshal(data, cfgAdr); err != nil {
 		return nil, nil, err
 	}
+	cfgAdr.Service_auth.secretsFromEnv()
 	return cfg, cfgAdr, nil
 }
+
+// SecretEnv — переменная окружения с секретом сервиса name.
+func SecretEnv(name string) string {
+	return "SERVICE_SECRET_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+func (s *ServiceAuth) secretsFromEnv() {
+	for name := range s.Services {
+		if secret, ok := os.LookupEnv(SecretEnv(name)); ok {
+			s.Services[name] = secret
+		}
+	}
+}
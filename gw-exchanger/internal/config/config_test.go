@@ -0,0 +1,24 @@
+package config
+
+import "testing"
+
+func TestServiceSecretsFromEnv(t *testing.T) {
+	t.Setenv("SERVICE_SECRET_GW_CURRENCY_WALLET", "from-env")
+
+	_, cfg, err := LoadConfig("config.yaml")
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if got := cfg.Service_auth.Services["gw-currency-wallet"]; got != "from-env" {
+		t.Fatalf("wallet secret = %q, want value from environment", got)
+	}
+	if got := cfg.Service_auth.Services["gw-treasury"]; got != "" {
+		t.Fatalf("treasury secret = %q, committed config must not contain secrets", got)
+	}
+}
+
+func TestSecretEnv(t *testing.T) {
+	if got := SecretEnv("gw-currency-wallet"); got != "SERVICE_SECRET_GW_CURRENCY_WALLET" {
+		t.Fatalf("SecretEnv = %q", got)
+	}
+}
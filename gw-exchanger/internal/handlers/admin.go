@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"gw-exchanger/api/admin"
+	"gw-exchanger/internal/auth"
+	"gw-exchanger/internal/cache"
+	"gw-exchanger/internal/logger"
+	"gw-exchanger/internal/storages"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// baseCurrency — валюта, относительно которой хранятся курсы. Ее курс всегда 1.
+const baseCurrency = "USD"
+
+var currencyCodeRe = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// AdminServer реализует AdminService поверх репозитория и кеша Server,
+// поэтому изменения сразу видны в ответах ExchangeService.
+type AdminServer struct {
+	admin.UnimplementedAdminServiceServer
+	lg    logger.Logger
+	db    storages.RepositoryInterface
+	cache *cache.Cache
+}
+
+func NewAdminServer(s *Server) *AdminServer {
+	return &AdminServer{lg: s.lg, db: s.db, cache: s.cache}
+}
+
+func (s *AdminServer) AddCurrency(ctx context.Context, in *admin.AddCurrencyRequest) (*admin.Currency, error) {
+	if err := validateCurrency(in.CurrencyCode); err != nil {
+		return nil, err
+	}
+	if err := validateRate(in.Rate); err != nil {
+		return nil, err
+	}
+	if in.CurrencyCode == baseCurrency && in.Rate != 1 {
+		return nil, status.Error(codes.InvalidArgument, "base currency rate must be 1")
+	}
+	c, err := s.db.AddCurrency(ctx, in.CurrencyCode, in.Rate)
+	if err != nil {
+		return nil, adminError(err)
+	}
+	s.cache.Invalidate(c.Code)
+	s.lg.InfoCtx(ctx, fmt.Sprintf("admin: %s added currency %s with rate %v", caller(ctx), c.Code, c.Rate))
+	return toCurrency(c), nil
+}
+
+func (s *AdminServer) DisableCurrency(ctx context.Context, in *admin.CurrencyCode) (*admin.Currency, error) {
+	if err := validateCurrency(in.CurrencyCode); err != nil {
+		return nil, err
+	}
+	if in.CurrencyCode == baseCurrency {
+		return nil, status.Error(codes.InvalidArgument, "base currency cannot be disabled")
+	}
+	c, err := s.db.DisableCurrency(ctx, in.CurrencyCode)
+	if err != nil {
+		return nil, adminError(err)
+	}
+	s.cache.Invalidate(c.Code)
+	s.lg.InfoCtx(ctx, fmt.Sprintf("admin: %s disabled currency %s", caller(ctx), c.Code))
+	return toCurrency(c), nil
+}
+
+func (s *AdminServer) SetRateOverride(ctx context.Context, in *admin.SetRateOverrideRequest) (*admin.RateOverride, error) {
+	if err := validateCurrency(in.CurrencyCode); err != nil {
+		return nil, err
+	}
+	if in.CurrencyCode == baseCurrency {
+		return nil, status.Error(codes.InvalidArgument, "base currency rate cannot be overridden")
+	}
+	if err := validateRate(in.Rate); err != nil {
+		return nil, err
+	}
+	if in.ExpiresAt == nil || !in.ExpiresAt.AsTime().After(time.Now()) {
+		return nil, status.Error(codes.InvalidArgument, "expires_at must be in the future")
+	}
+	reason := strings.TrimSpace(in.Reason)
+	if reason == "" {
+		return nil, status.Error(codes.InvalidArgument, "reason is required")
+	}
+
+	o, err := s.db.SetRateOverride(ctx, storages.RateOverride{
+		Currency:  in.CurrencyCode,
+		Rate:      in.Rate,
+		Reason:    reason,
+		CreatedBy: caller(ctx),
+		ExpiresAt: in.ExpiresAt.AsTime(),
+	})
+	if err != nil {
+		return nil, adminError(err)
+	}
+	s.cache.Invalidate(o.Currency)
+	s.lg.WarnCtx(ctx, fmt.Sprintf("admin: %s pinned %s at %v until %s: %s",
+		o.CreatedBy, o.Currency, o.Rate, o.ExpiresAt.Format(time.RFC3339), o.Reason))
+	return toRateOverride(o), nil
+}
+
+func (s *AdminServer) RemoveRateOverride(ctx context.Context, in *admin.RateOverrideId) (*admin.RateOverride, error) {
+	if in.Id <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	o, err := s.db.RemoveRateOverride(ctx, in.Id)
+	if err != nil {
+		return nil, adminError(err)
+	}
+	s.cache.Invalidate(o.Currency)
+	s.lg.InfoCtx(ctx, fmt.Sprintf("admin: %s removed rate override %d for %s", caller(ctx), o.ID, o.Currency))
+	return toRateOverride(o), nil
+}
+
+func (s *AdminServer) ListRateOverrides(ctx context.Context, in *admin.ListRateOverridesRequest) (*admin.ListRateOverridesResponse, error) {
+	if in.CurrencyCode != "" {
+		if err := validateCurrency(in.CurrencyCode); err != nil {
+			return nil, err
+		}
+	}
+	list, err := s.db.ListRateOverrides(ctx, in.CurrencyCode)
+	if err != nil {
+		return nil, adminError(err)
+	}
+	res := &admin.ListRateOverridesResponse{Overrides: make([]*admin.RateOverride, 0, len(list))}
+	for _, o := range list {
+		res.Overrides = append(res.Overrides, toRateOverride(o))
+	}
+	return res, nil
+}
+
+// RunOverrideExpiry раз в interval снимает истекшие ручные курсы до отмены ctx.
+// Курсы из базы уже не учитывают истекший ручной курс, а снятие нужно, чтобы
+// сбросить кеш и оповестить подписчиков. Срок хранится в базе, поэтому
+// истечение не теряется при перезапуске.
+func (s *Server) RunOverrideExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.expireRateOverrides(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// expireRateOverrides применяет снятие сразу, не дожидаясь уведомления
+// триггера: остальные реплики узнают о нем через LISTEN.
+func (s *Server) expireRateOverrides(ctx context.Context) {
+	expired, err := s.db.ExpireRateOverrides(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.lg.WarnCtx(ctx, fmt.Sprintf("expire rate overrides: %v", err))
+		}
+		return
+	}
+	for _, o := range expired {
+		s.lg.InfoCtx(ctx, fmt.Sprintf("rate override %d for %s expired", o.ID, o.Currency))
+		s.ApplyRateChange(ctx, storages.RateChange{Table: "rate_overrides", Op: "EXPIRE", Currency: o.Currency})
+	}
+}
+
+func validateCurrency(code string) error {
+	if !currencyCodeRe.MatchString(code) {
+		return status.Error(codes.InvalidArgument, "currency_code must be three uppercase letters")
+	}
+	return nil
+}
+
+func validateRate(rate float32) error {
+	if !(rate > 0) || math.IsInf(float64(rate), 0) {
+		return status.Error(codes.InvalidArgument, "rate must be a positive number")
+	}
+	return nil
+}
+
+// caller — имя сервиса из проверенного токена.
+func caller(ctx context.Context) string {
+	if name, ok := auth.ServiceFromContext(ctx); ok {
+		return name
+	}
+	return "anonymous"
+}
+
+func adminError(err error) error {
+	if errors.Is(err, storages.ErrNotFound) {
+		return status.Error(codes.NotFound, "currency or rate override not found")
+	}
+	return status.Error(codes.Internal, "internal error")
+}
+
+func toCurrency(c storages.Currency) *admin.Currency {
+	return &admin.Currency{
+		CurrencyCode: c.Code,
+		Rate:         c.Rate,
+		Enabled:      c.Enabled,
+		UpdatedAt:    timestamppb.New(c.UpdatedAt),
+	}
+}
+
+func toRateOverride(o storages.RateOverride) *admin.RateOverride {
+	return &admin.RateOverride{
+		Id:           o.ID,
+		CurrencyCode: o.Currency,
+		Rate:         o.Rate,
+		Reason:       o.Reason,
+		CreatedBy:    o.CreatedBy,
+		CreatedAt:    timestamppb.New(o.CreatedAt),
+		ExpiresAt:    timestamppb.New(o.ExpiresAt),
+	}
+}
@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"gw-exchanger/api/admin"
+	"gw-exchanger/api/rates"
+	"gw-exchanger/internal/cache"
+	"gw-exchanger/internal/storages"
+
+	exchange "github.com/IlyaBroo/exchange_grpc/exchange"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) DebugCtx(ctx context.Context, msg string)            {}
+func (nopLogger) InfoCtx(ctx context.Context, msg string)             {}
+func (nopLogger) WarnCtx(ctx context.Context, msg string)             {}
+func (nopLogger) ErrorCtx(ctx context.Context, msg string)            {}
+func (nopLogger) FatalCtx(ctx context.Context, msg string, err error) {}
+
+type fakeOverride struct {
+	storages.RateOverride
+	removed bool
+}
+
+// fakeRepo хранит курсы поставщика и ручные курсы в памяти и выбирает
+// действующий курс так же, как effectiveRatesQuery: последний неснятый и
+// неистекший ручной курс важнее курса поставщика. Часы задаются полем now.
+type fakeRepo struct {
+	storages.RepositoryInterface
+
+	mu        sync.Mutex
+	now       time.Time
+	rates     map[string]float32
+	overrides []*fakeOverride
+	err       error
+	getRates  int
+	setCalls  int
+}
+
+func newFakeRepo(rates map[string]float32) *fakeRepo {
+	return &fakeRepo{now: time.Now(), rates: rates}
+}
+
+func (f *fakeRepo) advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+func (f *fakeRepo) active(o *fakeOverride) bool {
+	return !o.removed && o.ExpiresAt.After(f.now)
+}
+
+func (f *fakeRepo) GetRates(ctx context.Context) (map[string]float32, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.getRates++
+	if f.err != nil {
+		return nil, f.err
+	}
+	res := make(map[string]float32, len(f.rates))
+	for code, rate := range f.rates {
+		res[code] = rate
+	}
+	for _, o := range f.overrides {
+		if f.active(o) {
+			res[o.Currency] = o.Rate
+		}
+	}
+	return res, nil
+}
+
+func (f *fakeRepo) SetRateOverride(ctx context.Context, o storages.RateOverride) (storages.RateOverride, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.setCalls++
+	if _, ok := f.rates[o.Currency]; !ok {
+		return storages.RateOverride{}, storages.ErrNotFound
+	}
+	for _, prev := range f.overrides {
+		if prev.Currency == o.Currency {
+			prev.removed = true
+		}
+	}
+	o.ID = int64(len(f.overrides) + 1)
+	o.CreatedAt = f.now
+	f.overrides = append(f.overrides, &fakeOverride{RateOverride: o})
+	return o, nil
+}
+
+func (f *fakeRepo) RemoveRateOverride(ctx context.Context, id int64) (storages.RateOverride, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, o := range f.overrides {
+		if o.ID == id && f.active(o) {
+			o.removed = true
+			return o.RateOverride, nil
+		}
+	}
+	return storages.RateOverride{}, storages.ErrNotFound
+}
+
+func (f *fakeRepo) ExpireRateOverrides(ctx context.Context) ([]storages.RateOverride, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	var res []storages.RateOverride
+	for _, o := range f.overrides {
+		if !o.removed && !o.ExpiresAt.After(f.now) {
+			o.removed = true
+			res = append(res, o.RateOverride)
+		}
+	}
+	return res, nil
+}
+
+func newTestServer(db storages.RepositoryInterface) *Server {
+	return &Server{lg: nopLogger{}, db: db, cache: cache.NewCache(time.Minute), feed: newFeed()}
+}
+
+func providerRates() map[string]float32 {
+	return map[string]float32{"USD": 1, "EUR": 1.1, "RUB": 0.011}
+}
+
+func overrideRequest(code string, rate float32, ttl time.Duration) *admin.SetRateOverrideRequest {
+	return &admin.SetRateOverrideRequest{
+		CurrencyCode: code,
+		Rate:         rate,
+		Reason:       "market halt",
+		ExpiresAt:    timestamppb.New(time.Now().Add(ttl)),
+	}
+}
+
+// nextUpdate возвращает обновление из очереди подписчика или nil, если очередь пуста.
+func nextUpdate(sub *subscription) *rates.RateUpdate {
+	select {
+	case u := <-sub.updates:
+		return u
+	default:
+		return nil
+	}
+}
+
+func currentRate(t *testing.T, s *Server, code string) float32 {
+	t.Helper()
+	res, err := s.GetExchangeRates(context.Background(), &exchange.Empty{})
+	if err != nil {
+		t.Fatalf("GetExchangeRates: %v", err)
+	}
+	return res.Rates[code]
+}
+
+func TestSetRateOverrideValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *admin.SetRateOverrideRequest
+		code codes.Code
+	}{
+		{name: "lowercase code", req: overrideRequest("eur", 1, time.Hour), code: codes.InvalidArgument},
+		{name: "long code", req: overrideRequest("EURO", 1, time.Hour), code: codes.InvalidArgument},
+		{name: "base currency", req: overrideRequest("USD", 1, time.Hour), code: codes.InvalidArgument},
+		{name: "zero rate", req: overrideRequest("EUR", 0, time.Hour), code: codes.InvalidArgument},
+		{name: "negative rate", req: overrideRequest("EUR", -1, time.Hour), code: codes.InvalidArgument},
+		{name: "infinite rate", req: overrideRequest("EUR", float32(math.Inf(1)), time.Hour), code: codes.InvalidArgument},
+		{name: "NaN rate", req: overrideRequest("EUR", float32(math.NaN()), time.Hour), code: codes.InvalidArgument},
+		{name: "expired", req: overrideRequest("EUR", 1, -time.Minute), code: codes.InvalidArgument},
+		{
+			name: "no expiry",
+			req:  &admin.SetRateOverrideRequest{CurrencyCode: "EUR", Rate: 1, Reason: "market halt"},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "blank reason",
+			req:  &admin.SetRateOverrideRequest{CurrencyCode: "EUR", Rate: 1, Reason: "  ", ExpiresAt: timestamppb.New(time.Now().Add(time.Hour))},
+			code: codes.InvalidArgument,
+		},
+		{name: "unknown currency", req: overrideRequest("GBP", 1, time.Hour), code: codes.NotFound},
+		{name: "valid", req: overrideRequest("EUR", 1.2, time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeRepo(providerRates())
+			a := NewAdminServer(newTestServer(db))
+			res, err := a.SetRateOverride(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.code {
+				t.Fatalf("code = %v (%v), want %v", code, err, tt.code)
+			}
+			if tt.code == codes.InvalidArgument && db.setCalls != 0 {
+				t.Fatal("invalid request reached the repository")
+			}
+			if err == nil && (res.CurrencyCode != "EUR" || res.Rate != 1.2 || res.CreatedBy != "anonymous") {
+				t.Fatalf("override = %v", res)
+			}
+		})
+	}
+}
+
+func TestRateOverrideInvalidatesCache(t *testing.T) {
+	db := newFakeRepo(providerRates())
+	s := newTestServer(db)
+	a := NewAdminServer(s)
+	ctx := context.Background()
+
+	if got := currentRate(t, s, "EUR"); got != 1.1 {
+		t.Fatalf("provider rate = %v, want 1.1", got)
+	}
+	s.cache.SetSpecificRate("EURRUB", 100)
+	s.cache.SetSpecificRate("RUBUSD", 0.011)
+
+	o, err := a.SetRateOverride(ctx, overrideRequest("EUR", 1.5, time.Hour))
+	if err != nil {
+		t.Fatalf("SetRateOverride: %v", err)
+	}
+	if len(s.cache.GetAll()) != 0 {
+		t.Fatal("rates snapshot survived the override")
+	}
+	if _, ok := s.cache.GetSpecificRate("EURRUB"); ok {
+		t.Fatal("EURRUB pair survived the override")
+	}
+	if _, ok := s.cache.GetSpecificRate("RUBUSD"); !ok {
+		t.Fatal("RUBUSD pair does not depend on EUR and must stay cached")
+	}
+	if got := currentRate(t, s, "EUR"); got != 1.5 {
+		t.Fatalf("rate with override = %v, want 1.5", got)
+	}
+
+	if _, err := a.RemoveRateOverride(ctx, &admin.RateOverrideId{Id: o.Id}); err != nil {
+		t.Fatalf("RemoveRateOverride: %v", err)
+	}
+	if len(s.cache.GetAll()) != 0 {
+		t.Fatal("rates snapshot survived the removal")
+	}
+	if got := currentRate(t, s, "EUR"); got != 1.1 {
+		t.Fatalf("rate after removal = %v, want 1.1", got)
+	}
+	if _, err := a.RemoveRateOverride(ctx, &admin.RateOverrideId{Id: o.Id}); status.Code(err) != codes.NotFound {
+		t.Fatalf("second removal: %v, want NotFound", err)
+	}
+}
+
+func TestOverrideExpiryRestoresProviderRate(t *testing.T) {
+	db := newFakeRepo(providerRates())
+	s := newTestServer(db)
+	a := NewAdminServer(s)
+	ctx := context.Background()
+
+	if _, err := a.SetRateOverride(ctx, overrideRequest("EUR", 1.5, time.Hour)); err != nil {
+		t.Fatalf("SetRateOverride: %v", err)
+	}
+	if got := currentRate(t, s, "EUR"); got != 1.5 {
+		t.Fatalf("rate with override = %v, want 1.5", got)
+	}
+	sub := s.feed.subscribe([]string{"EUR"})
+
+	s.expireRateOverrides(ctx)
+	if u := nextUpdate(sub); u != nil {
+		t.Fatalf("update before expiry: %v", u)
+	}
+
+	db.advance(2 * time.Hour)
+	s.expireRateOverrides(ctx)
+	u := nextUpdate(sub)
+	if u == nil || u.CurrencyCode != "EUR" || u.Rate != 1.1 || !u.Enabled {
+		t.Fatalf("update after expiry = %v, want EUR 1.1", u)
+	}
+	if got := currentRate(t, s, "EUR"); got != 1.1 {
+		t.Fatalf("rate after expiry = %v, want 1.1", got)
+	}
+
+	s.expireRateOverrides(ctx)
+	if u := nextUpdate(sub); u != nil {
+		t.Fatalf("expired override applied twice: %v", u)
+	}
+}
+
+func TestOverrideExpiryIgnoresReplacedAndRemoved(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("replaced", func(t *testing.T) {
+		db := newFakeRepo(providerRates())
+		s := newTestServer(db)
+		a := NewAdminServer(s)
+		if _, err := a.SetRateOverride(ctx, overrideRequest("EUR", 1.5, time.Hour)); err != nil {
+			t.Fatalf("SetRateOverride: %v", err)
+		}
+		if _, err := a.SetRateOverride(ctx, overrideRequest("EUR", 1.7, 3*time.Hour)); err != nil {
+			t.Fatalf("SetRateOverride: %v", err)
+		}
+		sub := s.feed.subscribe(nil)
+		db.advance(2 * time.Hour)
+		s.expireRateOverrides(ctx)
+		if u := nextUpdate(sub); u != nil {
+			t.Fatalf("replaced override expired: %v", u)
+		}
+		if got := currentRate(t, s, "EUR"); got != 1.7 {
+			t.Fatalf("rate = %v, want the newer override 1.7", got)
+		}
+	})
+
+	t.Run("removed", func(t *testing.T) {
+		db := newFakeRepo(providerRates())
+		s := newTestServer(db)
+		a := NewAdminServer(s)
+		o, err := a.SetRateOverride(ctx, overrideRequest("EUR", 1.5, time.Hour))
+		if err != nil {
+			t.Fatalf("SetRateOverride: %v", err)
+		}
+		if _, err := a.RemoveRateOverride(ctx, &admin.RateOverrideId{Id: o.Id}); err != nil {
+			t.Fatalf("RemoveRateOverride: %v", err)
+		}
+		sub := s.feed.subscribe(nil)
+		db.advance(2 * time.Hour)
+		s.expireRateOverrides(ctx)
+		if u := nextUpdate(sub); u != nil {
+			t.Fatalf("removed override expired: %v", u)
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"slices"
+	"sync"
+
+	"gw-exchanger/api/rates"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// subscriberBuffer — сколько обновлений может ждать отправки одному подписчику.
+const subscriberBuffer = 64
+
+// feed рассылает обновления курсов подписчикам SubscribeRates.
+// Подписчик, не успевающий читать, отключается, чтобы не задерживать остальных.
+type feed struct {
+	mu   sync.Mutex
+	subs map[*subscription]struct{}
+}
+
+type subscription struct {
+	currencies []string
+	updates    chan *rates.RateUpdate
+	done       chan struct{}
+	err        error // причина отключения, читается после закрытия done
+}
+
+func newFeed() *feed {
+	return &feed{subs: make(map[*subscription]struct{})}
+}
+
+func (f *feed) subscribe(currencies []string) *subscription {
+	sub := &subscription{
+		currencies: currencies,
+		updates:    make(chan *rates.RateUpdate, subscriberBuffer),
+		done:       make(chan struct{}),
+	}
+	f.mu.Lock()
+	f.subs[sub] = struct{}{}
+	f.mu.Unlock()
+	return sub
+}
+
+func (f *feed) unsubscribe(sub *subscription) {
+	f.mu.Lock()
+	delete(f.subs, sub)
+	f.mu.Unlock()
+}
+
+func (f *feed) publish(u *rates.RateUpdate) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for sub := range f.subs {
+		if len(sub.currencies) > 0 && !slices.Contains(sub.currencies, u.CurrencyCode) {
+			continue
+		}
+		select {
+		case sub.updates <- u:
+		default:
+			f.drop(sub, status.Error(codes.ResourceExhausted, "subscriber is too slow, resubscribe"))
+		}
+	}
+}
+
+// closeAll отключает всех подписчиков при остановке сервера.
+func (f *feed) closeAll() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for sub := range f.subs {
+		f.drop(sub, status.Error(codes.Unavailable, "server is shutting down"))
+	}
+}
+
+func (f *feed) drop(sub *subscription, err error) {
+	delete(f.subs, sub)
+	sub.err = err
+	close(sub.done)
+}
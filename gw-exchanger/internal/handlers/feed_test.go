@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gw-exchanger/api/rates"
+	"gw-exchanger/internal/storages"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// blockingStream — поток SubscribeRates, который держит первую отправку до
+// закрытия release, как клиент, переставший читать.
+type blockingStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	release chan struct{}
+	sent    chan *rates.RateUpdate
+}
+
+func (s *blockingStream) Context() context.Context { return s.ctx }
+
+func (s *blockingStream) Send(u *rates.RateUpdate) error {
+	<-s.release
+	s.sent <- u
+	return nil
+}
+
+func subscribers(f *feed) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subs)
+}
+
+func update(code string) *rates.RateUpdate {
+	return &rates.RateUpdate{CurrencyCode: code, Rate: 1, Enabled: true}
+}
+
+func TestFeedFiltersByCurrency(t *testing.T) {
+	f := newFeed()
+	eur := f.subscribe([]string{"EUR"})
+	all := f.subscribe(nil)
+
+	f.publish(update("RUB"))
+	f.publish(update("EUR"))
+
+	if u := nextUpdate(eur); u == nil || u.CurrencyCode != "EUR" {
+		t.Fatalf("EUR subscriber got %v", u)
+	}
+	if u := nextUpdate(eur); u != nil {
+		t.Fatalf("EUR subscriber got extra %v", u)
+	}
+	for _, code := range []string{"RUB", "EUR"} {
+		if u := nextUpdate(all); u == nil || u.CurrencyCode != code {
+			t.Fatalf("subscriber to all got %v, want %s", u, code)
+		}
+	}
+}
+
+func TestFeedDropsSlowSubscriber(t *testing.T) {
+	f := newFeed()
+	slow := f.subscribe(nil)
+	fast := f.subscribe(nil)
+
+	for i := 0; i < subscriberBuffer+1; i++ {
+		f.publish(update("EUR"))
+		if nextUpdate(fast) == nil {
+			t.Fatalf("fast subscriber missed update %d", i)
+		}
+	}
+
+	select {
+	case <-slow.done:
+	default:
+		t.Fatal("slow subscriber was not dropped")
+	}
+	if status.Code(slow.err) != codes.ResourceExhausted {
+		t.Fatalf("slow subscriber error = %v, want ResourceExhausted", slow.err)
+	}
+	select {
+	case <-fast.done:
+		t.Fatal("fast subscriber was dropped")
+	default:
+	}
+	if n := subscribers(f); n != 1 {
+		t.Fatalf("subscribers = %d, want 1", n)
+	}
+
+	// Отключенному подписчику больше ничего не отправляется, и повторная
+	// отписка из SubscribeRates безопасна.
+	f.publish(update("EUR"))
+	f.unsubscribe(slow)
+	if nextUpdate(fast) == nil {
+		t.Fatal("fast subscriber missed update after drop")
+	}
+}
+
+func TestSubscribeRatesEndsForSlowClient(t *testing.T) {
+	s := newTestServer(newFakeRepo(providerRates()))
+	r := NewRatesServer(s)
+	stream := &blockingStream{
+		ctx:     context.Background(),
+		release: make(chan struct{}),
+		sent:    make(chan *rates.RateUpdate, 2*subscriberBuffer),
+	}
+	done := make(chan error, 1)
+	go func() { done <- r.SubscribeRates(&rates.SubscribeRatesRequest{Currencies: []string{"EUR"}}, stream) }()
+	waitFor(t, func() bool { return subscribers(s.feed) == 1 })
+
+	// Одно обновление может застрять в Send, остальные переполняют очередь.
+	for i := 0; i < subscriberBuffer+2; i++ {
+		s.feed.publish(update("EUR"))
+	}
+	if n := subscribers(s.feed); n != 0 {
+		t.Fatalf("subscribers = %d, want the slow one dropped", n)
+	}
+	close(stream.release)
+
+	select {
+	case err := <-done:
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Fatalf("SubscribeRates = %v, want ResourceExhausted", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SubscribeRates did not return after the client was dropped")
+	}
+}
+
+func TestSubscribeRatesRejectsBadCurrency(t *testing.T) {
+	s := newTestServer(newFakeRepo(providerRates()))
+	stream := &blockingStream{ctx: context.Background()}
+	err := NewRatesServer(s).SubscribeRates(&rates.SubscribeRatesRequest{Currencies: []string{"EUR", "eur"}}, stream)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("SubscribeRates = %v, want InvalidArgument", err)
+	}
+	if n := subscribers(s.feed); n != 0 {
+		t.Fatalf("subscribers = %d, want 0", n)
+	}
+}
+
+func TestShutdownEndsSubscriptions(t *testing.T) {
+	s := newTestServer(newFakeRepo(providerRates()))
+	stream := &blockingStream{ctx: context.Background()}
+	done := make(chan error, 1)
+	go func() { done <- NewRatesServer(s).SubscribeRates(&rates.SubscribeRatesRequest{}, stream) }()
+	waitFor(t, func() bool { return subscribers(s.feed) == 1 })
+
+	s.Shutdown()
+	select {
+	case err := <-done:
+		if status.Code(err) != codes.Unavailable {
+			t.Fatalf("SubscribeRates = %v, want Unavailable", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SubscribeRates did not return on shutdown")
+	}
+}
+
+func TestApplyRateChange(t *testing.T) {
+	db := newFakeRepo(providerRates())
+	s := newTestServer(db)
+	ctx := context.Background()
+	s.cache.Set(map[string]float32{"USD": 1, "EUR": 0.9, "RUB": 0.011})
+	s.cache.SetSpecificRate("EURUSD", 0.9)
+	sub := s.feed.subscribe(nil)
+
+	s.ApplyRateChange(ctx, storages.RateChange{Table: "currency_rates_usd", Op: "UPDATE", Currency: "EUR"})
+	if u := nextUpdate(sub); u == nil || u.CurrencyCode != "EUR" || u.Rate != 1.1 || !u.Enabled {
+		t.Fatalf("update = %v, want EUR 1.1", u)
+	}
+	if _, ok := s.cache.GetSpecificRate("EURUSD"); ok {
+		t.Fatal("EURUSD survived the change")
+	}
+	if got := s.cache.GetAll()["EUR"]; got != 1.1 {
+		t.Fatalf("cached EUR = %v, want refreshed 1.1", got)
+	}
+
+	// Отключенной валюты нет среди действующих курсов.
+	delete(db.rates, "RUB")
+	s.ApplyRateChange(ctx, storages.RateChange{Table: "currency_rates_usd", Op: "UPDATE", Currency: "RUB"})
+	if u := nextUpdate(sub); u == nil || u.CurrencyCode != "RUB" || u.Enabled {
+		t.Fatalf("update = %v, want RUB disabled", u)
+	}
+}
+
+func TestApplyRateChangeKeepsSubscribersOnDBError(t *testing.T) {
+	db := newFakeRepo(providerRates())
+	db.err = errors.New("connection refused")
+	s := newTestServer(db)
+	s.cache.SetSpecificRate("EURUSD", 0.9)
+	sub := s.feed.subscribe(nil)
+
+	s.ApplyRateChange(context.Background(), storages.RateChange{Table: "currency_rates_usd", Op: "UPDATE", Currency: "EUR"})
+	if _, ok := s.cache.GetSpecificRate("EURUSD"); ok {
+		t.Fatal("EURUSD survived the change")
+	}
+	if u := nextUpdate(sub); u != nil {
+		t.Fatalf("update without fresh rates: %v", u)
+	}
+}
+
+func TestResyncClearsCacheAndPublishesAllRates(t *testing.T) {
+	s := newTestServer(newFakeRepo(providerRates()))
+	s.cache.Set(map[string]float32{"USD": 1, "EUR": 0.9, "GBP": 1.3})
+	s.cache.SetSpecificRate("GBPUSD", 1.3)
+	sub := s.feed.subscribe(nil)
+
+	s.Resync(context.Background())
+	if _, ok := s.cache.GetSpecificRate("GBPUSD"); ok {
+		t.Fatal("pair cache survived resync")
+	}
+	cached := s.cache.GetAll()
+	if _, ok := cached["GBP"]; ok || cached["EUR"] != 1.1 {
+		t.Fatalf("cache after resync = %v", cached)
+	}
+	got := map[string]float32{}
+	for u := nextUpdate(sub); u != nil; u = nextUpdate(sub) {
+		got[u.CurrencyCode] = u.Rate
+	}
+	if len(got) != 3 || got["USD"] != 1 || got["EUR"] != 1.1 || got["RUB"] != 0.011 {
+		t.Fatalf("resync updates = %v", got)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition was not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
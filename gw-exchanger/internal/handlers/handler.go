@@ -2,16 +2,18 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"gw-exchanger/internal/cache"
 	"gw-exchanger/internal/config"
 	"gw-exchanger/internal/logger"
 	"gw-exchanger/internal/storages"
-	"math"
 	"time"
 
 	exchange "github.com/IlyaBroo/exchange_grpc/exchange"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 type Server struct {
@@ -19,6 +21,7 @@ type Server struct {
 	lg    logger.Logger
 	db    storages.RepositoryInterface
 	cache *cache.Cache
+	feed  *feed
 }
 
 func NewServer(lg logger.Logger, ctx context.Context, cfg *config.ConfigAdr) *Server {
@@ -28,6 +31,7 @@ func NewServer(lg logger.Logger, ctx context.Context, cfg *config.ConfigAdr) *Se
 	s.lg = lg
 	s.db = db
 	s.cache = cache
+	s.feed = newFeed()
 	return s
 
 }
@@ -79,11 +83,19 @@ func (s *Server) GetExchangeRateForCurrency(ctx context.Context, in *exchange.Cu
 	}
 	cachedRates := s.cache.GetAll()
 	if len(cachedRates) > 0 {
+		from, okFrom := cachedRates[in.FromCurrency]
+		to, okTo := cachedRates[in.ToCurrency]
+		if !okFrom || !okTo {
+			return nil, status.Error(codes.NotFound, storages.ErrUnknownCurrency.Error())
+		}
 		s.lg.InfoCtx(ctx, "Returning cached from all exchange rate")
-		excRateResponse.Rate = calculateRate(cachedRates[in.FromCurrency], cachedRates[in.ToCurrency])
+		excRateResponse.Rate = calculateRate(from, to)
 		return excRateResponse, nil
 	}
 	res, err := s.db.GetRatesForCurrency(ctx, in.FromCurrency, in.ToCurrency)
+	if errors.Is(err, storages.ErrUnknownCurrency) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
 	if err != nil {
 		s.lg.ErrorCtx(ctx, "GetExchangeRateForCurrency failed")
 		return nil, err
@@ -94,6 +106,11 @@ func (s *Server) GetExchangeRateForCurrency(ctx context.Context, in *exchange.Cu
 	return excRateResponse, nil
 }
 
+// Ready проверяет, что сервер может отвечать на запросы курсов.
+func (s *Server) Ready(ctx context.Context) error {
+	return s.db.Ready(ctx)
+}
+
 func (s *Server) getIDFromContext(ctx context.Context) string {
 
 	arraystring := metadata.ValueFromIncomingContext(ctx, "requestID")
@@ -107,8 +124,8 @@ func (s *Server) getIDFromContext(ctx context.Context) string {
 	return id
 }
 
+// calculateRate переводит курсы к USD в курс пары: сколько единиц to дают
+// за единицу from. Так же считает курс и репозиторий.
 func calculateRate(from, to float32) float32 {
-	res := 1 / from * to
-	res = float32(math.Round(float64(res)*100) / 100)
-	return res
+	return 1 / to * from
 }
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gw-exchanger/api/rates"
+	"gw-exchanger/internal/storages"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// RatesServer реализует RateService поверх ленты обновлений Server.
+type RatesServer struct {
+	rates.UnimplementedRateServiceServer
+	srv *Server
+}
+
+func NewRatesServer(s *Server) *RatesServer {
+	return &RatesServer{srv: s}
+}
+
+func (s *RatesServer) SubscribeRates(in *rates.SubscribeRatesRequest, stream rates.RateService_SubscribeRatesServer) error {
+	for _, code := range in.Currencies {
+		if err := validateCurrency(code); err != nil {
+			return err
+		}
+	}
+	ctx := stream.Context()
+	sub := s.srv.feed.subscribe(in.Currencies)
+	defer s.srv.feed.unsubscribe(sub)
+	s.srv.lg.InfoCtx(ctx, fmt.Sprintf("%s subscribed to rate updates %v", caller(ctx), in.Currencies))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sub.done:
+			return sub.err
+		case u := <-sub.updates:
+			if err := stream.Send(u); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ApplyRateChange сбрасывает кеш валюты, перечитывает действующие курсы
+// и рассылает подписчикам новый курс валюты.
+func (s *Server) ApplyRateChange(ctx context.Context, change storages.RateChange) {
+	s.lg.InfoCtx(ctx, fmt.Sprintf("rate change: %s %s %s", change.Table, change.Op, change.Currency))
+	s.cache.Invalidate(change.Currency)
+	current, err := s.db.GetRates(ctx)
+	if err != nil {
+		s.lg.WarnCtx(ctx, fmt.Sprintf("refresh rates after change of %s: %v", change.Currency, err))
+		return
+	}
+	s.cache.Set(current)
+	rate, ok := current[change.Currency]
+	s.feed.publish(&rates.RateUpdate{
+		CurrencyCode: change.Currency,
+		Rate:         rate,
+		Enabled:      ok,
+		ChangedAt:    timestamppb.Now(),
+	})
+}
+
+// Shutdown завершает потоки подписчиков, чтобы GracefulStop не ждал их.
+func (s *Server) Shutdown() {
+	s.feed.closeAll()
+}
+
+// Resync сбрасывает весь кеш и рассылает все курсы. Вызывается, когда
+// уведомления об изменениях могли быть потеряны.
+func (s *Server) Resync(ctx context.Context) {
+	s.cache.Clear()
+	current, err := s.db.GetRates(ctx)
+	if err != nil {
+		s.lg.WarnCtx(ctx, fmt.Sprintf("resync rates: %v", err))
+		return
+	}
+	s.cache.Set(current)
+	now := time.Now()
+	for code, rate := range current {
+		s.feed.publish(&rates.RateUpdate{
+			CurrencyCode: code,
+			Rate:         rate,
+			Enabled:      true,
+			ChangedAt:    timestamppb.New(now),
+		})
+	}
+}
+
+// maxPairs ограничивает размер одного пакетного запроса.
+const maxPairs = 200
+
+func (s *RatesServer) GetExchangeRatesForPairs(ctx context.Context, in *rates.PairsRequest) (*rates.PairsResponse, error) {
+	if len(in.Pairs) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "pairs are required")
+	}
+	if len(in.Pairs) > maxPairs {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("at most %d pairs per request", maxPairs))
+	}
+	snapshot, asOf, source, err := s.srv.rateSnapshot(ctx)
+	if err != nil {
+		s.srv.lg.ErrorCtx(ctx, fmt.Sprintf("GetExchangeRatesForPairs failed: %v", err))
+		return nil, status.Error(codes.Unavailable, "rates are unavailable")
+	}
+
+	res := &rates.PairsResponse{
+		Rates:  make([]*rates.PairRate, 0, len(in.Pairs)),
+		AsOf:   timestamppb.New(asOf),
+		Source: source,
+	}
+	for _, p := range in.Pairs {
+		r := &rates.PairRate{FromCurrency: p.FromCurrency, ToCurrency: p.ToCurrency}
+		from, okFrom := snapshot[p.FromCurrency]
+		to, okTo := snapshot[p.ToCurrency]
+		switch {
+		case p.FromCurrency == p.ToCurrency:
+			r.Error = "from and to currency are the same"
+		case !okFrom || !okTo:
+			r.Error = storages.ErrUnknownCurrency.Error()
+		default:
+			r.Rate = calculateRate(from, to)
+		}
+		res.Rates = append(res.Rates, r)
+	}
+	return res, nil
+}
+
+// rateSnapshot возвращает копию всех курсов из кеша, а при пустом кеше
+// читает их из базы и кеширует. Вместе с курсами возвращает время их
+// чтения из базы и источник ответа.
+func (s *Server) rateSnapshot(ctx context.Context) (map[string]float32, time.Time, string, error) {
+	if cached, asOf := s.cache.Snapshot(); len(cached) > 0 {
+		return cached, asOf, "cache", nil
+	}
+	current, err := s.db.GetRates(ctx)
+	if err != nil {
+		return nil, time.Time{}, "", err
+	}
+	s.cache.Set(current)
+	return current, time.Now(), "database", nil
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gw-exchanger/api/rates"
+	"gw-exchanger/internal/storages"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// shiftingRepo переключает курс EUR между 1.1 и 2.2 после каждого чтения,
+// как поставщик во время резкого движения рынка.
+type shiftingRepo struct {
+	*fakeRepo
+}
+
+func (r shiftingRepo) GetRates(ctx context.Context) (map[string]float32, error) {
+	res, err := r.fakeRepo.GetRates(ctx)
+	r.mu.Lock()
+	if r.rates["EUR"] == 1.1 {
+		r.rates["EUR"] = 2.2
+	} else {
+		r.rates["EUR"] = 1.1
+	}
+	r.mu.Unlock()
+	return res, err
+}
+
+func pairs(codes ...string) *rates.PairsRequest {
+	in := &rates.PairsRequest{}
+	for i := 0; i+1 < len(codes); i += 2 {
+		in.Pairs = append(in.Pairs, &rates.CurrencyPair{FromCurrency: codes[i], ToCurrency: codes[i+1]})
+	}
+	return in
+}
+
+func TestGetExchangeRatesForPairs(t *testing.T) {
+	db := newFakeRepo(providerRates())
+	r := NewRatesServer(newTestServer(db))
+
+	res, err := r.GetExchangeRatesForPairs(context.Background(), pairs(
+		"EUR", "USD",
+		"USD", "USD",
+		"GBP", "USD",
+		"EUR", "GBP",
+		"RUB", "EUR",
+	))
+	if err != nil {
+		t.Fatalf("GetExchangeRatesForPairs: %v", err)
+	}
+	want := []struct {
+		from, to string
+		rate     float32
+		err      string
+	}{
+		{"EUR", "USD", calculateRate(1.1, 1), ""},
+		{"USD", "USD", 0, "from and to currency are the same"},
+		{"GBP", "USD", 0, storages.ErrUnknownCurrency.Error()},
+		{"EUR", "GBP", 0, storages.ErrUnknownCurrency.Error()},
+		{"RUB", "EUR", calculateRate(0.011, 1.1), ""},
+	}
+	if len(res.Rates) != len(want) {
+		t.Fatalf("got %d rates, want %d", len(res.Rates), len(want))
+	}
+	for i, w := range want {
+		got := res.Rates[i]
+		if got.FromCurrency != w.from || got.ToCurrency != w.to || got.Rate != w.rate || got.Error != w.err {
+			t.Fatalf("pair %d = %v, want %v", i, got, w)
+		}
+	}
+	if res.Source != "database" || res.AsOf == nil {
+		t.Fatalf("source = %q, as_of = %v", res.Source, res.AsOf)
+	}
+	if db.getRates != 1 {
+		t.Fatalf("GetRates called %d times, want 1", db.getRates)
+	}
+}
+
+func TestGetExchangeRatesForPairsLimits(t *testing.T) {
+	many := func(n int) *rates.PairsRequest {
+		in := &rates.PairsRequest{}
+		for i := 0; i < n; i++ {
+			in.Pairs = append(in.Pairs, &rates.CurrencyPair{FromCurrency: "EUR", ToCurrency: "USD"})
+		}
+		return in
+	}
+	tests := []struct {
+		name  string
+		in    *rates.PairsRequest
+		code  codes.Code
+		reads int
+	}{
+		{name: "no pairs", in: &rates.PairsRequest{}, code: codes.InvalidArgument},
+		{name: "too many pairs", in: many(maxPairs + 1), code: codes.InvalidArgument},
+		{name: "limit", in: many(maxPairs), reads: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeRepo(providerRates())
+			res, err := NewRatesServer(newTestServer(db)).GetExchangeRatesForPairs(context.Background(), tt.in)
+			if code := status.Code(err); code != tt.code {
+				t.Fatalf("code = %v (%v), want %v", code, err, tt.code)
+			}
+			if db.getRates != tt.reads {
+				t.Fatalf("GetRates called %d times, want %d", db.getRates, tt.reads)
+			}
+			if err == nil && len(res.Rates) != len(tt.in.Pairs) {
+				t.Fatalf("got %d rates, want %d", len(res.Rates), len(tt.in.Pairs))
+			}
+		})
+	}
+}
+
+func TestGetExchangeRatesForPairsUnavailable(t *testing.T) {
+	db := newFakeRepo(providerRates())
+	db.err = errors.New("connection refused")
+	_, err := NewRatesServer(newTestServer(db)).GetExchangeRatesForPairs(context.Background(), pairs("EUR", "USD"))
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("code = %v (%v), want Unavailable", status.Code(err), err)
+	}
+}
+
+func TestGetExchangeRatesForPairsUsesOneSnapshot(t *testing.T) {
+	db := shiftingRepo{newFakeRepo(providerRates())}
+	s := newTestServer(db)
+	r := NewRatesServer(s)
+	ctx := context.Background()
+
+	in := pairs("EUR", "USD", "USD", "EUR", "EUR", "RUB", "EUR", "USD")
+	res, err := r.GetExchangeRatesForPairs(ctx, in)
+	if err != nil {
+		t.Fatalf("GetExchangeRatesForPairs: %v", err)
+	}
+	if db.getRates != 1 {
+		t.Fatalf("GetRates called %d times, want 1", db.getRates)
+	}
+	eurUSD := calculateRate(1.1, 1)
+	if res.Rates[0].Rate != eurUSD || res.Rates[3].Rate != eurUSD ||
+		res.Rates[1].Rate != calculateRate(1, 1.1) || res.Rates[2].Rate != calculateRate(1.1, 0.011) {
+		t.Fatalf("rates are not from one snapshot: %v", res.Rates)
+	}
+
+	// Второй запрос отвечает из кеша с временем того же чтения из базы.
+	_, asOf := s.cache.Snapshot()
+	res, err = r.GetExchangeRatesForPairs(ctx, in)
+	if err != nil {
+		t.Fatalf("GetExchangeRatesForPairs: %v", err)
+	}
+	if res.Source != "cache" || !res.AsOf.AsTime().Equal(asOf) || res.Rates[0].Rate != eurUSD {
+		t.Fatalf("cached response: source %q, as_of %v, rate %v", res.Source, res.AsOf.AsTime(), res.Rates[0].Rate)
+	}
+	if db.getRates != 1 {
+		t.Fatalf("GetRates called %d times, want 1", db.getRates)
+	}
+}
+
+func TestGetExchangeRatesForPairsDuringRateChanges(t *testing.T) {
+	db := shiftingRepo{newFakeRepo(providerRates())}
+	s := newTestServer(db)
+	r := NewRatesServer(s)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for ctx.Err() == nil {
+			s.ApplyRateChange(ctx, storages.RateChange{Table: "currency_rates_usd", Op: "UPDATE", Currency: "EUR"})
+		}
+	}()
+	in := pairs("EUR", "USD", "RUB", "USD", "EUR", "RUB")
+	for i := 0; i < 200; i++ {
+		res, err := r.GetExchangeRatesForPairs(ctx, in)
+		if err != nil {
+			t.Fatalf("GetExchangeRatesForPairs: %v", err)
+		}
+		eur, rub, eurRUB := res.Rates[0].Rate, res.Rates[1].Rate, res.Rates[2].Rate
+		if eurRUB != calculateRate(eur, rub) {
+			t.Fatalf("EUR/RUB %v does not match EUR/USD %v and RUB/USD %v", eurRUB, eur, rub)
+		}
+	}
+	cancel()
+	wg.Wait()
+}
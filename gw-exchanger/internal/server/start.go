@@ -2,10 +2,16 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"gw-exchanger/api/admin"
+	"gw-exchanger/api/rates"
+	"gw-exchanger/internal/auth"
 	"gw-exchanger/internal/config"
 	"gw-exchanger/internal/handlers"
 	"gw-exchanger/internal/logger"
+	"gw-exchanger/internal/storages"
+	"gw-exchanger/internal/tlsconfig"
 	"net"
 	"os"
 	"os/signal"
@@ -14,6 +20,19 @@ import (
 
 	exchange "github.com/IlyaBroo/exchange_grpc/exchange"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+)
+
+const (
+	healthCheckInterval = 5 * time.Second
+	// overrideExpiryInterval — как долго истекший ручной курс может
+	// оставаться в кеше и не доходить до подписчиков.
+	overrideExpiryInterval = time.Second
+	// keepaliveMinTime — как часто клиенты могут слать keepalive-пинги,
+	// не получая GOAWAY. Должно быть не больше keepalive_time_sec кошелька.
+	keepaliveMinTime = 10 * time.Second
 )
 
 func Start(ctx context.Context, cfg *config.ConfigAdr, lg logger.Logger) error {
@@ -23,10 +42,45 @@ func Start(ctx context.Context, cfg *config.ConfigAdr, lg logger.Logger) error {
 		lg.ErrorCtx(ctx, fmt.Sprintf("Failed to listen: %v", err))
 		return err
 	}
-	s := grpc.NewServer()
+	opts := []grpc.ServerOption{grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+		MinTime:             keepaliveMinTime,
+		PermitWithoutStream: true,
+	})}
+	if tlsCfg := cfg.Grpc_tls; tlsCfg.Enabled {
+		if tlsCfg.Cert_file == "" {
+			return errors.New("grpc_tls: cert_file is required")
+		}
+		certs, err := tlsconfig.New(tlsCfg.Cert_file, tlsCfg.Key_file, tlsCfg.Ca_file)
+		if err != nil {
+			lg.ErrorCtx(ctx, fmt.Sprintf("Failed to load TLS certificates: %v", err))
+			return err
+		}
+		go certs.Run(ctx, time.Duration(tlsCfg.Reload_sec)*time.Second, lg)
+		opts = append(opts, grpc.Creds(certs.ServerCredentials()))
+		if tlsCfg.Ca_file != "" {
+			lg.InfoCtx(ctx, "gRPC server requires client certificates")
+		}
+	}
+	if cfg.Service_auth.Enabled {
+		authz := auth.New(cfg.Service_auth, lg)
+		opts = append(opts,
+			grpc.ChainUnaryInterceptor(authz.UnaryInterceptor()),
+			grpc.ChainStreamInterceptor(authz.StreamInterceptor()))
+	} else {
+		lg.WarnCtx(ctx, "Service authentication is disabled, every client may call any method")
+	}
+	s := grpc.NewServer(opts...)
 
 	server := handlers.NewServer(lg, ctx, cfg)
 	exchange.RegisterExchangeServiceServer(s, server)
+	admin.RegisterAdminServiceServer(s, handlers.NewAdminServer(server))
+	rates.RegisterRateServiceServer(s, handlers.NewRatesServer(server))
+	go storages.NewListener(cfg, lg).Run(ctx, server.ApplyRateChange, server.Resync)
+	go server.RunOverrideExpiry(ctx, overrideExpiryInterval)
+
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(s, hs)
+	go watchHealth(ctx, hs, server, lg)
 	lg.InfoCtx(ctx, fmt.Sprintf("gRPC server listening on port %s", cfg.APP_ADR))
 
 	stop := make(chan os.Signal, 1)
@@ -43,16 +97,57 @@ func Start(ctx context.Context, cfg *config.ConfigAdr, lg logger.Logger) error {
 	ctxout, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	hs.Shutdown()
+	server.Shutdown()
 	lg.InfoCtx(ctx, "Calling GracefulStop...")
-	s.GracefulStop()
-	lg.InfoCtx(ctx, "GracefulStop called, waiting for server to finish...")
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
 
 	select {
 	case <-ctxout.Done():
 		lg.WarnCtx(ctx, "Server timeout reached, forcing exit")
-	case <-time.After(5 * time.Second):
+		s.Stop()
+	case <-stopped:
 		lg.InfoCtx(ctx, "Server shutdown completed")
 	}
 
 	return nil
 }
+
+// watchHealth периодически проверяет базу и переключает статус grpc.health.v1
+// для всего сервера и для ExchangeService между SERVING и NOT_SERVING.
+func watchHealth(ctx context.Context, hs *health.Server, server *handlers.Server, lg logger.Logger) {
+	services := []string{"", exchange.ExchangeService_ServiceDesc.ServiceName}
+	for _, name := range services {
+		hs.SetServingStatus(name, healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+	serving := false
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, healthCheckInterval)
+		err := server.Ready(checkCtx)
+		cancel()
+		if (err == nil) != serving {
+			serving = err == nil
+			status := healthpb.HealthCheckResponse_NOT_SERVING
+			if serving {
+				status = healthpb.HealthCheckResponse_SERVING
+				lg.InfoCtx(ctx, "health: serving")
+			} else {
+				lg.WarnCtx(ctx, fmt.Sprintf("health: not serving: %v", err))
+			}
+			for _, name := range services {
+				hs.SetServingStatus(name, status)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
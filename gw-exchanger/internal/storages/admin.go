@@ -0,0 +1,132 @@
+package storages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const overrideColumns = "id, currency_code, exchange_rate, reason, created_by, created_at, expires_at"
+
+// AddCurrency добавляет валюту или включает существующую, обновляя ее курс.
+func (r *Repository) AddCurrency(ctx context.Context, code string, rate float32) (Currency, error) {
+	var c Currency
+	err := r.db.QueryRow(ctx, `INSERT INTO currency_rates_usd (currency_code, exchange_rate) VALUES ($1, $2)
+		ON CONFLICT (currency_code) DO UPDATE
+		SET exchange_rate = EXCLUDED.exchange_rate, enabled = TRUE, updated_at = now()
+		RETURNING currency_code, exchange_rate, enabled, updated_at`, code, rate).
+		Scan(&c.Code, &c.Rate, &c.Enabled, &c.UpdatedAt)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("add currency %s failed: %v", code, err))
+		return Currency{}, err
+	}
+	return c, nil
+}
+
+func (r *Repository) DisableCurrency(ctx context.Context, code string) (Currency, error) {
+	var c Currency
+	err := r.db.QueryRow(ctx, `UPDATE currency_rates_usd SET enabled = FALSE, updated_at = now()
+		WHERE currency_code = $1
+		RETURNING currency_code, exchange_rate, enabled, updated_at`, code).
+		Scan(&c.Code, &c.Rate, &c.Enabled, &c.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Currency{}, ErrNotFound
+	}
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("disable currency %s failed: %v", code, err))
+		return Currency{}, err
+	}
+	return c, nil
+}
+
+// SetRateOverride снимает прежний ручной курс валюты и ставит новый одним
+// запросом. Для неизвестной или отключенной валюты возвращает ErrNotFound.
+func (r *Repository) SetRateOverride(ctx context.Context, o RateOverride) (RateOverride, error) {
+	row := r.db.QueryRow(ctx, `WITH cur AS (
+			SELECT currency_code FROM currency_rates_usd WHERE currency_code = $1 AND enabled
+		), prev AS (
+			UPDATE rate_overrides SET removed_at = now()
+			WHERE currency_code IN (SELECT currency_code FROM cur) AND removed_at IS NULL
+		)
+		INSERT INTO rate_overrides (currency_code, exchange_rate, reason, created_by, expires_at)
+		SELECT currency_code, $2, $3, $4, $5 FROM cur
+		RETURNING `+overrideColumns, o.Currency, o.Rate, o.Reason, o.CreatedBy, o.ExpiresAt)
+	res, err := scanOverride(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return RateOverride{}, ErrNotFound
+	}
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("set rate override for %s failed: %v", o.Currency, err))
+		return RateOverride{}, err
+	}
+	return res, nil
+}
+
+// RemoveRateOverride снимает действующий ручной курс.
+func (r *Repository) RemoveRateOverride(ctx context.Context, id int64) (RateOverride, error) {
+	row := r.db.QueryRow(ctx, `UPDATE rate_overrides SET removed_at = now()
+		WHERE id = $1 AND removed_at IS NULL AND expires_at > now()
+		RETURNING `+overrideColumns, id)
+	res, err := scanOverride(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return RateOverride{}, ErrNotFound
+	}
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("remove rate override %d failed: %v", id, err))
+		return RateOverride{}, err
+	}
+	return res, nil
+}
+
+// ListRateOverrides возвращает действующие ручные курсы, пустой code — по всем валютам.
+func (r *Repository) ListRateOverrides(ctx context.Context, code string) ([]RateOverride, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+overrideColumns+` FROM rate_overrides
+		WHERE removed_at IS NULL AND expires_at > now() AND ($1 = '' OR currency_code = $1)
+		ORDER BY currency_code, created_at DESC`, code)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("list rate overrides failed: %v", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []RateOverride
+	for rows.Next() {
+		o, err := scanOverride(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, o)
+	}
+	return res, rows.Err()
+}
+
+// ExpireRateOverrides снимает истекшие ручные курсы и возвращает их. Снятие
+// меняет строки rate_overrides, поэтому триггер оповещает все реплики.
+func (r *Repository) ExpireRateOverrides(ctx context.Context) ([]RateOverride, error) {
+	rows, err := r.db.Query(ctx, `UPDATE rate_overrides SET removed_at = expires_at
+		WHERE removed_at IS NULL AND expires_at <= now()
+		RETURNING `+overrideColumns)
+	if err != nil {
+		r.lg.ErrorCtx(ctx, fmt.Sprintf("expire rate overrides failed: %v", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []RateOverride
+	for rows.Next() {
+		o, err := scanOverride(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, o)
+	}
+	return res, rows.Err()
+}
+
+func scanOverride(row pgx.Row) (RateOverride, error) {
+	var o RateOverride
+	err := row.Scan(&o.ID, &o.Currency, &o.Rate, &o.Reason, &o.CreatedBy, &o.CreatedAt, &o.ExpiresAt)
+	return o, err
+}
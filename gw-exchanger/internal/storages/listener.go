@@ -0,0 +1,110 @@
+package storages
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gw-exchanger/internal/config"
+	"gw-exchanger/internal/logger"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// RateChangesChannel — канал NOTIFY, в который пишет триггер notify_rate_change.
+const RateChangesChannel = "rate_changes"
+
+// RateChange — уведомление об изменении строки currency_rates_usd или rate_overrides.
+type RateChange struct {
+	Table    string `json:"table"`
+	Op       string `json:"op"`
+	Currency string `json:"currency"`
+}
+
+// notifyConn — соединение, на котором Listener ждет уведомлений. Его реализует *pgx.Conn.
+type notifyConn interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	WaitForNotification(ctx context.Context) (*pgconn.Notification, error)
+	Close(ctx context.Context) error
+}
+
+// Listener слушает RateChangesChannel на отдельном соединении, поскольку
+// соединения пула не держат LISTEN между запросами.
+type Listener struct {
+	connect    func(ctx context.Context) (notifyConn, error)
+	lg         logger.Logger
+	backoff    time.Duration
+	backoffMax time.Duration
+}
+
+func NewListener(cfg *config.ConfigAdr, lg logger.Logger) *Listener {
+	l := &Listener{
+		connect: func(ctx context.Context) (notifyConn, error) {
+			conn, err := pgx.Connect(ctx, cfg.Database_url)
+			if err != nil {
+				return nil, err
+			}
+			return conn, nil
+		},
+		lg:         lg,
+		backoff:    time.Duration(cfg.Database.Connect_backoff_ms) * time.Millisecond,
+		backoffMax: time.Duration(cfg.Database.Connect_backoff_max_ms) * time.Millisecond,
+	}
+	if l.backoff <= 0 {
+		l.backoff = defaultConnectBackoff
+	}
+	if l.backoffMax <= 0 {
+		l.backoffMax = defaultConnectBackoffMax
+	}
+	return l
+}
+
+// Run вызывает onChange на каждое уведомление до отмены ctx. При обрыве
+// соединения переподключается с экспоненциальной паузой; после каждого
+// подключения вызывает onResync, потому что уведомления за время обрыва потеряны.
+func (l *Listener) Run(ctx context.Context, onChange func(context.Context, RateChange), onResync func(context.Context)) {
+	backoff := l.backoff
+	for {
+		err := l.listen(ctx, onChange, onResync, func() { backoff = l.backoff })
+		if ctx.Err() != nil {
+			return
+		}
+		l.lg.WarnCtx(ctx, fmt.Sprintf("rate listener disconnected, reconnecting in %s: %v", backoff, err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, l.backoffMax)
+	}
+}
+
+func (l *Listener) listen(ctx context.Context, onChange func(context.Context, RateChange), onResync func(context.Context), connected func()) error {
+	conn, err := l.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+RateChangesChannel); err != nil {
+		return err
+	}
+	connected()
+	l.lg.InfoCtx(ctx, fmt.Sprintf("listening for %s notifications", RateChangesChannel))
+	onResync(ctx)
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var change RateChange
+		if err := json.Unmarshal([]byte(n.Payload), &change); err != nil {
+			l.lg.WarnCtx(ctx, fmt.Sprintf("bad %s payload %q: %v", RateChangesChannel, n.Payload, err))
+			continue
+		}
+		onChange(ctx, change)
+	}
+}
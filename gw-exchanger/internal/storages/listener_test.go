@@ -0,0 +1,178 @@
+package storages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// recordLogger запоминает предупреждения.
+type recordLogger struct {
+	mu    sync.Mutex
+	warns []string
+}
+
+func (l *recordLogger) DebugCtx(ctx context.Context, msg string)            {}
+func (l *recordLogger) InfoCtx(ctx context.Context, msg string)             {}
+func (l *recordLogger) ErrorCtx(ctx context.Context, msg string)            {}
+func (l *recordLogger) FatalCtx(ctx context.Context, msg string, err error) {}
+func (l *recordLogger) WarnCtx(ctx context.Context, msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.warns = append(l.warns, msg)
+}
+
+func (l *recordLogger) reconnects() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var res []string
+	for _, w := range l.warns {
+		if after, ok := strings.CutPrefix(w, "rate listener disconnected, reconnecting in "); ok {
+			res = append(res, strings.SplitN(after, ":", 2)[0])
+		}
+	}
+	return res
+}
+
+// fakeNotifyConn отдает payloads по порядку, затем возвращает err, а при
+// пустом err ждет отмены ctx.
+type fakeNotifyConn struct {
+	execErr  error
+	payloads []string
+	err      error
+	listened string
+	closed   bool
+}
+
+func (c *fakeNotifyConn) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	c.listened = sql
+	return pgconn.CommandTag{}, c.execErr
+}
+
+func (c *fakeNotifyConn) WaitForNotification(ctx context.Context) (*pgconn.Notification, error) {
+	if len(c.payloads) > 0 {
+		n := &pgconn.Notification{Channel: RateChangesChannel, Payload: c.payloads[0]}
+		c.payloads = c.payloads[1:]
+		return n, nil
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (c *fakeNotifyConn) Close(ctx context.Context) error {
+	c.closed = true
+	return nil
+}
+
+func TestListenerReconnectsAndResyncs(t *testing.T) {
+	broken := &fakeNotifyConn{execErr: errors.New("permission denied")}
+	first := &fakeNotifyConn{
+		payloads: []string{
+			`{"table":"currency_rates_usd","op":"UPDATE","currency":"EUR"}`,
+			`not json`,
+			`{"table":"rate_overrides","op":"INSERT","currency":"RUB"}`,
+		},
+		err: errors.New("connection reset"),
+	}
+	second := &fakeNotifyConn{payloads: []string{`{"table":"rate_overrides","op":"DELETE","currency":"USD"}`}}
+
+	dials := []func() (notifyConn, error){
+		func() (notifyConn, error) { return nil, errors.New("connection refused") },
+		func() (notifyConn, error) { return broken, nil },
+		func() (notifyConn, error) { return first, nil },
+		func() (notifyConn, error) { return second, nil },
+	}
+	lg := &recordLogger{}
+	l := &Listener{
+		lg:         lg,
+		backoff:    time.Millisecond,
+		backoffMax: 3 * time.Millisecond,
+		connect: func(ctx context.Context) (notifyConn, error) {
+			if len(dials) == 0 {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			dial := dials[0]
+			dials = dials[1:]
+			return dial()
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var events []string
+	onChange := func(_ context.Context, c RateChange) {
+		events = append(events, fmt.Sprintf("%s %s %s", c.Table, c.Op, c.Currency))
+		if c.Currency == "USD" {
+			cancel()
+		}
+	}
+	onResync := func(context.Context) { events = append(events, "resync") }
+
+	done := make(chan struct{})
+	go func() {
+		l.Run(ctx, onChange, onResync)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("listener did not stop after ctx was cancelled")
+	}
+
+	want := []string{
+		"resync",
+		"currency_rates_usd UPDATE EUR",
+		"rate_overrides INSERT RUB",
+		"resync",
+		"rate_overrides DELETE USD",
+	}
+	if fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Fatalf("events = %q, want %q", events, want)
+	}
+	// Пауза растет, пока LISTEN не удался, и сбрасывается после подключения.
+	if got, want := lg.reconnects(), []string{"1ms", "2ms", "1ms"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("reconnect backoffs = %v, want %v", got, want)
+	}
+	for i, c := range []*fakeNotifyConn{broken, first, second} {
+		if c.listened != "LISTEN "+RateChangesChannel {
+			t.Fatalf("connection %d: exec %q", i, c.listened)
+		}
+		if !c.closed {
+			t.Fatalf("connection %d was not closed", i)
+		}
+	}
+}
+
+func TestListenerBackoffIsCapped(t *testing.T) {
+	lg := &recordLogger{}
+	attempts := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	l := &Listener{
+		lg:         lg,
+		backoff:    time.Millisecond,
+		backoffMax: 4 * time.Millisecond,
+		connect: func(context.Context) (notifyConn, error) {
+			attempts++
+			if attempts == 5 {
+				cancel()
+			}
+			return nil, errors.New("connection refused")
+		},
+	}
+	l.Run(ctx, func(context.Context, RateChange) { t.Fatal("unexpected change") }, func(context.Context) { t.Fatal("unexpected resync") })
+
+	if got, want := lg.reconnects(), []string{"1ms", "2ms", "4ms", "4ms"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("reconnect backoffs = %v, want %v", got, want)
+	}
+}
@@ -2,7 +2,9 @@ package storages
 
 import (
 	"context"
+	"errors"
 	"gw-exchanger/internal/logger"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -11,6 +13,13 @@ import (
 type RepositoryInterface interface {
 	GetRates(context.Context) (map[string]float32, error)
 	GetRatesForCurrency(ctx context.Context, from, to string) (float32, error)
+	AddCurrency(ctx context.Context, code string, rate float32) (Currency, error)
+	DisableCurrency(ctx context.Context, code string) (Currency, error)
+	SetRateOverride(ctx context.Context, o RateOverride) (RateOverride, error)
+	RemoveRateOverride(ctx context.Context, id int64) (RateOverride, error)
+	ListRateOverrides(ctx context.Context, code string) ([]RateOverride, error)
+	ExpireRateOverrides(ctx context.Context) ([]RateOverride, error)
+	Ready(ctx context.Context) error
 	Close()
 }
 
@@ -18,6 +27,7 @@ type DBPool interface {
 	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
 	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
 	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	Ping(ctx context.Context) error
 	Close()
 }
 
@@ -27,6 +37,26 @@ type Repository struct {
 	ctx context.Context
 }
 
-const (
-	maxconns = 2000
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrUnknownCurrency = errors.New("unknown or disabled currency")
 )
+
+// Currency — валюта справочника, курс задан относительно USD.
+type Currency struct {
+	Code      string
+	Rate      float32
+	Enabled   bool
+	UpdatedAt time.Time
+}
+
+// RateOverride — ручной курс валюты, действующий до ExpiresAt.
+type RateOverride struct {
+	ID        int64
+	Currency  string
+	Rate      float32
+	Reason    string
+	CreatedBy string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
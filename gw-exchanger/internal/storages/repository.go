@@ -9,12 +9,23 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// effectiveRatesQuery выбирает включенные валюты с курсом с учетом
+// действующего ручного курса, который важнее данных поставщика.
+const effectiveRatesQuery = `SELECT c.currency_code, COALESCE(o.exchange_rate, c.exchange_rate)
+FROM currency_rates_usd c
+LEFT JOIN LATERAL (
+	SELECT exchange_rate FROM rate_overrides
+	WHERE currency_code = c.currency_code AND removed_at IS NULL AND expires_at > now()
+	ORDER BY created_at DESC, id DESC
+	LIMIT 1
+) o ON TRUE
+WHERE c.enabled`
+
 func NewRepository(lg logger.Logger, ctx context.Context, cfg *config.ConfigAdr) RepositoryInterface {
-	conf, err := pgxpool.ParseConfig(cfg.Database_url)
+	conf, err := poolConfig(cfg)
 	if err != nil {
-		lg.FatalCtx(ctx, "Could not parse database URL: ", err)
+		lg.FatalCtx(ctx, "Could not parse database settings: ", err)
 	}
-	conf.MaxConns = maxconns
 
 	pg, err := pgxpool.NewWithConfig(ctx, conf)
 	if err != nil {
@@ -24,6 +35,7 @@ func NewRepository(lg logger.Logger, ctx context.Context, cfg *config.ConfigAdr)
 	rep.db = pg
 	rep.lg = lg
 	rep.ctx = ctx
+	go rep.connect(ctx, cfg.Database)
 	return rep
 }
 
@@ -35,7 +47,7 @@ func (r *Repository) GetRates(ctx context.Context) (map[string]float32, error) {
 
 	rates := make(map[string]float32)
 
-	rows, err := r.db.Query(ctx, "SELECT currency_code, exchange_rate FROM currency_rates_usd")
+	rows, err := r.db.Query(ctx, effectiveRatesQuery)
 	if err != nil {
 		r.lg.ErrorCtx(ctx, "func get_rates sql query failed")
 		return nil, err
@@ -66,7 +78,7 @@ func (r *Repository) GetRatesForCurrency(ctx context.Context, from, to string) (
 
 	rates := make(map[string]float32)
 
-	rows, err := r.db.Query(ctx, "SELECT currency_code, exchange_rate FROM currency_rates_usd WHERE currency_code = $1 OR currency_code = $2 LIMIT 2", from, to)
+	rows, err := r.db.Query(ctx, effectiveRatesQuery+" AND c.currency_code IN ($1, $2)", from, to)
 	if err != nil {
 		r.lg.ErrorCtx(ctx, "func get_rates sql query failed")
 		return 0, err
@@ -88,6 +100,12 @@ func (r *Repository) GetRatesForCurrency(ctx context.Context, from, to string) (
 		r.lg.ErrorCtx(ctx, fmt.Sprintf("Error iterating rows: %v ", err))
 		return 0, err
 	}
+	if _, ok := rates[from]; !ok {
+		return 0, ErrUnknownCurrency
+	}
+	if _, ok := rates[to]; !ok {
+		return 0, ErrUnknownCurrency
+	}
 	var res float32
 	res = 1 / rates[to] * rates[from]
 	// res = roundToTwoDecimalPlaces(res)
@@ -0,0 +1,202 @@
+// Package tlsconfig собирает gRPC-учетные данные TLS из файлов сертификатов
+// и перечитывает файлы при изменении, не разрывая открытые соединения.
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"gw-exchanger/internal/logger"
+
+	"google.golang.org/grpc/credentials"
+)
+
+const defaultReloadInterval = 30 * time.Second
+
+// Reloader хранит текущие сертификат, ключ и корневые сертификаты.
+// Новые соединения используют последнюю успешно загруженную версию.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	roots    *x509.CertPool
+	modTimes map[string]time.Time
+}
+
+// New загружает файлы. Пустой certFile или keyFile — без собственного
+// сертификата, пустой caFile — системные корневые сертификаты у клиента
+// и без проверки клиентов у сервера.
+func New(certFile, keyFile, caFile string) (*Reloader, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("tls: cert_file and key_file must be set together")
+	}
+	r := &Reloader{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload перечитывает файлы, если время изменения хотя бы одного из них
+// поменялось. При ошибке остается прежняя версия, а попытка повторится
+// при следующем вызове.
+func (r *Reloader) Reload() (bool, error) {
+	modTimes := make(map[string]time.Time)
+	for _, f := range []string{r.certFile, r.keyFile, r.caFile} {
+		if f == "" {
+			continue
+		}
+		st, err := os.Stat(f)
+		if err != nil {
+			return false, err
+		}
+		modTimes[f] = st.ModTime()
+	}
+	r.mu.RLock()
+	changed := r.modTimes == nil || !sameTimes(r.modTimes, modTimes)
+	r.mu.RUnlock()
+	if !changed {
+		return false, nil
+	}
+
+	var cert *tls.Certificate
+	if r.certFile != "" {
+		c, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return false, fmt.Errorf("tls: load key pair: %w", err)
+		}
+		cert = &c
+	}
+	var roots *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return false, err
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return false, fmt.Errorf("tls: no certificates in %s", r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.roots, r.modTimes = cert, roots, modTimes
+	r.mu.Unlock()
+	return true, nil
+}
+
+func sameTimes(a, b map[string]time.Time) bool {
+	for f, t := range b {
+		if !a[f].Equal(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// Run проверяет файлы каждые interval до отмены ctx.
+func (r *Reloader) Run(ctx context.Context, interval time.Duration, lg logger.Logger) {
+	if interval <= 0 {
+		interval = defaultReloadInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		changed, err := r.Reload()
+		switch {
+		case err != nil:
+			lg.WarnCtx(ctx, fmt.Sprintf("tls: reload certificates: %v", err))
+		case changed:
+			lg.InfoCtx(ctx, "tls: certificates reloaded")
+		}
+	}
+}
+
+func (r *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, r.roots
+}
+
+// serverConfig требует сертификат клиента, если задан caFile.
+func (r *Reloader) serverConfig() *tls.Config {
+	cert, roots := r.current()
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cert != nil {
+		cfg.Certificates = []tls.Certificate{*cert}
+	}
+	if roots != nil {
+		cfg.ClientCAs = roots
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg
+}
+
+func (r *Reloader) clientConfig(serverName string) *tls.Config {
+	cert, roots := r.current()
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: roots, ServerName: serverName}
+	if cert != nil {
+		cfg.Certificates = []tls.Certificate{*cert}
+	}
+	return cfg
+}
+
+// ServerCredentials возвращает учетные данные для grpc.Creds.
+func (r *Reloader) ServerCredentials() credentials.TransportCredentials {
+	return &reloadingCredentials{r: r, server: true}
+}
+
+// ClientCredentials возвращает учетные данные для grpc.WithTransportCredentials.
+// Пустой serverName — имя хоста из адреса соединения.
+func (r *Reloader) ClientCredentials(serverName string) credentials.TransportCredentials {
+	return &reloadingCredentials{r: r, serverName: serverName}
+}
+
+// reloadingCredentials строит стандартные TLS-учетные данные gRPC заново
+// для каждого рукопожатия, чтобы новые соединения видели свежие файлы.
+type reloadingCredentials struct {
+	r          *Reloader
+	server     bool
+	serverName string
+}
+
+func (c *reloadingCredentials) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return credentials.NewTLS(c.r.clientConfig(c.serverName)).ClientHandshake(ctx, authority, conn)
+}
+
+func (c *reloadingCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	cfg := c.r.serverConfig()
+	if len(cfg.Certificates) == 0 {
+		return nil, nil, errors.New("tls: server certificate is not configured")
+	}
+	return credentials.NewTLS(cfg).ServerHandshake(conn)
+}
+
+func (c *reloadingCredentials) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{SecurityProtocol: "tls", SecurityVersion: "1.2", ServerName: c.serverName}
+}
+
+func (c *reloadingCredentials) Clone() credentials.TransportCredentials {
+	clone := *c
+	return &clone
+}
+
+func (c *reloadingCredentials) OverrideServerName(name string) error {
+	c.serverName = name
+	return nil
+}
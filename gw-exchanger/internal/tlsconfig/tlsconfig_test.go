@@ -0,0 +1,280 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func noError(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func wantError(t *testing.T, err error) {
+	t.Helper()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	noError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	noError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	noError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue выпускает сертификат для localhost и 127.0.0.1 и возвращает PEM сертификата и ключа.
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	noError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	noError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	noError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+var writes int
+
+// writeFile записывает файл и сдвигает время изменения, чтобы Reload
+// заметил перезапись даже на файловых системах с грубыми отметками времени.
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	noError(t, os.WriteFile(path, data, 0o600))
+	writes++
+	mtime := time.Now().Add(time.Duration(writes) * time.Second)
+	noError(t, os.Chtimes(path, mtime, mtime))
+}
+
+type files struct{ cert, key, ca string }
+
+func writeFiles(t *testing.T, dir, name string, cert, key, ca []byte) files {
+	t.Helper()
+	f := files{filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key"), filepath.Join(dir, name+"-ca.crt")}
+	if cert != nil {
+		writeFile(t, f.cert, cert)
+		writeFile(t, f.key, key)
+	}
+	if ca != nil {
+		writeFile(t, f.ca, ca)
+	}
+	return f
+}
+
+func startServer(t *testing.T, creds credentials.TransportCredentials) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	noError(t, err)
+	s := grpc.NewServer(grpc.Creds(creds))
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+func check(t *testing.T, addr string, creds credentials.TransportCredentials) error {
+	t.Helper()
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
+	noError(t, err)
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	return err
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCA(t, "test-ca")
+	srvCert, srvKey := ca.issue(t, "gw-exchanger", x509.ExtKeyUsageServerAuth)
+	cliCert, cliKey := ca.issue(t, "gw-currency-wallet", x509.ExtKeyUsageClientAuth)
+
+	sf := writeFiles(t, dir, "server", srvCert, srvKey, ca.pem)
+	server, err := New(sf.cert, sf.key, sf.ca)
+	noError(t, err)
+	addr := startServer(t, server.ServerCredentials())
+
+	cf := writeFiles(t, dir, "client", cliCert, cliKey, ca.pem)
+	client, err := New(cf.cert, cf.key, cf.ca)
+	noError(t, err)
+	noError(t, check(t, addr, client.ClientCredentials("")))
+	noError(t, check(t, addr, client.ClientCredentials("localhost")))
+
+	t.Run("without client certificate", func(t *testing.T) {
+		anon, err := New("", "", cf.ca)
+		noError(t, err)
+		wantError(t, check(t, addr, anon.ClientCredentials("")))
+	})
+
+	t.Run("untrusted server", func(t *testing.T) {
+		other := newCA(t, "other-ca")
+		of := writeFiles(t, t.TempDir(), "client", cliCert, cliKey, other.pem)
+		c, err := New(of.cert, of.key, of.ca)
+		noError(t, err)
+		wantError(t, check(t, addr, c.ClientCredentials("")))
+	})
+
+	t.Run("wrong server name", func(t *testing.T) {
+		wantError(t, check(t, addr, client.ClientCredentials("gw-exchanger.example")))
+	})
+}
+
+func TestServerWithoutCAAcceptsAnonymousClients(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCA(t, "test-ca")
+	srvCert, srvKey := ca.issue(t, "gw-exchanger", x509.ExtKeyUsageServerAuth)
+
+	sf := writeFiles(t, dir, "server", srvCert, srvKey, nil)
+	server, err := New(sf.cert, sf.key, "")
+	noError(t, err)
+	addr := startServer(t, server.ServerCredentials())
+
+	cf := writeFiles(t, dir, "client", nil, nil, ca.pem)
+	client, err := New("", "", cf.ca)
+	noError(t, err)
+	noError(t, check(t, addr, client.ClientCredentials("")))
+}
+
+func TestReloadRotatesCertificates(t *testing.T) {
+	dir := t.TempDir()
+	oldCA, newCA := newCA(t, "old-ca"), newCA(t, "new-ca")
+	srvCert, srvKey := oldCA.issue(t, "gw-exchanger", x509.ExtKeyUsageServerAuth)
+	cliCert, cliKey := oldCA.issue(t, "gw-currency-wallet", x509.ExtKeyUsageClientAuth)
+
+	sf := writeFiles(t, dir, "server", srvCert, srvKey, oldCA.pem)
+	server, err := New(sf.cert, sf.key, sf.ca)
+	noError(t, err)
+	addr := startServer(t, server.ServerCredentials())
+
+	cf := writeFiles(t, dir, "client", cliCert, cliKey, oldCA.pem)
+	client, err := New(cf.cert, cf.key, cf.ca)
+	noError(t, err)
+	noError(t, check(t, addr, client.ClientCredentials("")))
+
+	changed, err := server.Reload()
+	noError(t, err)
+	if changed {
+		t.Fatal("unchanged files are not reloaded")
+	}
+
+	// Сервер переходит на новый УЦ, клиент пока доверяет только старому.
+	srvCert, srvKey = newCA.issue(t, "gw-exchanger", x509.ExtKeyUsageServerAuth)
+	writeFiles(t, dir, "server", srvCert, srvKey, newCA.pem)
+	changed, err = server.Reload()
+	noError(t, err)
+	if !changed {
+		t.Fatal("changed files were not reloaded")
+	}
+	wantError(t, check(t, addr, client.ClientCredentials("")))
+
+	cliCert, cliKey = newCA.issue(t, "gw-currency-wallet", x509.ExtKeyUsageClientAuth)
+	writeFiles(t, dir, "client", cliCert, cliKey, newCA.pem)
+	changed, err = client.Reload()
+	noError(t, err)
+	if !changed {
+		t.Fatal("changed files were not reloaded")
+	}
+	noError(t, check(t, addr, client.ClientCredentials("")))
+}
+
+func TestReloadKeepsPreviousCertificatesOnError(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCA(t, "test-ca")
+	srvCert, srvKey := ca.issue(t, "gw-exchanger", x509.ExtKeyUsageServerAuth)
+	cliCert, cliKey := ca.issue(t, "gw-currency-wallet", x509.ExtKeyUsageClientAuth)
+
+	sf := writeFiles(t, dir, "server", srvCert, srvKey, ca.pem)
+	server, err := New(sf.cert, sf.key, sf.ca)
+	noError(t, err)
+	addr := startServer(t, server.ServerCredentials())
+	cf := writeFiles(t, dir, "client", cliCert, cliKey, ca.pem)
+	client, err := New(cf.cert, cf.key, cf.ca)
+	noError(t, err)
+
+	// Ключ еще не дописан: старая пара продолжает работать.
+	writeFile(t, sf.key, []byte("not a key"))
+	_, err = server.Reload()
+	wantError(t, err)
+	noError(t, check(t, addr, client.ClientCredentials("")))
+
+	writeFile(t, sf.key, srvKey)
+	changed, err := server.Reload()
+	noError(t, err)
+	if !changed {
+		t.Fatal("changed files were not reloaded")
+	}
+	noError(t, check(t, addr, client.ClientCredentials("")))
+}
+
+func TestNewRequiresCertAndKeyTogether(t *testing.T) {
+	_, err := New("server.crt", "", "")
+	wantError(t, err)
+}
+
+// Пакет повторяет gw-currency-wallet/internal/tlsconfig, отличается только
+// импорт логгера. Тест падает, если копии разошлись; вне общего дерева
+// репозитория, например в сборке образа, он пропускается.
+func TestMatchesWalletCopy(t *testing.T) {
+	wallet, err := os.ReadFile(filepath.Join("..", "..", "..", "gw-currency-wallet", "internal", "tlsconfig", "tlsconfig.go"))
+	if os.IsNotExist(err) {
+		t.Skip("gw-currency-wallet is not next to gw-exchanger")
+	}
+	noError(t, err)
+	own, err := os.ReadFile("tlsconfig.go")
+	noError(t, err)
+	want := strings.Replace(string(wallet), `"gw-currency-wallet/internal/logger"`, `"gw-exchanger/internal/logger"`, 1)
+	if string(own) != want {
+		t.Fatal("tlsconfig.go differs from the gw-currency-wallet copy, change both")
+	}
+}
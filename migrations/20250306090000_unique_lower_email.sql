@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+UPDATE users SET email = lower(trim(email));
+CREATE UNIQUE INDEX users_email_lower_idx ON users (lower(email));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS users_email_lower_idx;
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Лимитные заявки на обмен. Сумма списывается с кошелька при создании заявки
+-- и возвращается при отмене или истечении.
+CREATE TABLE exchange_orders (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    from_currency VARCHAR(3) NOT NULL,
+    to_currency VARCHAR(3) NOT NULL,
+    amount DECIMAL(15, 2) NOT NULL CHECK (amount > 0),
+    target_rate DECIMAL(20, 8) NOT NULL CHECK (target_rate > 0),
+    status VARCHAR(16) NOT NULL DEFAULT 'open'
+        CHECK (status IN ('open', 'filled', 'cancelled', 'expired')),
+    filled_rate DECIMAL(20, 8),
+    received DECIMAL(15, 2),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ,
+    closed_at TIMESTAMPTZ,
+    CHECK (from_currency <> to_currency)
+);
+
+CREATE INDEX exchange_orders_open_idx ON exchange_orders (from_currency, to_currency, target_rate)
+    WHERE status = 'open';
+CREATE INDEX exchange_orders_user_idx ON exchange_orders (user_id, created_at DESC);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE exchange_orders;
+-- +goose StatementEnd
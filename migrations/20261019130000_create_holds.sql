@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Холды резервируют часть баланса кошелька. Сумма остается в wallets, пока
+-- холд не списан, но не доступна для вывода и обмена. Холд, у которого прошел
+-- expires_at, перестает действовать сразу, даже если статус еще active.
+CREATE TABLE holds (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    currency VARCHAR(3) NOT NULL,
+    amount DECIMAL(15, 2) NOT NULL CHECK (amount > 0),
+    captured DECIMAL(15, 2) CHECK (captured > 0 AND captured <= amount),
+    reference VARCHAR(128) NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'captured', 'released', 'expired')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    closed_at TIMESTAMPTZ
+);
+
+CREATE INDEX holds_active_idx ON holds (user_id, currency) WHERE status = 'active';
+CREATE INDEX holds_user_idx ON holds (user_id, created_at DESC);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE holds;
+-- +goose StatementEnd
@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Регулярные операции пользователя по расписанию cron (UTC): обмен,
+-- пополнение с обменом и перевод другому пользователю.
+CREATE TABLE scheduled_operations (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('exchange', 'deposit_exchange', 'transfer')),
+    from_currency VARCHAR(3) NOT NULL,
+    to_currency VARCHAR(3) NOT NULL,
+    recipient_id INT REFERENCES users(id) ON DELETE CASCADE,
+    amount DECIMAL(15, 2) NOT NULL CHECK (amount > 0),
+    cron VARCHAR(64) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paused')),
+    next_run_at TIMESTAMPTZ NOT NULL,
+    last_run_at TIMESTAMPTZ,
+    failures INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    -- Перевод идет другому пользователю в той же валюте, остальные операции — между валютами своего кошелька.
+    CHECK (CASE WHEN kind = 'transfer'
+        THEN to_currency = from_currency AND recipient_id IS NOT NULL AND recipient_id <> user_id
+        ELSE to_currency <> from_currency AND recipient_id IS NULL END)
+);
+
+CREATE INDEX scheduled_operations_due_idx ON scheduled_operations (next_run_at) WHERE status = 'active';
+CREATE INDEX scheduled_operations_user_idx ON scheduled_operations (user_id);
+
+-- История запусков.
+CREATE TABLE scheduled_runs (
+    id BIGSERIAL PRIMARY KEY,
+    schedule_id BIGINT NOT NULL REFERENCES scheduled_operations(id) ON DELETE CASCADE,
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    status VARCHAR(16) NOT NULL CHECK (status IN ('succeeded', 'failed')),
+    rate DECIMAL(20, 8),
+    error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX scheduled_runs_schedule_idx ON scheduled_runs (schedule_id, started_at DESC);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE scheduled_runs;
+DROP TABLE scheduled_operations;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- published_at — когда событие опубликовано в шину событий. Рассылка вебхуков
+-- (dispatched_at) и публикация идут независимо друг от друга.
+ALTER TABLE outbox_events ADD COLUMN published_at TIMESTAMPTZ;
+
+CREATE INDEX outbox_events_unpublished_idx ON outbox_events (id) WHERE published_at IS NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX outbox_events_unpublished_idx;
+ALTER TABLE outbox_events DROP COLUMN published_at;
+-- +goose StatementEnd
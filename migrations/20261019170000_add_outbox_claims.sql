@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- claimed_until — до какого момента события публикует реплика, которая их
+-- взяла. Публикация идет вне транзакции; если реплика не отметила событие
+-- к этому времени, его публикует следующая.
+ALTER TABLE outbox_events ADD COLUMN claimed_until TIMESTAMPTZ;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE outbox_events DROP COLUMN claimed_until;
+-- +goose StatementEnd
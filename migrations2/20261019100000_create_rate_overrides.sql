@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE currency_rates_usd ADD COLUMN enabled BOOLEAN NOT NULL DEFAULT TRUE;
+CREATE UNIQUE INDEX currency_rates_usd_code_idx ON currency_rates_usd (currency_code);
+
+-- Ручные курсы. Действует последний неснятый и неистекший курс валюты.
+CREATE TABLE rate_overrides (
+    id BIGSERIAL PRIMARY KEY,
+    currency_code VARCHAR(3) NOT NULL REFERENCES currency_rates_usd (currency_code),
+    exchange_rate REAL NOT NULL CHECK (exchange_rate > 0),
+    reason TEXT NOT NULL,
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    removed_at TIMESTAMPTZ
+);
+
+CREATE INDEX rate_overrides_active_idx ON rate_overrides (currency_code, created_at DESC)
+    WHERE removed_at IS NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE rate_overrides;
+DROP INDEX currency_rates_usd_code_idx;
+ALTER TABLE currency_rates_usd DROP COLUMN enabled;
+
+-- +goose StatementEnd
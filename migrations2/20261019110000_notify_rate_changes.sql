@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- notify_rate_change сообщает gw-exchanger об изменении курса валюты,
+-- чтобы он сбросил кеш, не дожидаясь истечения TTL.
+CREATE FUNCTION notify_rate_change() RETURNS TRIGGER AS $$
+DECLARE
+    code TEXT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        code := OLD.currency_code;
+    ELSE
+        code := NEW.currency_code;
+    END IF;
+    PERFORM pg_notify('rate_changes', json_build_object(
+        'table', TG_TABLE_NAME,
+        'op', TG_OP,
+        'currency', code
+    )::text);
+    IF TG_OP = 'UPDATE' AND OLD.currency_code <> NEW.currency_code THEN
+        PERFORM pg_notify('rate_changes', json_build_object(
+            'table', TG_TABLE_NAME,
+            'op', TG_OP,
+            'currency', OLD.currency_code
+        )::text);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER currency_rates_usd_notify
+    AFTER INSERT OR UPDATE OR DELETE ON currency_rates_usd
+    FOR EACH ROW EXECUTE FUNCTION notify_rate_change();
+
+CREATE TRIGGER rate_overrides_notify
+    AFTER INSERT OR UPDATE OR DELETE ON rate_overrides
+    FOR EACH ROW EXECUTE FUNCTION notify_rate_change();
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TRIGGER rate_overrides_notify ON rate_overrides;
+DROP TRIGGER currency_rates_usd_notify ON currency_rates_usd;
+DROP FUNCTION notify_rate_change();
+
+-- +goose StatementEnd